@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/assets"
 	"github.com/projects-for-public/help-govern/internal/config"
 	"github.com/projects-for-public/help-govern/internal/handlers"
+	"github.com/projects-for-public/help-govern/internal/i18n"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
 	"github.com/projects-for-public/help-govern/internal/services"
 	"github.com/projects-for-public/help-govern/internal/utils"
 	"gorm.io/driver/postgres"
@@ -23,11 +29,146 @@ func main() {
 		utils.Fatal("Failed to connect to database: %v", err)
 	}
 
-	reportService := services.NewReportService(db)
-	reportHandler := handlers.NewReportHandler(reportService)
+	catalog, err := i18n.Load(cfg.LocalesDir, cfg.LocaleFallbacks)
+	if err != nil {
+		utils.Fatal("Failed to load message catalogs: %v", err)
+	}
+
+	// Uploads are not part of a release, so they are not fingerprinted
+	assetManifest, err := assets.Load("web/static", cfg.UploadDir)
+	if err != nil {
+		utils.Fatal("Failed to hash static assets: %v", err)
+	}
+
+	imageStore, err := services.NewLocalImageStore(cfg.UploadDir, cfg.UploadURLPrefix)
+	if err != nil {
+		utils.Fatal("Failed to set up image storage: %v", err)
+	}
+
+	events := services.NewEventBus()
+
+	templates, err := services.LoadNotificationTemplates(cfg.NotificationTemplatesDir)
+	if err != nil {
+		utils.Fatal("Failed to load notification templates: %v", err)
+	}
+	translationService := services.NewTranslationService(db, catalog, templates)
+	templates.SetTranslations(translationService)
+	go translationService.Run(context.Background(), 5*time.Minute)
+
+	subscriptionService := services.NewSubscriptionService(db)
+	notificationService := services.NewNotificationService(db, templates, subscriptionService, cfg.PublicBaseURL)
+	if cfg.SMTPHost != "" {
+		smtpNotifier, err := services.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		if err != nil {
+			utils.Fatal("Invalid SMTP_FROM: %v", err)
+		}
+		notificationService.RegisterNotifier(models.ChannelEmail, smtpNotifier)
+	} else {
+		utils.Info("SMTP_HOST not set, email notifications disabled")
+	}
+	if cfg.VAPIDPrivateKey != "" {
+		vapidKeys, err := services.ParseVAPIDKeys(cfg.VAPIDPublicKey, cfg.VAPIDPrivateKey)
+		if err != nil {
+			utils.Fatal("Invalid VAPID keys: %v", err)
+		}
+		notificationService.RegisterNotifier(models.ChannelWebPush,
+			services.NewWebPushNotifier(db, vapidKeys, cfg.VAPIDSubject))
+	} else {
+		utils.Info("VAPID keys not set, web push notifications disabled")
+	}
+	events.Subscribe(notificationService.HandleEvent)
+	go notificationService.Run(context.Background(), 30*time.Second)
+
+	webhookService := services.NewWebhookService(db, cfg.PublicBaseURL)
+	events.Subscribe(webhookService.HandleEvent)
+	go webhookService.Run(context.Background(), 15*time.Second)
+
+	tileCache := services.NewTileCache(cfg.TileCacheDir, 24*time.Hour, cfg.TileCacheMaxBytes)
+	events.Subscribe(tileCache.HandleEvent)
+	go tileCache.Run(context.Background(), 10*time.Minute)
+	heatmapService := services.NewHeatmapService(db, tileCache)
+	vectorTileService := services.NewVectorTileService(db, tileCache)
+	staticMapService := services.NewStaticMapService(db, services.NewMapTileSource(cfg.MapTileURL), cfg.MapCacheDir)
+
+	sitemapService := services.NewSitemapService(db, cfg.PublicBaseURL)
+	events.Subscribe(sitemapService.HandleEvent)
+	go sitemapService.Run(context.Background(), time.Minute)
+
+	statsService := services.NewStatsService(db)
+	go statsService.Run(context.Background(), 15*time.Minute)
+
+	idempotencyService := services.NewIdempotencyService(db, cfg.IdempotencyWindow)
+	go idempotencyService.Run(context.Background(), time.Hour)
+
+	var translator services.Translator
+	switch cfg.Translator {
+	case "dictionary":
+		dictionary, err := services.LoadDictionaryTranslator(cfg.TranslationDictionaryDir)
+		if err != nil {
+			utils.Fatal("Failed to load translation dictionaries: %v", err)
+		}
+		translator = dictionary
+	case "libretranslate":
+		translator = services.NewLibreTranslator(cfg.TranslatorURL, cfg.TranslatorAPIKey)
+	default:
+		utils.Info("TRANSLATOR=none, report descriptions will not be translated")
+	}
+	reportTranslationService := services.NewReportTranslationService(db, translator, cfg.TranslationTargets)
+	events.Subscribe(reportTranslationService.HandleEvent)
+
+	reportService := services.NewReportService(db, cfg, events)
+	imageService := services.NewImageService(db, imageStore, events)
+	volunteerService := services.NewVolunteerService(db, reportService, imageService)
+	resolutionService := services.NewResolutionService(db, reportService, imageService)
+	trackingService := services.NewTrackingService(db)
+
+	reportHandler := handlers.NewReportHandler(reportService, imageService, trackingService, reportTranslationService, cfg.PublicBaseURL)
+	adminHandler := handlers.NewAdminHandler(reportService, imageService)
+	categoryHandler := handlers.NewCategoryHandler(reportService, translationService)
+	pageHandler := handlers.NewPageHandler(reportService, translationService, reportTranslationService, cfg.PublicBaseURL)
+	feedHandler := handlers.NewFeedHandler(reportService, translationService, cfg.PublicBaseURL)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapService, cfg.PublicBaseURL)
+	pwaHandler, err := handlers.NewPWAHandler(assetManifest, "web/templates/sw.js")
+	if err != nil {
+		utils.Fatal("Failed to render service worker: %v", err)
+	}
+	resolutionHandler := handlers.NewResolutionHandler(resolutionService)
+	trackingHandler := handlers.NewTrackingHandler(trackingService, notificationService, cfg.PublicBaseURL)
+	pushHandler := handlers.NewPushHandler(subscriptionService, cfg.VAPIDPublicKey)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, notificationService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	open311Handler := handlers.NewOpen311Handler(reportService, translationService, cfg.PublicBaseURL)
+	volunteerHandler := handlers.NewVolunteerHandler(volunteerService, reportService, cfg.PublicBaseURL)
+	statsHandler := handlers.NewStatsHandler(statsService, translationService)
+	tileHandler := handlers.NewTileHandler(heatmapService, vectorTileService, staticMapService)
+	translationHandler := handlers.NewTranslationHandler(translationService)
 
 	h := &handlers.Handlers{
-		Report: reportHandler,
+		Report:     reportHandler,
+		Admin:      adminHandler,
+		Categories: categoryHandler,
+		Pages:      pageHandler,
+		Feeds:      feedHandler,
+		Sitemaps:   sitemapHandler,
+		PWA:        pwaHandler,
+		Catalog:    catalog,
+		Assets:     assetManifest,
+		AdminAuth:  middleware.AdminAuth(cfg.AdminToken),
+
+		OptionalAdmin: middleware.OptionalAdmin(cfg.AdminToken),
+		Idempotency:   middleware.Idempotency(idempotencyService),
+		VolunteerAuth: middleware.VolunteerAuth(volunteerService),
+
+		Resolution:    resolutionHandler,
+		Tracking:      trackingHandler,
+		Push:          pushHandler,
+		Subscriptions: subscriptionHandler,
+		Webhooks:      webhookHandler,
+		Open311:       open311Handler,
+		Volunteers:    volunteerHandler,
+		Stats:         statsHandler,
+		Tiles:         tileHandler,
+		Translations:  translationHandler,
 		// Add other handlers here as needed
 	}
 
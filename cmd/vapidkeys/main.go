@@ -0,0 +1,19 @@
+// Command vapidkeys generates a VAPID key pair for web push notifications.
+// Add the printed lines to the server environment.
+package main
+
+import (
+	"fmt"
+
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+func main() {
+	keys, err := services.GenerateVAPIDKeys()
+	if err != nil {
+		utils.Fatal("Failed to generate VAPID keys: %v", err)
+	}
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", keys.PublicKey)
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", keys.PrivateKey)
+}
@@ -0,0 +1,139 @@
+// Package assets fingerprints the static files the site ships, such as
+// scripts and stylesheets, so they can be served under content-hashed names
+// and cached by browsers and the service worker forever.
+//
+// Files are hashed once at startup. css/style.css is served as
+// /assets/css/style.<hash>.css, and a changed file gets a new URL on the next
+// deploy. Version fingerprints the whole set and names the service worker
+// cache.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// URLPrefix is where hashed assets are served
+const URLPrefix = "/assets/"
+
+// Manifest maps static files to their hashed names
+type Manifest struct {
+	// names maps a file, relative to the static directory with forward
+	// slashes, to its hashed name; files maps hashed names back to paths on
+	// disk
+	names map[string]string
+	files map[string]string
+
+	// Version changes whenever any asset does
+	Version string
+}
+
+// Load hashes every file under dir, except those under the skip
+// directories (such as user uploads, which are not part of a release)
+func Load(dir string, skip ...string) (*Manifest, error) {
+	m := &Manifest{names: map[string]string{}, files: map[string]string{}}
+	skipped := map[string]bool{}
+	for _, s := range skip {
+		if abs, err := filepath.Abs(s); err == nil {
+			skipped[abs] = true
+		}
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(p); err == nil && skipped[abs] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		ext := path.Ext(name)
+		hashed := fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), sum[:10], ext)
+		m.names[name] = hashed
+		m.files[hashed] = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	version := sha256.New()
+	for _, name := range m.sortedNames() {
+		fmt.Fprintf(version, "%s\n", m.names[name])
+	}
+	m.Version = hex.EncodeToString(version.Sum(nil))[:12]
+	return m, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (m *Manifest) sortedNames() []string {
+	names := make([]string, 0, len(m.names))
+	for name := range m.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// URL returns the hashed URL of a static file, e.g. "css/style.css". Files
+// unknown at startup keep their plain /static URL.
+func (m *Manifest) URL(name string) string {
+	if hashed, ok := m.names[strings.TrimPrefix(name, "/")]; ok {
+		return URLPrefix + hashed
+	}
+	return "/static/" + strings.TrimPrefix(name, "/")
+}
+
+// File returns the path on disk of a hashed asset name
+func (m *Manifest) File(hashed string) (string, bool) {
+	p, ok := m.files[strings.TrimPrefix(hashed, "/")]
+	return p, ok
+}
+
+// URLs returns the hashed URLs of every asset under the given directories,
+// e.g. "css" and "js", in name order
+func (m *Manifest) URLs(dirs ...string) []string {
+	var urls []string
+	for _, name := range m.sortedNames() {
+		for _, d := range dirs {
+			if strings.HasPrefix(name, strings.TrimSuffix(d, "/")+"/") {
+				urls = append(urls, URLPrefix+m.names[name])
+				break
+			}
+		}
+	}
+	return urls
+}
@@ -3,10 +3,76 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	DatabaseURL string
+
+	// PublicBaseURL is where the site is reachable, used for absolute share links
+	PublicBaseURL string
+
+	// AdminToken guards the /admin routes until JWT auth lands.
+	AdminToken string
+
+	// Duplicate detection: open reports of the same category within
+	// DuplicateRadiusMeters and created within DuplicateWindow are offered
+	// to the reporter as possible duplicates.
+	DuplicateRadiusMeters float64
+	DuplicateWindow       time.Duration
+
+	// Responses to requests sent with an Idempotency-Key header are replayed
+	// to retries for IdempotencyWindow
+	IdempotencyWindow time.Duration
+
+	// Uploaded images are stored in UploadDir and served under UploadURLPrefix
+	// until Cloudinary is integrated.
+	UploadDir       string
+	UploadURLPrefix string
+
+	// Rendered map tiles are cached under TileCacheDir, which is pruned
+	// down to TileCacheMaxBytes
+	TileCacheDir      string
+	TileCacheMaxBytes int64
+
+	// Static report maps are composed from the raster tiles at MapTileURL, a
+	// URL or directory template with {z}, {x} and {y}, and cached together
+	// with those tiles under MapCacheDir
+	MapTileURL  string
+	MapCacheDir string
+
+	// Email notifications are sent through SMTP when SMTPHost is set
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	NotificationTemplatesDir string
+
+	// LocalesDir holds the message catalogs, one <locale>.json per language.
+	// LocaleFallbacks chains languages before English, e.g. "mr:hi" serves
+	// Hindi where Marathi is missing.
+	LocalesDir      string
+	LocaleFallbacks string
+
+	// Report descriptions are machine translated by Translator: "dictionary"
+	// (offline glossaries in TranslationDictionaryDir), "libretranslate"
+	// (TranslatorURL, TranslatorAPIKey) or "none". New reports are translated
+	// into TranslationTargets up front, other languages on demand.
+	Translator               string
+	TranslatorURL            string
+	TranslatorAPIKey         string
+	TranslationDictionaryDir string
+	TranslationTargets       []string
+
+	// Web push is enabled when both VAPID keys are set.
+	// Generate them with `go run ./cmd/vapidkeys`.
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	VAPIDSubject    string
 }
 
 func Load() (*Config, error) {
@@ -14,7 +80,121 @@ func Load() (*Config, error) {
 	if dbURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
 	}
+	radius, err := getEnvFloat("DUPLICATE_RADIUS_METERS", 50)
+	if err != nil {
+		return nil, err
+	}
+	window, err := getEnvDuration("DUPLICATE_WINDOW", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	idempotencyWindow, err := getEnvDuration("IDEMPOTENCY_WINDOW", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	tileCacheMaxMB, err := getEnvInt("TILE_CACHE_MAX_MB", 512)
+	if err != nil {
+		return nil, err
+	}
+	smtpPort, err := getEnvInt("SMTP_PORT", 587)
+	if err != nil {
+		return nil, err
+	}
+	vapidPublic, vapidPrivate := os.Getenv("VAPID_PUBLIC_KEY"), os.Getenv("VAPID_PRIVATE_KEY")
+	if (vapidPublic == "") != (vapidPrivate == "") {
+		return nil, fmt.Errorf("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+	}
+	translator := getEnv("TRANSLATOR", "dictionary")
+	switch translator {
+	case "dictionary", "none":
+	case "libretranslate":
+		if os.Getenv("TRANSLATOR_URL") == "" {
+			return nil, fmt.Errorf("TRANSLATOR_URL must be set for TRANSLATOR=libretranslate")
+		}
+	default:
+		return nil, fmt.Errorf("invalid TRANSLATOR %q: must be dictionary, libretranslate or none", translator)
+	}
+	var targets []string
+	for _, t := range strings.Split(getEnv("TRANSLATION_TARGETS", "en,hi"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
 	return &Config{
-		DatabaseURL: dbURL,
+		DatabaseURL:           dbURL,
+		PublicBaseURL:         getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		AdminToken:            os.Getenv("ADMIN_TOKEN"),
+		DuplicateRadiusMeters: radius,
+		DuplicateWindow:       window,
+		IdempotencyWindow:     idempotencyWindow,
+		UploadDir:             getEnv("UPLOAD_DIR", "web/static/uploads"),
+		UploadURLPrefix:       getEnv("UPLOAD_URL_PREFIX", "/static/uploads"),
+		TileCacheDir:          getEnv("TILE_CACHE_DIR", "cache/tiles"),
+		TileCacheMaxBytes:     int64(tileCacheMaxMB) << 20,
+		MapTileURL:            getEnv("MAP_TILE_URL", "https://tile.openstreetmap.org/{z}/{x}/{y}.png"),
+		MapCacheDir:           getEnv("MAP_CACHE_DIR", "cache/maps"),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     smtpPort,
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getEnv("SMTP_FROM", "Help Govern <no-reply@localhost>"),
+
+		NotificationTemplatesDir: getEnv("NOTIFICATION_TEMPLATES_DIR", "web/templates/email"),
+		LocalesDir:               getEnv("LOCALES_DIR", "web/locales"),
+		LocaleFallbacks:          getEnv("LOCALE_FALLBACKS", "mr:hi"),
+
+		Translator:               translator,
+		TranslatorURL:            os.Getenv("TRANSLATOR_URL"),
+		TranslatorAPIKey:         os.Getenv("TRANSLATOR_API_KEY"),
+		TranslationDictionaryDir: getEnv("TRANSLATION_DICTIONARY_DIR", "web/locales/dictionary"),
+		TranslationTargets:       targets,
+
+		VAPIDPublicKey:  vapidPublic,
+		VAPIDPrivateKey: vapidPrivate,
+		VAPIDSubject:    getEnv("VAPID_SUBJECT", "mailto:admin@localhost"),
 	}, nil
 }
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return i, nil
+}
+
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
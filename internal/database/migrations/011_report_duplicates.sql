@@ -0,0 +1,6 @@
+ALTER TABLE reports
+    ADD COLUMN merged_into_id INTEGER REFERENCES reports(id),
+    ADD COLUMN duplicate_count INTEGER NOT NULL DEFAULT 0;
+
+-- Duplicate lookups filter by category among non-merged reports
+CREATE INDEX idx_reports_category_open ON reports(category, created_at) WHERE merged_into_id IS NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE report_confirmations (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL, -- sha256 of the device ID, or of the IP when no device ID is sent
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (report_id, fingerprint)
+);
+
+ALTER TABLE reports ADD COLUMN confirmation_count INTEGER NOT NULL DEFAULT 0;
+
+-- Sorting and filtering by confirmations
+CREATE INDEX idx_reports_confirmation_count ON reports(confirmation_count);
@@ -0,0 +1,17 @@
+CREATE TABLE resolution_claims (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    note TEXT,
+    status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'verified' or 'rejected'
+    claimant_ip INET,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP,
+    reviewed_by INTEGER REFERENCES users(id),
+    review_notes TEXT
+);
+
+-- Resolution photos belong to the claim that submitted them
+ALTER TABLE images ADD COLUMN resolution_claim_id INTEGER REFERENCES resolution_claims(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_resolution_claims_status ON resolution_claims(status);
+CREATE INDEX idx_resolution_claims_report_id ON resolution_claims(report_id);
@@ -0,0 +1,9 @@
+ALTER TABLE reports
+    ADD COLUMN share_slug VARCHAR(32),
+    ADD COLUMN edit_token_hash VARCHAR(64); -- sha256 of the reporter's private edit token
+
+-- Give existing reports a slug so they can be shared too
+UPDATE reports SET share_slug = substr(md5(random()::text || id::text), 1, 12) WHERE share_slug IS NULL;
+
+ALTER TABLE reports ALTER COLUMN share_slug SET NOT NULL;
+CREATE UNIQUE INDEX idx_reports_share_slug ON reports(share_slug);
@@ -0,0 +1,24 @@
+-- One-time tracking tokens handed to anonymous reporters who want follow-up
+CREATE TABLE tracking_tokens (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL, -- sha256 of the token
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Opt-in notification channels for people following a report, no User account needed
+CREATE TABLE subscriptions (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER REFERENCES reports(id) ON DELETE CASCADE,
+    tracking_token_id INTEGER REFERENCES tracking_tokens(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL, -- 'email' or 'webpush'
+    email VARCHAR(100),
+    push_endpoint TEXT,
+    push_p256dh VARCHAR(100),
+    push_auth VARCHAR(50),
+    locale VARCHAR(10) DEFAULT 'en',
+    unsubscribe_token VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_subscriptions_report_id ON subscriptions(report_id);
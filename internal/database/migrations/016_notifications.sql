@@ -0,0 +1,20 @@
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL, -- 'email' or 'webpush'
+    recipient TEXT NOT NULL,
+    subscription_id INTEGER REFERENCES subscriptions(id) ON DELETE SET NULL,
+    event VARCHAR(50) NOT NULL,
+    locale VARCHAR(10) DEFAULT 'en',
+    subject VARCHAR(255),
+    body_text TEXT,
+    body_html TEXT,
+    status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'sent' or 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+-- Queue worker picks up due pending notifications
+CREATE INDEX idx_notifications_due ON notifications(next_attempt_at) WHERE status = 'pending';
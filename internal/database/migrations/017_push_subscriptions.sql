@@ -0,0 +1,8 @@
+-- Subscriptions can follow an area (a circle) instead of a single report
+ALTER TABLE subscriptions
+    ADD COLUMN center_lat DECIMAL(10, 8),
+    ADD COLUMN center_lng DECIMAL(11, 8),
+    ADD COLUMN radius_m INTEGER;
+
+-- Unregistering and pruning look subscriptions up by push endpoint
+CREATE INDEX idx_subscriptions_push_endpoint ON subscriptions(push_endpoint);
@@ -0,0 +1,33 @@
+-- Area subscriptions: a circle or a polygon, optional category filters and daily digests
+ALTER TABLE subscriptions
+    ADD COLUMN polygon JSONB, -- [[lat, lng], ...] ring, replaces the circle when set
+    ADD COLUMN min_lat DECIMAL(10, 8),
+    ADD COLUMN min_lng DECIMAL(11, 8),
+    ADD COLUMN max_lat DECIMAL(10, 8),
+    ADD COLUMN max_lng DECIMAL(11, 8),
+    ADD COLUMN categories TEXT[], -- NULL or empty means every category
+    ADD COLUMN delivery VARCHAR(10) NOT NULL DEFAULT 'instant', -- 'instant' or 'daily'
+    ADD COLUMN last_digest_at TIMESTAMP;
+
+-- Bounding boxes for existing circles (same approximation as utils.BoundingBox)
+UPDATE subscriptions
+SET min_lat = center_lat - degrees(radius_m / 6371000.0),
+    max_lat = center_lat + degrees(radius_m / 6371000.0),
+    min_lng = center_lng - degrees(radius_m / 6371000.0) / GREATEST(cos(radians(center_lat)), 1e-6),
+    max_lng = center_lng + degrees(radius_m / 6371000.0) / GREATEST(cos(radians(center_lat)), 1e-6)
+WHERE report_id IS NULL AND radius_m IS NOT NULL;
+
+CREATE INDEX idx_subscriptions_bounds ON subscriptions(min_lat, max_lat, min_lng, max_lng)
+    WHERE report_id IS NULL;
+
+-- Events waiting for the next daily digest of a subscription
+CREATE TABLE digest_items (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL, -- 'new_report' or 'status_changed'
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_digest_items_subscription_id ON digest_items(subscription_id);
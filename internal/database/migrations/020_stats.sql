@@ -0,0 +1,94 @@
+-- Public analytics. The materialized views below are refreshed on a schedule
+-- by StatsService so the /stats endpoints never scan reports directly.
+
+-- One row per live report with its verification and resolution times. Older
+-- reports may lack verified_at/resolved_at, so fall back to status_updates.
+CREATE VIEW stats_report_times AS
+SELECT r.id,
+       r.category,
+       r.status,
+       r.state,
+       r.created_at,
+       COALESCE(r.verified_at, (
+           SELECT MIN(su.updated_at) FROM status_updates su
+           WHERE su.report_id = r.id AND su.new_status = 'verified'
+       )) AS verified_at,
+       CASE WHEN r.status = 'resolved' THEN COALESCE(r.resolved_at, (
+           SELECT MAX(su.updated_at) FROM status_updates su
+           WHERE su.report_id = r.id AND su.new_status = 'resolved'
+       )) END AS resolved_at
+FROM reports r
+WHERE r.merged_into_id IS NULL AND r.status <> 'withdrawn';
+
+-- Counts by category, status and state ('' when unknown)
+CREATE MATERIALIZED VIEW stats_counts AS
+SELECT category, status, COALESCE(state, '') AS state, COUNT(*) AS reports
+FROM stats_report_times
+GROUP BY category, status, COALESCE(state, '');
+
+CREATE UNIQUE INDEX idx_stats_counts ON stats_counts(category, status, state);
+
+-- Median and p90 time-to-verify and time-to-resolve in seconds, overall
+-- (dimension 'all'), per category and per state
+CREATE MATERIALIZED VIEW stats_resolution_times AS
+SELECT CASE WHEN GROUPING(category) = 0 THEN 'category'
+            WHEN GROUPING(state) = 0 THEN 'state'
+            ELSE 'all' END AS dimension,
+       CASE WHEN GROUPING(category) = 0 THEN category
+            WHEN GROUPING(state) = 0 THEN COALESCE(state, '')
+            ELSE '' END AS key,
+       COUNT(*) AS reports,
+       COUNT(verify_s) AS verified,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY verify_s) AS verify_median_s,
+       percentile_cont(0.9) WITHIN GROUP (ORDER BY verify_s) AS verify_p90_s,
+       COUNT(resolve_s) AS resolved,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY resolve_s) AS resolve_median_s,
+       percentile_cont(0.9) WITHIN GROUP (ORDER BY resolve_s) AS resolve_p90_s
+FROM (
+    SELECT category, state,
+           EXTRACT(EPOCH FROM verified_at - created_at) AS verify_s,
+           EXTRACT(EPOCH FROM resolved_at - created_at) AS resolve_s
+    FROM stats_report_times
+) t
+GROUP BY GROUPING SETS ((), (category), (state));
+
+CREATE UNIQUE INDEX idx_stats_resolution_times ON stats_resolution_times(dimension, key);
+
+-- Reports created, verified and resolved per calendar month and category
+CREATE MATERIALIZED VIEW stats_monthly AS
+SELECT month, category,
+       COUNT(*) FILTER (WHERE kind = 'created') AS created,
+       COUNT(*) FILTER (WHERE kind = 'verified') AS verified,
+       COUNT(*) FILTER (WHERE kind = 'resolved') AS resolved
+FROM (
+    SELECT date_trunc('month', created_at)::date AS month, category, 'created' AS kind
+    FROM stats_report_times
+    UNION ALL
+    SELECT date_trunc('month', verified_at)::date, category, 'verified'
+    FROM stats_report_times WHERE verified_at IS NOT NULL
+    UNION ALL
+    SELECT date_trunc('month', resolved_at)::date, category, 'resolved'
+    FROM stats_report_times WHERE resolved_at IS NOT NULL
+) e
+GROUP BY month, category;
+
+CREATE UNIQUE INDEX idx_stats_monthly ON stats_monthly(month, category);
+
+-- Per-authority performance for the reports in its state
+CREATE MATERIALIZED VIEW stats_authorities AS
+SELECT a.id AS authority_id,
+       a.authority_name,
+       a.state,
+       a.twitter_handle,
+       COUNT(t.id) AS reports,
+       COUNT(t.id) FILTER (WHERE t.status IN ('pending', 'verified', 'in_progress')) AS open_reports,
+       COUNT(t.resolved_at) AS resolved,
+       percentile_cont(0.5) WITHIN GROUP (
+           ORDER BY EXTRACT(EPOCH FROM t.resolved_at - t.created_at)
+       ) AS resolve_median_s
+FROM state_authorities a
+LEFT JOIN stats_report_times t ON t.state = a.state
+WHERE a.is_active
+GROUP BY a.id, a.authority_name, a.state, a.twitter_handle;
+
+CREATE UNIQUE INDEX idx_stats_authorities ON stats_authorities(authority_id);
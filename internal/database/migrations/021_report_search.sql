@@ -0,0 +1,30 @@
+-- Full-text search. Postgres has no Hindi stemmer, so text is indexed twice:
+-- with 'english' (stemmed, for "manholes" ~ "manhole") and with 'simple'
+-- (exact lowercase tokens, which also covers Devanagari). Queries are run
+-- against both configurations and OR-ed together.
+
+-- Public search: description, city and category
+ALTER TABLE reports ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', replace(category, '_', ' ')), 'A') ||
+    setweight(to_tsvector('simple', replace(category, '_', ' ')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(city, '')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(city, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'C')
+) STORED;
+
+-- Moderator search additionally matches admin notes. Kept separate so public
+-- searches can never hit text only moderators may see.
+ALTER TABLE reports ADD COLUMN admin_search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', replace(category, '_', ' ')), 'A') ||
+    setweight(to_tsvector('simple', replace(category, '_', ' ')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(city, '')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(city, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'C') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(admin_notes, '')), 'D') ||
+    setweight(to_tsvector('simple', COALESCE(admin_notes, '')), 'D')
+) STORED;
+
+CREATE INDEX idx_reports_search ON reports USING GIN (search_vector);
+CREATE INDEX idx_reports_admin_search ON reports USING GIN (admin_search_vector);
@@ -0,0 +1,23 @@
+-- Translations of entity text, replacing per-language columns such as
+-- categories.name_hi. English stays in the entity's own columns (or template
+-- files); rows here hold every other language.
+CREATE TABLE translations (
+    id SERIAL PRIMARY KEY,
+    entity VARCHAR(50) NOT NULL, -- 'category', 'authority' or 'notification_template'
+    entity_id VARCHAR(100) NOT NULL, -- row ID, or the event name for notification templates
+    field VARCHAR(50) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (entity, entity_id, field, locale)
+);
+
+CREATE INDEX idx_translations_locale ON translations(locale);
+
+INSERT INTO translations (entity, entity_id, field, locale, value)
+SELECT 'category', id::text, 'name', 'hi', name_hi
+FROM categories
+WHERE name_hi IS NOT NULL AND name_hi <> '';
+
+ALTER TABLE categories DROP COLUMN name_hi;
@@ -0,0 +1,14 @@
+-- Language of each report description, detected at submission
+ALTER TABLE reports ADD COLUMN description_language VARCHAR(10);
+
+-- Machine translations of report descriptions, one per target language.
+-- The original stays in reports.description.
+CREATE TABLE report_translations (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL,
+    description TEXT NOT NULL,
+    engine VARCHAR(50) NOT NULL, -- translator that produced it, e.g. 'libretranslate'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (report_id, locale)
+);
@@ -0,0 +1,5 @@
+-- Reports queued offline and synced later carry the hash of a
+-- client-generated submission ID, so a draft sent twice creates one report
+ALTER TABLE reports ADD COLUMN submission_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_reports_submission_hash ON reports(submission_hash) WHERE submission_hash IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Responses to requests sent with an Idempotency-Key header, so a retried
+-- request gets the original response instead of running twice
+CREATE TABLE idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(255) NOT NULL, -- method and path, e.g. 'POST /reports/12/images'
+    key_hash VARCHAR(64) NOT NULL, -- sha256 of the client's key
+    request_hash VARCHAR(64) NOT NULL, -- sha256 of the request body and edit token
+    status_code INTEGER, -- NULL while the first request is still running
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    response_body BYTEA, -- AES-GCM sealed with a key derived from the client's key
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    UNIQUE (scope, key_hash)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,18 @@
+-- API keys issued to NGO volunteers who survey areas offline and upload
+-- reports in batches
+CREATE TABLE volunteer_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    organization VARCHAR(255),
+    key_hash VARCHAR(64) NOT NULL UNIQUE, -- sha256 of the key, which is shown once
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+ALTER TABLE reports
+    ADD COLUMN volunteer_key_id INTEGER REFERENCES volunteer_keys(id),
+    ADD COLUMN captured_at TIMESTAMP; -- when the volunteer saw the issue, from their device
+
+CREATE INDEX idx_reports_volunteer_key_id ON reports(volunteer_key_id) WHERE volunteer_key_id IS NOT NULL;
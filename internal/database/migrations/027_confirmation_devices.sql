@@ -0,0 +1,8 @@
+-- Confirmations are deduplicated on the client IP and, when the client
+-- sends one, also on its device ID, so changing the device ID alone does
+-- not count again. From here on fingerprint is always the sha256 of the IP,
+-- whatever 012 says; the device ID goes in device_fingerprint.
+ALTER TABLE report_confirmations ADD COLUMN device_fingerprint VARCHAR(64); -- sha256 of the X-Device-ID header
+
+CREATE UNIQUE INDEX idx_report_confirmations_device ON report_confirmations(report_id, device_fingerprint)
+    WHERE device_fingerprint IS NOT NULL;
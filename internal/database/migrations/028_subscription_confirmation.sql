@@ -0,0 +1,8 @@
+-- Email subscriptions are double opt-in: nothing is sent to an address until
+-- its owner follows the link in the confirmation email
+ALTER TABLE subscriptions
+    ADD COLUMN confirmed_at TIMESTAMP,
+    ADD COLUMN confirm_token_hash VARCHAR(64) UNIQUE; -- sha256 of the token in the confirmation link
+
+-- Existing subscribers keep their notifications
+UPDATE subscriptions SET confirmed_at = created_at;
@@ -0,0 +1,5 @@
+-- The queue worker claims notifications by marking them 'sending' until a
+-- lease runs out, then sends them outside of any transaction. Claimed rows
+-- whose lease expired are due again.
+DROP INDEX idx_notifications_due;
+CREATE INDEX idx_notifications_due ON notifications(next_attempt_at) WHERE status IN ('pending', 'sending');
@@ -0,0 +1,5 @@
+-- The queue worker claims deliveries by marking them 'sending' until a lease
+-- runs out, then calls the endpoints outside of any transaction. Claimed rows
+-- whose lease expired are due again.
+DROP INDEX idx_webhook_deliveries_due;
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status IN ('pending', 'sending');
@@ -0,0 +1,13 @@
+-- When each report was created or last changed status, kept on the row so
+-- feeds can list the most recently active reports from an index instead of
+-- scanning status_updates for every report.
+ALTER TABLE reports ADD COLUMN last_activity_at TIMESTAMP;
+
+UPDATE reports SET last_activity_at = COALESCE(GREATEST(created_at,
+    (SELECT MAX(updated_at) FROM status_updates WHERE status_updates.report_id = reports.id)), CURRENT_TIMESTAMP);
+
+ALTER TABLE reports
+    ALTER COLUMN last_activity_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN last_activity_at SET NOT NULL;
+
+CREATE INDEX idx_reports_last_activity ON reports(last_activity_at DESC, id DESC) WHERE merged_into_id IS NULL;
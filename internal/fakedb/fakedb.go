@@ -0,0 +1,228 @@
+// Package fakedb is a database/sql driver for tests. It answers queries from
+// canned rows and records every statement, so code built on GORM can be
+// tested without PostgreSQL.
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DB is the fake database behind a GORM handle from New
+type DB struct {
+	mu sync.Mutex
+	// tables maps a table name to the rows returned by successive queries
+	// on it; the last set keeps being returned
+	tables map[string][]rows
+	// returning is tables for INSERT ... RETURNING
+	returning map[string][]rows
+	execs     []Statement
+	// queries are the statements that returned rows
+	queries []Statement
+	// failures maps a statement prefix to the error it fails with
+	failures map[string]error
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// Statement is a recorded statement with its arguments
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// New opens a GORM handle on a fake database using the PostgreSQL dialect
+func New(t testing.TB) (*gorm.DB, *DB) {
+	t.Helper()
+	fake := &DB{tables: map[string][]rows{}, returning: map[string][]rows{}, failures: map[string]error{}}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// SetRows makes queries on table return values. Calling it again queues
+// another set: each query takes the next set, and the last one is kept.
+func (f *DB) SetRows(table string, columns []string, values ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[table] = append(f.tables[table], rows{columns: columns, values: values})
+}
+
+// SetReturning is SetRows for inserts into table that return rows, such as
+// the generated ID. Without it such inserts return nothing, as when ON
+// CONFLICT DO NOTHING skipped the row.
+func (f *DB) SetReturning(table string, columns []string, values ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.returning[table] = append(f.returning[table], rows{columns: columns, values: values})
+}
+
+// Fail makes statements that start with prefix fail with err.
+// Fail("COMMIT", err) makes transactions fail to commit.
+func (f *DB) Fail(prefix string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[prefix] = err
+}
+
+// failure returns the error a statement should fail with; callers hold mu
+func (f *DB) failure(query string) error {
+	for prefix, err := range f.failures {
+		if strings.HasPrefix(query, prefix) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Executed returns the statements run so far that start with prefix,
+// e.g. `DELETE FROM "subscriptions"`. Failed queries are included.
+func (f *DB) Executed(prefix string) []Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return withPrefix(f.execs, prefix)
+}
+
+// Queried is Executed for queries, e.g. `SELECT * FROM "images"`
+func (f *DB) Queried(prefix string) []Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return withPrefix(f.queries, prefix)
+}
+
+func withPrefix(statements []Statement, prefix string) []Statement {
+	var found []Statement
+	for _, e := range statements {
+		if strings.HasPrefix(e.Query, prefix) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// next takes the rows for a query from sets; callers hold mu
+func next(sets map[string][]rows, query, pattern string) (*result, bool) {
+	for table, queued := range sets {
+		if strings.Contains(query, strings.Replace(pattern, "%s", table, 1)) {
+			if len(queued) > 1 {
+				sets[table] = queued[1:]
+			}
+			return &result{rows: queued[0]}, true
+		}
+	}
+	return nil, false
+}
+
+func (f *DB) Connect(context.Context) (driver.Conn, error) { return conn{f}, nil }
+func (f *DB) Driver() driver.Driver                        { return nil }
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{c.db}, nil }
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.execs = append(c.db.execs, Statement{Query: query, Args: values(args)})
+	if err := c.db.failure(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if err := c.db.failure(query); err != nil {
+		c.db.execs = append(c.db.execs, Statement{Query: query, Args: values(args)})
+		return nil, err
+	}
+	c.db.queries = append(c.db.queries, Statement{Query: query, Args: values(args)})
+	if strings.HasPrefix(query, "INSERT INTO ") {
+		if r, ok := next(c.db.returning, query, `INSERT INTO "%s"`); ok {
+			return r, nil
+		}
+	}
+	if r, ok := next(c.db.tables, query, `FROM "%s"`); ok {
+		return r, nil
+	}
+	return &result{}, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, a := range args {
+		v[i] = a.Value
+	}
+	return v
+}
+
+type stmt struct {
+	conn  conn
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	n := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		n[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return n
+}
+
+type tx struct{ db *DB }
+
+// Commit fails with the error set by Fail("COMMIT", err)
+func (t tx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	return t.db.failure("COMMIT")
+}
+
+func (tx) Rollback() error { return nil }
+
+type result struct {
+	rows rows
+	next int
+}
+
+func (r *result) Columns() []string { return r.rows.columns }
+func (r *result) Close() error      { return nil }
+
+func (r *result) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows.values) {
+		return io.EOF
+	}
+	copy(dest, r.rows.values[r.next])
+	r.next++
+	return nil
+}
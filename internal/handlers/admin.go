@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type AdminHandler struct {
+	Reports *services.ReportService
+	Images  *services.ImageService
+}
+
+func NewAdminHandler(reports *services.ReportService, images *services.ImageService) *AdminHandler {
+	return &AdminHandler{Reports: reports, Images: images}
+}
+
+// MergeReportRequest folds the report in the URL into the report given by Into
+type MergeReportRequest struct {
+	Into  int    `json:"into" binding:"required"`
+	Notes string `json:"notes"`
+}
+
+// POST /admin/reports/:id/merge
+func (h *AdminHandler) MergeReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /admin/reports/:id/merge - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	var req MergeReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /admin/reports/:id/merge - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	canonical, err := h.Reports.MergeReports(c.Request.Context(), id, req.Into, req.Notes)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case errors.Is(err, services.ErrInvalidMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /admin/reports/:id/merge - failed to merge %d into %d: %v", id, req.Into, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not merge reports.")})
+		return
+	}
+	utils.Info("POST /admin/reports/:id/merge - merged report %d into %d", id, canonical.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"id":              canonical.ID,
+		"merged_id":       id,
+		"duplicate_count": canonical.DuplicateCount,
+	})
+}
+
+// UpdateStatusRequest is the payload for PUT /admin/reports/:id/status
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending verified in_progress resolved rejected"`
+	Notes  string `json:"notes"`
+}
+
+// PUT /admin/reports/:id/status
+func (h *AdminHandler) UpdateStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("PUT /admin/reports/:id/status - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	var req UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("PUT /admin/reports/:id/status - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	report, update, err := h.Reports.UpdateStatus(c.Request.Context(), id, req.Status, req.Notes, nil)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "Merged or withdrawn reports cannot change status.")})
+		return
+	case err != nil:
+		utils.Error("PUT /admin/reports/:id/status - failed to update report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not update status.")})
+		return
+	}
+	utils.Info("PUT /admin/reports/:id/status - report %d is now %s", id, report.Status)
+	c.JSON(http.StatusOK, gin.H{"id": report.ID, "status": report.Status, "update": update})
+}
+
+// ModerateImageRequest is the payload for PUT /admin/images/:id/moderate
+type ModerateImageRequest struct {
+	ModerationStatus string `json:"moderation_status" binding:"required,oneof=approved rejected"`
+	Notes            string `json:"notes"`
+}
+
+// PUT /admin/images/:id/moderate
+func (h *AdminHandler) ModerateImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("PUT /admin/images/:id/moderate - invalid image ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid image ID.")})
+		return
+	}
+	var req ModerateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("PUT /admin/images/:id/moderate - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	img, err := h.Images.ModerateImage(c.Request.Context(), id, req.ModerationStatus, req.Notes, nil)
+	switch {
+	case errors.Is(err, services.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Image not found.")})
+		return
+	case err != nil:
+		utils.Error("PUT /admin/images/:id/moderate - failed to moderate image %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not moderate image.")})
+		return
+	}
+	utils.Info("PUT /admin/images/:id/moderate - image %d %s", img.ID, img.ModerationStatus)
+	c.JSON(http.StatusOK, img)
+}
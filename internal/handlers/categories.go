@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// CategoryResponse is a category with its name in the request's language
+type CategoryResponse struct {
+	models.Category
+	DisplayName string `json:"display_name"`
+}
+
+type CategoryHandler struct {
+	Service      *services.ReportService
+	Translations *services.TranslationService
+}
+
+func NewCategoryHandler(service *services.ReportService, translations *services.TranslationService) *CategoryHandler {
+	return &CategoryHandler{Service: service, Translations: translations}
+}
+
+// GET /categories
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	categories, err := h.Service.ListCategories(c.Request.Context())
+	if err != nil {
+		utils.Error("GET /categories - failed to list categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list categories.")})
+		return
+	}
+	out := make([]CategoryResponse, len(categories))
+	for i, cat := range categories {
+		out[i] = localizedCategory(c, h.Translations, cat)
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": out, "locale": middleware.Lang(c)})
+}
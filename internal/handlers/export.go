@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+var exportContentTypes = map[string]string{
+	services.ExportCSV:     "text/csv; charset=utf-8",
+	services.ExportGeoJSON: "application/geo+json",
+	services.ExportKML:     "application/vnd.google-earth.kml+xml",
+}
+
+// ExportFormats lists the formats served under /export/reports.<format>
+var ExportFormats = []string{services.ExportCSV, services.ExportGeoJSON, services.ExportKML}
+
+// ExportReports returns the handler for GET /export/reports.<format>.
+// The response is streamed, so it has no Content-Length and a failure part
+// way through can only be logged.
+func (h *ReportHandler) ExportReports(format string) gin.HandlerFunc {
+	path := "GET /export/reports." + format
+	return func(c *gin.Context) {
+		filter, err := reportFilterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+			return
+		}
+		c.Header("Content-Type", exportContentTypes[format])
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="reports-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		c.Status(http.StatusOK)
+		n, err := h.Service.ExportReports(c.Request.Context(), c.Writer, format, filter)
+		if err != nil {
+			utils.Error("%s - export failed after %d reports: %v", path, n, err)
+			return
+		}
+		utils.Info("%s - exported %d reports", path, n)
+	}
+}
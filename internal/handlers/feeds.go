@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Feed formats served under /feeds/reports.<format>
+const (
+	FeedAtom = "atom"
+	FeedRSS  = "rss"
+)
+
+// FeedFormats lists the formats served under /feeds/reports.<format>
+var FeedFormats = []string{FeedAtom, FeedRSS}
+
+var feedContentTypes = map[string]string{
+	FeedAtom: "application/atom+xml; charset=utf-8",
+	FeedRSS:  "application/rss+xml; charset=utf-8",
+}
+
+const (
+	// feedSize is the number of most recently active reports in a feed
+	feedSize = 50
+	// feedCacheControl lets readers and proxies poll cheaply; conditional
+	// requests make a refresh cost one light query
+	feedCacheControl = "public, max-age=300"
+)
+
+// FeedHandler serves Atom and RSS feeds of reports for people who follow an
+// area without using the API, such as local journalists
+type FeedHandler struct {
+	Reports      *services.ReportService
+	Translations *services.TranslationService
+
+	// PublicBaseURL is used for feed and entry links
+	PublicBaseURL string
+}
+
+func NewFeedHandler(reports *services.ReportService, translations *services.TranslationService, publicBaseURL string) *FeedHandler {
+	return &FeedHandler{Reports: reports, Translations: translations, PublicBaseURL: strings.TrimRight(publicBaseURL, "/")}
+}
+
+// ReportsFeed returns the handler for GET /feeds/reports.<format>: the
+// reports most recently created or changed in status, newest activity first,
+// narrowed by the state, city, category and status query parameters
+func (h *FeedHandler) ReportsFeed(format string) gin.HandlerFunc {
+	path := "GET /feeds/reports." + format
+	return func(c *gin.Context) {
+		filter := services.ReportFilter{
+			State:    c.Query("state"),
+			City:     c.Query("city"),
+			Category: c.Query("category"),
+			Status:   c.Query("status"),
+			Sort:     "activity",
+			Limit:    feedSize,
+		}
+		if filter.Status == "" {
+			// Moderated reports only, like the sitemap
+			filter.Statuses = services.SitemapStatuses
+		}
+		// Check the client's copy against a light query before loading
+		// the reports with their images and timelines
+		versions, err := h.Reports.ListReportVersions(c.Request.Context(), filter)
+		if err != nil {
+			utils.Error("%s - failed to list reports: %v", path, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not build feed.")})
+			return
+		}
+		var updated time.Time
+		for _, v := range versions {
+			if v.LastActivityAt.After(updated) {
+				updated = v.LastActivityAt
+			}
+		}
+		c.Header("Cache-Control", feedCacheControl)
+		if notModified(c, feedETag(c, format, versions), updated) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		reports, err := h.Reports.ListReports(c.Request.Context(), filter)
+		if err != nil {
+			utils.Error("%s - failed to list reports: %v", path, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not build feed.")})
+			return
+		}
+		if updated.IsZero() {
+			updated = time.Now()
+		}
+
+		feed := h.buildFeed(c, filter, reports, updated)
+		var body interface{}
+		if format == FeedRSS {
+			body = feed.rss()
+		} else {
+			body = feed.atom()
+		}
+		out, err := xml.Marshal(body)
+		if err != nil {
+			utils.Error("%s - failed to encode feed: %v", path, err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, feedContentTypes[format], append([]byte(xml.Header), out...))
+	}
+}
+
+// feedETag fingerprints everything a feed is rendered from, so it can be
+// checked before the reports are loaded
+func feedETag(c *gin.Context, format string, versions []services.ReportVersion) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s\n", format, middleware.Lang(c), c.Request.URL.RawQuery)
+	for _, v := range versions {
+		fmt.Fprintf(h, "%d|%s|%d|%s\n", v.ID, v.Status, v.LastActivityAt.UnixNano(), v.DescriptionHash)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// notModified sets the ETag and Last-Modified validators and reports whether
+// the request's If-None-Match or, failing that, If-Modified-Since shows the
+// client already has this version
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// reportFeed is a feed before it is encoded as Atom or RSS
+type reportFeed struct {
+	Site    string
+	Title   string
+	SelfURL string
+	SiteURL string
+	Lang    string
+	Updated time.Time
+	Entries []feedEntry
+}
+
+type feedEntry struct {
+	Title      string
+	URL        string
+	Published  time.Time
+	Updated    time.Time
+	Summary    string
+	Content    string // HTML
+	Categories []feedCategory
+	Latitude   float64
+	Longitude  float64
+}
+
+type feedCategory struct {
+	Scheme string
+	Term   string
+	Label  string
+}
+
+func (h *FeedHandler) buildFeed(c *gin.Context, filter services.ReportFilter, reports []models.Report, updated time.Time) *reportFeed {
+	names := categoryNames(c, h.Reports, h.Translations)
+
+	title := tr(c, "Reports")
+	if filter.Category != "" {
+		title = tr(c, "%s reports", categoryName(c, names, filter.Category))
+	}
+	var place []string
+	for _, p := range []string{filter.City, filter.State} {
+		if p != "" {
+			place = append(place, p)
+		}
+	}
+	if len(place) > 0 {
+		title = tr(c, "%s in %s", title, strings.Join(place, ", "))
+	}
+	if filter.Status != "" {
+		title = fmt.Sprintf("%s (%s)", title, statusLabel(c, filter.Status))
+	}
+
+	feed := &reportFeed{
+		Site:    tr(c, "Civic Infrastructure Reporting"),
+		Title:   title,
+		SelfURL: h.PublicBaseURL + c.Request.URL.RequestURI(),
+		SiteURL: h.PublicBaseURL + "/",
+		Lang:    middleware.Lang(c),
+		Updated: updated,
+		Entries: make([]feedEntry, len(reports)),
+	}
+	for i := range reports {
+		r := &reports[i]
+		category, status := categoryName(c, names, r.Category), statusLabel(c, r.Status)
+		entryTitle := tr(c, "%s reported", category)
+		if r.City != nil && *r.City != "" {
+			entryTitle = tr(c, "%s reported in %s", category, *r.City)
+		}
+		summary := r.Description
+		if summary == "" {
+			summary = tr(c, "Status: %s", status)
+		}
+		var content strings.Builder
+		if r.Description != "" {
+			fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(r.Description))
+		}
+		fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(tr(c, "Status: %s", status)))
+		fmt.Fprintf(&content, `<p><img src="%s" width="%d" height="%d" alt="%s"></p>`,
+			html.EscapeString(r.MapImageURL(h.PublicBaseURL)), services.StaticMapWidth, services.StaticMapHeight,
+			html.EscapeString(tr(c, "Map of the report location")))
+
+		feed.Entries[i] = feedEntry{
+			Title:     fmt.Sprintf("%s: %s", entryTitle, status),
+			URL:       r.GenerateShareURL(h.PublicBaseURL),
+			Published: r.CreatedAt,
+			Updated:   r.LastActivityAt,
+			Summary:   summary,
+			Content:   content.String(),
+			Categories: []feedCategory{
+				{Scheme: h.PublicBaseURL + "/categories", Term: r.Category, Label: category},
+				{Term: r.Status, Label: status},
+			},
+			Latitude:  r.Latitude,
+			Longitude: r.Longitude,
+		}
+	}
+	return feed
+}
+
+// Atom 1.0 (RFC 4287)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Point      string         `xml:"http://www.georss.org/georss point"`
+}
+
+type atomCategory struct {
+	Scheme string `xml:"scheme,attr,omitempty"`
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (f *reportFeed) atom() *atomFeed {
+	out := &atomFeed{
+		Lang:    f.Lang,
+		ID:      f.SelfURL,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.SiteURL},
+		},
+		Author:  atomAuthor{Name: f.Site},
+		Entries: make([]atomEntry, len(f.Entries)),
+	}
+	for i, e := range f.Entries {
+		out.Entries[i] = atomEntry{
+			ID:        e.URL,
+			Title:     e.Title,
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: e.URL},
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Summary:   atomText{Type: "text", Body: e.Summary},
+			Content:   atomText{Type: "html", Body: e.Content},
+			Point:     fmt.Sprintf("%g %g", e.Latitude, e.Longitude),
+		}
+		for _, cat := range e.Categories {
+			out.Entries[i].Categories = append(out.Entries[i].Categories, atomCategory(cat))
+		}
+	}
+	return out
+}
+
+// RSS 2.0 (https://www.rssboard.org/rss-specification). RSS has no updated
+// date, so every status change is a new item: the guid includes the last
+// activity and pubDate is its time.
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Categories  []rssCategory `xml:"category"`
+	Point       string        `xml:"http://www.georss.org/georss point"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssCategory struct {
+	Domain string `xml:"domain,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+func (f *reportFeed) rss() *rssFeed {
+	out := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.SiteURL,
+			Description:   f.Site + ": " + f.Title,
+			Language:      f.Lang,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+			Items:         make([]rssItem, len(f.Entries)),
+		},
+	}
+	for i, e := range f.Entries {
+		out.Channel.Items[i] = rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			Description: e.Content,
+			GUID:        rssGUID{Value: fmt.Sprintf("%s#%d", e.URL, e.Updated.Unix())},
+			PubDate:     e.Updated.UTC().Format(time.RFC1123Z),
+			Point:       fmt.Sprintf("%g %g", e.Latitude, e.Longitude),
+		}
+		for _, cat := range e.Categories {
+			out.Channel.Items[i].Categories = append(out.Channel.Items[i].Categories, rssCategory{Domain: cat.Scheme, Value: cat.Label})
+		}
+	}
+	return out
+}
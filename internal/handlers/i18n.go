@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// tr translates an English message into the request's language
+func tr(c *gin.Context, msg string, args ...interface{}) string {
+	return middleware.T(c, msg, args...)
+}
+
+// knownLocale reports whether the catalog can render notifications in
+// locale. An empty locale means the default one.
+func knownLocale(c *gin.Context, locale string) bool {
+	if locale == "" {
+		return true
+	}
+	for _, l := range middleware.Catalog(c).Locales() {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// pageData adds what every server-rendered page needs for translation: the
+// negotiated language (for <html lang> and the t template function) and its
+// messages for client-side scripts
+func pageData(c *gin.Context, data gin.H) gin.H {
+	lang := middleware.Lang(c)
+	data["Lang"] = lang
+	messages := middleware.Catalog(c).Messages(lang)
+	if messages == nil {
+		messages = map[string]string{}
+	}
+	data["Messages"] = messages
+	return data
+}
+
+// humanize turns a category or status such as "broken_streetlight" into an
+// English label, "Broken streetlight"
+func humanize(name string) string {
+	label := strings.ReplaceAll(name, "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// statusLabel names a report status in the request's language
+func statusLabel(c *gin.Context, status string) string {
+	return tr(c, humanize(status))
+}
+
+// localizedCategory returns cat with its name and description in the
+// request's language. Stored translations come first; a name without one
+// falls back to its English label translated through the message catalog.
+func localizedCategory(c *gin.Context, translations *services.TranslationService, cat models.Category) CategoryResponse {
+	lang, id := middleware.Lang(c), strconv.Itoa(cat.ID)
+	out := CategoryResponse{
+		Category:    cat,
+		DisplayName: translations.Translate(services.EntityCategory, id, "name", lang, tr(c, humanize(cat.Name))),
+	}
+	if cat.Description != nil {
+		description := translations.Translate(services.EntityCategory, id, "description", lang, *cat.Description)
+		out.Description = &description
+	}
+	return out
+}
+
+// categoryNames maps every category to its name in the request's language.
+// Categories that cannot be listed keep their English label.
+func categoryNames(c *gin.Context, reports *services.ReportService, translations *services.TranslationService) map[string]string {
+	names := map[string]string{}
+	categories, err := reports.ListCategories(c.Request.Context())
+	if err != nil {
+		utils.Error("%s %s - failed to list categories: %v", c.Request.Method, c.FullPath(), err)
+		return names
+	}
+	for _, cat := range categories {
+		names[cat.Name] = localizedCategory(c, translations, cat).DisplayName
+	}
+	return names
+}
+
+// categoryName names a category in the request's language, given the names
+// from categoryNames
+func categoryName(c *gin.Context, names map[string]string, category string) string {
+	if name, ok := names[category]; ok {
+		return name
+	}
+	return tr(c, humanize(category))
+}
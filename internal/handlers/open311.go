@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Open311Handler serves the GeoReport v2 API (http://wiki.open311.org/GeoReport_v2)
+// under /open311/v2. Categories are exposed as services and reports as
+// service requests. Every endpoint answers in JSON or XML depending on the
+// .json/.xml suffix.
+type Open311Handler struct {
+	Reports      *services.ReportService
+	Translations *services.TranslationService
+
+	// PublicBaseURL is used to build absolute media URLs
+	PublicBaseURL string
+}
+
+func NewOpen311Handler(reports *services.ReportService, translations *services.TranslationService, publicBaseURL string) *Open311Handler {
+	return &Open311Handler{Reports: reports, Translations: translations, PublicBaseURL: strings.TrimRight(publicBaseURL, "/")}
+}
+
+// Open311 only knows "open" and "closed"; our finer status goes in status_notes
+var open311Statuses = map[string][]string{
+	"open":   {"pending", "verified", "in_progress"},
+	"closed": {"resolved", "rejected"},
+}
+
+// open311Visible are the statuses listed when no status filter is given
+var open311Visible = []string{"pending", "verified", "in_progress", "resolved", "rejected"}
+
+// Open311 defaults for GET requests: the last 90 days, at most 1000 requests
+const (
+	open311DefaultWindow = 90 * 24 * time.Hour
+	open311MaxRequests   = 1000
+)
+
+type open311Service struct {
+	ServiceCode string `json:"service_code" xml:"service_code"`
+	ServiceName string `json:"service_name" xml:"service_name"`
+	Description string `json:"description" xml:"description"`
+	Metadata    bool   `json:"metadata" xml:"metadata"`
+	Type        string `json:"type" xml:"type"`
+	Keywords    string `json:"keywords" xml:"keywords"`
+	Group       string `json:"group" xml:"group"`
+}
+
+type open311ServiceList struct {
+	XMLName  xml.Name         `xml:"services"`
+	Services []open311Service `xml:"service"`
+}
+
+type open311Attribute struct{}
+
+type open311ServiceDefinition struct {
+	XMLName     xml.Name           `json:"-" xml:"service_definition"`
+	ServiceCode string             `json:"service_code" xml:"service_code"`
+	Attributes  []open311Attribute `json:"attributes" xml:"attributes>attribute"`
+}
+
+type open311Request struct {
+	ServiceRequestID  string  `json:"service_request_id" xml:"service_request_id"`
+	Status            string  `json:"status" xml:"status"`
+	StatusNotes       string  `json:"status_notes" xml:"status_notes"`
+	ServiceName       string  `json:"service_name" xml:"service_name"`
+	ServiceCode       string  `json:"service_code" xml:"service_code"`
+	Description       string  `json:"description" xml:"description"`
+	AgencyResponsible string  `json:"agency_responsible" xml:"agency_responsible"`
+	ServiceNotice     string  `json:"service_notice" xml:"service_notice"`
+	RequestedDatetime string  `json:"requested_datetime" xml:"requested_datetime"`
+	UpdatedDatetime   string  `json:"updated_datetime" xml:"updated_datetime"`
+	ExpectedDatetime  string  `json:"expected_datetime" xml:"expected_datetime"`
+	Address           string  `json:"address" xml:"address"`
+	AddressID         string  `json:"address_id" xml:"address_id"`
+	Zipcode           string  `json:"zipcode" xml:"zipcode"`
+	Lat               float64 `json:"lat" xml:"lat"`
+	Long              float64 `json:"long" xml:"long"`
+	MediaURL          string  `json:"media_url" xml:"media_url"`
+}
+
+type open311RequestList struct {
+	XMLName  xml.Name         `xml:"service_requests"`
+	Requests []open311Request `xml:"request"`
+}
+
+type open311Created struct {
+	ServiceRequestID string `json:"service_request_id" xml:"service_request_id"`
+	ServiceNotice    string `json:"service_notice" xml:"service_notice"`
+	AccountID        string `json:"account_id" xml:"account_id"`
+}
+
+type open311CreatedList struct {
+	XMLName  xml.Name         `xml:"service_requests"`
+	Requests []open311Created `xml:"request"`
+}
+
+type open311Error struct {
+	Code        int    `json:"code" xml:"code"`
+	Description string `json:"description" xml:"description"`
+}
+
+type open311ErrorList struct {
+	XMLName xml.Name       `xml:"errors"`
+	Errors  []open311Error `xml:"error"`
+}
+
+// splitFormat strips a .json or .xml suffix
+func splitFormat(s string) (name, format string, ok bool) {
+	for _, f := range []string{"json", "xml"} {
+		if strings.HasSuffix(s, "."+f) {
+			return strings.TrimSuffix(s, "."+f), f, true
+		}
+	}
+	return s, "", false
+}
+
+// respond writes jsonBody or xmlBody depending on the format
+func (h *Open311Handler) respond(c *gin.Context, status int, format string, jsonBody, xmlBody interface{}) {
+	if format != "xml" {
+		c.JSON(status, jsonBody)
+		return
+	}
+	out, err := xml.Marshal(xmlBody)
+	if err != nil {
+		utils.Error("%s %s - failed to encode XML: %v", c.Request.Method, c.FullPath(), err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, "text/xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
+
+func (h *Open311Handler) fail(c *gin.Context, status int, format, description string) {
+	errs := []open311Error{{Code: status, Description: description}}
+	h.respond(c, status, format, errs, open311ErrorList{Errors: errs})
+}
+
+// GET /open311/v2/services.{json,xml}
+func (h *Open311Handler) ListServices(c *gin.Context) {
+	_, format, _ := splitFormat(c.FullPath())
+	categories, err := h.Reports.ListCategories(c.Request.Context())
+	if err != nil {
+		utils.Error("GET /open311/v2/services - failed to list categories: %v", err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not list services.")
+		return
+	}
+	list := make([]open311Service, 0, len(categories))
+	for _, cat := range categories {
+		svc := open311Service{
+			ServiceCode: cat.Name,
+			ServiceName: humanize(cat.Name),
+			Metadata:    false,
+			Type:        "realtime",
+			Group:       "infrastructure",
+		}
+		if cat.Description != nil {
+			svc.Description = *cat.Description
+		}
+		// Translated names let clients match services in other languages
+		svc.Keywords = strings.Join(h.Translations.Values(services.EntityCategory, strconv.Itoa(cat.ID), "name"), ",")
+		list = append(list, svc)
+	}
+	h.respond(c, http.StatusOK, format, list, open311ServiceList{Services: list})
+}
+
+// GET /open311/v2/services/:code (code ends in .json or .xml)
+// Our services take no extra attributes.
+func (h *Open311Handler) GetService(c *gin.Context) {
+	code, format, ok := splitFormat(c.Param("code"))
+	if !ok {
+		h.fail(c, http.StatusNotFound, "json", "Unknown format.")
+		return
+	}
+	exists, err := h.Reports.CategoryExists(c.Request.Context(), code)
+	if err != nil {
+		utils.Error("GET /open311/v2/services/:code - failed to check category %s: %v", code, err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not load service.")
+		return
+	}
+	if !exists {
+		h.fail(c, http.StatusNotFound, format, "Service not found.")
+		return
+	}
+	def := open311ServiceDefinition{ServiceCode: code, Attributes: []open311Attribute{}}
+	h.respond(c, http.StatusOK, format, def, def)
+}
+
+// POST /open311/v2/requests.{json,xml}
+// Accepts the standard form fields; lat and long are required since we do not
+// geocode addresses. Submissions go through the same validation as POST /reports.
+func (h *Open311Handler) CreateRequest(c *gin.Context) {
+	_, format, _ := splitFormat(c.FullPath())
+	lat, latErr := strconv.ParseFloat(c.PostForm("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.PostForm("long"), 64)
+	if c.PostForm("service_code") == "" {
+		h.fail(c, http.StatusBadRequest, format, "service_code is required.")
+		return
+	}
+	if latErr != nil || lngErr != nil {
+		h.fail(c, http.StatusBadRequest, format, "lat and long are required.")
+		return
+	}
+	description := c.PostForm("description")
+	if addr := c.PostForm("address_string"); addr != "" {
+		if description != "" {
+			description += "\n\n"
+		}
+		description += "Address: " + addr
+	}
+	report := models.Report{
+		Category:    c.PostForm("service_code"),
+		Latitude:    lat,
+		Longitude:   lng,
+		Description: description,
+		ReporterIP:  c.ClientIP(),
+		Status:      "pending",
+	}
+	if err := h.Reports.ValidateNewReport(c.Request.Context(), &report); err != nil {
+		if errors.Is(err, services.ErrInvalidReport) {
+			h.fail(c, http.StatusBadRequest, format, err.Error())
+			return
+		}
+		utils.Error("POST /open311/v2/requests - failed to validate report: %v", err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not validate request.")
+		return
+	}
+	if _, err := h.Reports.CreateReport(c.Request.Context(), &report); err != nil {
+		utils.Error("POST /open311/v2/requests - failed to create report: %v", err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not create request.")
+		return
+	}
+	utils.Info("POST /open311/v2/requests - created report %d (%s)", report.ID, report.Category)
+	created := []open311Created{{
+		ServiceRequestID: strconv.Itoa(report.ID),
+		ServiceNotice:    "Your report will be reviewed by a moderator.",
+	}}
+	h.respond(c, http.StatusCreated, format, created, open311CreatedList{Requests: created})
+}
+
+// GET /open311/v2/requests.{json,xml}
+// Supports service_request_id (comma separated, overrides the other
+// filters), service_code, start_date, end_date and status (open, closed).
+func (h *Open311Handler) ListRequests(c *gin.Context) {
+	_, format, _ := splitFormat(c.FullPath())
+	filter := services.ReportFilter{Limit: open311MaxRequests}
+	if ids := c.Query("service_request_id"); ids != "" {
+		for _, part := range strings.Split(ids, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				h.fail(c, http.StatusBadRequest, format, "Invalid service_request_id.")
+				return
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+		filter.Statuses = open311Visible
+	} else {
+		filter.Category = c.Query("service_code")
+		if status := c.Query("status"); status != "" {
+			for _, s := range strings.Split(status, ",") {
+				mapped, ok := open311Statuses[strings.TrimSpace(s)]
+				if !ok {
+					h.fail(c, http.StatusBadRequest, format, "status must be open or closed.")
+					return
+				}
+				filter.Statuses = append(filter.Statuses, mapped...)
+			}
+		} else {
+			filter.Statuses = open311Visible
+		}
+		end := time.Now()
+		if v := c.Query("end_date"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				h.fail(c, http.StatusBadRequest, format, "end_date must be a W3C datetime.")
+				return
+			}
+			end = t
+		}
+		start := end.Add(-open311DefaultWindow)
+		if v := c.Query("start_date"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				h.fail(c, http.StatusBadRequest, format, "start_date must be a W3C datetime.")
+				return
+			}
+			start = t
+		}
+		filter.Since, filter.Until = &start, &end
+	}
+	reports, err := h.Reports.ListReports(c.Request.Context(), filter)
+	if err != nil {
+		utils.Error("GET /open311/v2/requests - failed to list reports: %v", err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not list requests.")
+		return
+	}
+	list := make([]open311Request, 0, len(reports))
+	for i := range reports {
+		list = append(list, h.toRequest(&reports[i]))
+	}
+	h.respond(c, http.StatusOK, format, list, open311RequestList{Requests: list})
+}
+
+// GET /open311/v2/requests/:id (id ends in .json or .xml)
+func (h *Open311Handler) GetRequest(c *gin.Context) {
+	raw, format, ok := splitFormat(c.Param("id"))
+	if !ok {
+		h.fail(c, http.StatusNotFound, "json", "Unknown format.")
+		return
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		h.fail(c, http.StatusBadRequest, format, "Invalid service_request_id.")
+		return
+	}
+	report, err := h.Reports.GetReportByID(c.Request.Context(), id)
+	if err != nil {
+		utils.Error("GET /open311/v2/requests/:id - failed to load report %d: %v", id, err)
+		h.fail(c, http.StatusInternalServerError, format, "Could not load request.")
+		return
+	}
+	// Merged reports live on as their canonical report; withdrawn ones are gone
+	if report != nil && report.MergedIntoID != nil {
+		report, err = h.Reports.GetReportByID(c.Request.Context(), *report.MergedIntoID)
+		if err != nil {
+			utils.Error("GET /open311/v2/requests/:id - failed to load canonical report for %d: %v", id, err)
+			h.fail(c, http.StatusInternalServerError, format, "Could not load request.")
+			return
+		}
+	}
+	if report == nil || report.Status == "withdrawn" {
+		h.fail(c, http.StatusNotFound, format, "Request not found.")
+		return
+	}
+	list := []open311Request{h.toRequest(report)}
+	h.respond(c, http.StatusOK, format, list, open311RequestList{Requests: list})
+}
+
+// toRequest maps a report to an Open311 service request
+func (h *Open311Handler) toRequest(r *models.Report) open311Request {
+	req := open311Request{
+		ServiceRequestID:  strconv.Itoa(r.ID),
+		Status:            "closed",
+		StatusNotes:       strings.ReplaceAll(r.Status, "_", " "),
+		ServiceName:       humanize(r.Category),
+		ServiceCode:       r.Category,
+		Description:       r.Description,
+		RequestedDatetime: r.CreatedAt.Format(time.RFC3339),
+		UpdatedDatetime:   r.CreatedAt.Format(time.RFC3339),
+		Lat:               r.Latitude,
+		Long:              r.Longitude,
+	}
+	if r.IsOpen() {
+		req.Status = "open"
+	}
+	var latest *models.StatusUpdate
+	for i := range r.StatusUpdates {
+		if latest == nil || r.StatusUpdates[i].UpdatedAt.After(latest.UpdatedAt) {
+			latest = &r.StatusUpdates[i]
+		}
+	}
+	if latest != nil {
+		req.UpdatedDatetime = latest.UpdatedAt.Format(time.RFC3339)
+		if latest.Notes != nil && *latest.Notes != "" {
+			req.StatusNotes += ": " + *latest.Notes
+		}
+	}
+	var place []string
+	if r.City != nil {
+		place = append(place, *r.City)
+	}
+	if r.State != nil {
+		place = append(place, *r.State)
+	}
+	req.Address = strings.Join(place, ", ")
+	for _, img := range r.Images {
+		if img.ImageType == models.ImageTypeReport && img.ModerationStatus == "approved" {
+			req.MediaURL = img.CloudinaryURL
+			if strings.HasPrefix(req.MediaURL, "/") {
+				req.MediaURL = h.PublicBaseURL + req.MediaURL
+			}
+			break
+		}
+	}
+	return req
+}
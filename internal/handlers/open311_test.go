@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+)
+
+func TestOpen311StatusMapping(t *testing.T) {
+	h := NewOpen311Handler(nil, nil, "https://helpgovern.example")
+	canonical := 12
+	for _, tc := range []struct {
+		report models.Report
+		status string
+		notes  string
+	}{
+		{models.Report{Status: "pending"}, "open", "pending"},
+		{models.Report{Status: "verified"}, "open", "verified"},
+		{models.Report{Status: "in_progress"}, "open", "in progress"},
+		{models.Report{Status: "resolved"}, "closed", "resolved"},
+		{models.Report{Status: "rejected"}, "closed", "rejected"},
+		{models.Report{Status: "verified", MergedIntoID: &canonical}, "closed", "verified"},
+	} {
+		req := h.toRequest(&tc.report)
+		if req.Status != tc.status || req.StatusNotes != tc.notes {
+			t.Errorf("%s (merged: %v) maps to %s %q, want %s %q", tc.report.Status, tc.report.MergedIntoID != nil, req.Status, req.StatusNotes, tc.status, tc.notes)
+		}
+	}
+}
+
+func TestOpen311StatusNotesFromLatestUpdate(t *testing.T) {
+	h := NewOpen311Handler(nil, nil, "https://helpgovern.example")
+	created := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	older, latest := "Crew assigned", "Road relaid"
+	report := models.Report{Status: "resolved", CreatedAt: created, StatusUpdates: []models.StatusUpdate{
+		{NewStatus: "resolved", Notes: &latest, UpdatedAt: created.Add(48 * time.Hour)},
+		{NewStatus: "in_progress", Notes: &older, UpdatedAt: created.Add(24 * time.Hour)},
+	}}
+
+	req := h.toRequest(&report)
+	if req.StatusNotes != "resolved: Road relaid" || req.UpdatedDatetime != "2025-06-03T09:00:00Z" {
+		t.Errorf("status_notes %q, updated_datetime %s; want the latest update's", req.StatusNotes, req.UpdatedDatetime)
+	}
+}
+
+func open311Router(t *testing.T) (*gin.Engine, *fakedb.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, fake := fakedb.New(t)
+	h := NewOpen311Handler(services.NewReportService(db, &config.Config{}, services.NewEventBus()), nil, "https://helpgovern.example")
+	r := gin.New()
+	r.GET("/open311/v2/requests.json", h.ListRequests)
+	r.GET("/open311/v2/requests/:id", h.GetRequest)
+	return r, fake
+}
+
+func TestOpen311ListRequestsStatusFilter(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		statuses []string
+	}{
+		{"", []string{"pending", "verified", "in_progress", "resolved", "rejected"}},
+		{"?status=open", []string{"pending", "verified", "in_progress"}},
+		{"?status=closed", []string{"resolved", "rejected"}},
+		{"?status=open,closed", []string{"pending", "verified", "in_progress", "resolved", "rejected"}},
+	} {
+		r, fake := open311Router(t)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/open311/v2/requests.json"+tc.query, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%q: %d %s", tc.query, w.Code, w.Body)
+			continue
+		}
+		q := fake.Queried(`SELECT * FROM "reports"`)[0]
+		got := map[string]bool{}
+		for _, a := range q.Args {
+			if s, ok := a.(string); ok {
+				got[s] = true
+			}
+		}
+		if len(got) != len(tc.statuses) {
+			t.Errorf("%q lists statuses %v, want %v", tc.query, got, tc.statuses)
+		}
+		for _, s := range tc.statuses {
+			if !got[s] {
+				t.Errorf("%q does not list %s reports", tc.query, s)
+			}
+		}
+	}
+
+	r, _ := open311Router(t)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/open311/v2/requests.json?status=withdrawn", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status=withdrawn: %d, want 400", w.Code)
+	}
+}
+
+func TestOpen311GetRequest(t *testing.T) {
+	columns := []string{"id", "category", "status", "merged_into_id", "created_at"}
+	t.Run("merged", func(t *testing.T) {
+		r, fake := open311Router(t)
+		fake.SetRows("reports", columns, []driver.Value{int64(13), "potholes", "pending", int64(12), time.Now()})
+		fake.SetRows("reports", columns, []driver.Value{int64(12), "potholes", "in_progress", nil, time.Now()})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/open311/v2/requests/13.json", nil))
+		var got []open311Request
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got) != 1 {
+			t.Fatalf("%d %s", w.Code, w.Body)
+		}
+		if got[0].ServiceRequestID != "12" || got[0].Status != "open" {
+			t.Errorf("got request %s %s, want the canonical report 12, open", got[0].ServiceRequestID, got[0].Status)
+		}
+	})
+	t.Run("withdrawn", func(t *testing.T) {
+		r, fake := open311Router(t)
+		fake.SetRows("reports", columns, []driver.Value{int64(13), "potholes", "withdrawn", nil, time.Now()})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/open311/v2/requests/13.xml", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("withdrawn request: %d, want 404", w.Code)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// PageHandler serves the server-rendered pages other than the map
+type PageHandler struct {
+	Reports            *services.ReportService
+	Translations       *services.TranslationService
+	ReportTranslations *services.ReportTranslationService
+
+	// PublicBaseURL is used for absolute links in share previews
+	PublicBaseURL string
+}
+
+func NewPageHandler(reports *services.ReportService, translations *services.TranslationService, reportTranslations *services.ReportTranslationService, publicBaseURL string) *PageHandler {
+	return &PageHandler{
+		Reports:            reports,
+		Translations:       translations,
+		ReportTranslations: reportTranslations,
+		PublicBaseURL:      strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// timelineEntry is one status change as shown on the report page
+type timelineEntry struct {
+	Status string
+	Notes  string
+	At     string
+}
+
+// GET /r/:slug
+// Public share link: the report page, with Open Graph and Twitter card tags
+// so shared links show a rich preview
+func (h *PageHandler) ReportPage(c *gin.Context) {
+	report, err := h.Reports.GetReportBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		utils.Error("GET /r/:slug - failed to fetch report: %v", err)
+		c.String(http.StatusInternalServerError, tr(c, "Failed to fetch report"))
+		return
+	}
+	if report == nil || report.Status == "withdrawn" {
+		c.String(http.StatusNotFound, tr(c, "Report not found"))
+		return
+	}
+	if report.MergedIntoID != nil {
+		canonical, err := h.Reports.GetReportByID(c.Request.Context(), *report.MergedIntoID)
+		if err == nil && canonical != nil {
+			c.Redirect(http.StatusMovedPermanently, "/r/"+canonical.ShareSlug)
+			return
+		}
+	}
+
+	category := categoryName(c, categoryNames(c, h.Reports, h.Translations), report.Category)
+	title := tr(c, "%s reported", category)
+	if report.City != nil && *report.City != "" {
+		title = tr(c, "%s reported in %s", category, *report.City)
+	}
+
+	description, original := report.Description, ""
+	translation, err := h.ReportTranslations.Localize(c.Request.Context(), report, middleware.Lang(c))
+	if err != nil {
+		utils.Error("GET /r/:slug - failed to load translation of report %d: %v", report.ID, err)
+	}
+	if translation != nil {
+		description, original = translation.Description, report.Description
+	}
+	summary := utils.Truncate(description, 200)
+	if summary == "" {
+		summary = tr(c, "Status: %s", statusLabel(c, report.Status))
+	}
+
+	var photos []models.Image
+	for _, img := range report.Images {
+		if img.ModerationStatus == "approved" {
+			photos = append(photos, img)
+		}
+	}
+	timeline := make([]timelineEntry, len(report.StatusUpdates))
+	for i, u := range report.StatusUpdates {
+		timeline[i] = timelineEntry{Status: statusLabel(c, u.NewStatus), At: u.UpdatedAt.Format("2 Jan 2006")}
+		if u.Notes != nil {
+			timeline[i].Notes = *u.Notes
+		}
+	}
+
+	image := report.MapImageURL(h.PublicBaseURL)
+	if len(photos) > 0 {
+		image = h.absoluteURL(photos[0].CloudinaryURL)
+	}
+
+	c.HTML(http.StatusOK, "report.html", pageData(c, gin.H{
+		"Report":              report,
+		"NoIndex":             !services.SitemapListed(report.Status),
+		"Title":               title,
+		"Category":            category,
+		"Status":              statusLabel(c, report.Status),
+		"Description":         description,
+		"OriginalDescription": original,
+		"Summary":             summary,
+		"Photos":              photos,
+		"Timeline":            timeline,
+		"ShareURL":            report.GenerateShareURL(h.PublicBaseURL),
+		"ImageURL":            image,
+		"MapURL":              report.MapImageURL(""),
+		"MapWidth":            services.StaticMapWidth,
+		"MapHeight":           services.StaticMapHeight,
+	}))
+}
+
+// absoluteURL prefixes site-relative URLs, such as locally stored uploads,
+// with the public base URL; crawlers need absolute image URLs
+func (h *PageHandler) absoluteURL(url string) string {
+	if strings.HasPrefix(url, "/") {
+		return h.PublicBaseURL + url
+	}
+	return url
+}
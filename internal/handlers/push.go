@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type PushHandler struct {
+	Service *services.SubscriptionService
+
+	// VAPIDPublicKey is handed to browsers as the applicationServerKey.
+	// Empty when web push is not configured.
+	VAPIDPublicKey string
+}
+
+func NewPushHandler(service *services.SubscriptionService, vapidPublicKey string) *PushHandler {
+	return &PushHandler{Service: service, VAPIDPublicKey: vapidPublicKey}
+}
+
+// GET /push/vapid-public-key
+func (h *PushHandler) VAPIDKey(c *gin.Context) {
+	if h.VAPIDPublicKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "UNAVAILABLE", "details": tr(c, "Push notifications are not configured.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"public_key": h.VAPIDPublicKey})
+}
+
+// PushSubscribeRequest registers a browser push subscription against either
+// a report or an area
+type PushSubscribeRequest struct {
+	Subscription PushSubscription `json:"subscription" binding:"required"`
+	ReportID     *int             `json:"report_id"`
+	Area         *AreaRequest     `json:"area"`
+	Locale       string           `json:"locale" binding:"omitempty,max=10"`
+}
+
+// POST /push/subscriptions
+func (h *PushHandler) Subscribe(c *gin.Context) {
+	if h.VAPIDPublicKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "UNAVAILABLE", "details": tr(c, "Push notifications are not configured.")})
+		return
+	}
+	var req PushSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /push/subscriptions - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if !knownLocale(c, req.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Unsupported locale.")})
+		return
+	}
+	if !strings.HasPrefix(req.Subscription.Endpoint, "https://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Push endpoint must use https.")})
+		return
+	}
+	if (req.ReportID == nil) == (req.Area == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Provide either report_id or area.")})
+		return
+	}
+	sub := models.Subscription{
+		Channel:      models.ChannelWebPush,
+		PushEndpoint: &req.Subscription.Endpoint,
+		PushP256dh:   &req.Subscription.Keys.P256dh,
+		PushAuth:     &req.Subscription.Keys.Auth,
+		Locale:       req.Locale,
+	}
+	var err error
+	if req.ReportID != nil {
+		err = h.Service.SubscribeReport(c.Request.Context(), *req.ReportID, &sub)
+	} else {
+		req.Area.apply(&sub)
+		err = h.Service.SubscribeArea(c.Request.Context(), &sub)
+	}
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case errors.Is(err, services.ErrInvalidArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /push/subscriptions - failed to subscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not save subscription.")})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                sub.ID,
+		"report_id":         sub.ReportID,
+		"unsubscribe_token": sub.UnsubscribeToken,
+	})
+}
+
+// PushUnsubscribeRequest removes a browser's subscriptions: the one for
+// ReportID when given, otherwise all of them
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	ReportID *int   `json:"report_id"`
+}
+
+// DELETE /push/subscriptions
+func (h *PushHandler) Unsubscribe(c *gin.Context) {
+	var req PushUnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("DELETE /push/subscriptions - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	removed, err := h.Service.UnsubscribePush(c.Request.Context(), req.Endpoint, req.ReportID)
+	if err != nil {
+		utils.Error("DELETE /push/subscriptions - failed to unsubscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not remove subscription.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
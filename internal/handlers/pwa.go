@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/assets"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+)
+
+// Hashed assets never change under the same name
+const assetCacheControl = "public, max-age=31536000, immutable"
+
+// PWAHandler makes the site installable and usable offline: the web app
+// manifest, the service worker, the offline fallback page and the hashed
+// static assets the service worker precaches
+type PWAHandler struct {
+	Assets *assets.Manifest
+
+	// serviceWorker is rendered once at startup; its cache name changes
+	// with every release, which makes browsers install the new one
+	serviceWorker []byte
+}
+
+// NewPWAHandler renders the service worker from the text template at
+// swTemplate
+func NewPWAHandler(manifest *assets.Manifest, swTemplate string) (*PWAHandler, error) {
+	source, err := os.ReadFile(swTemplate)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("sw.js").Funcs(template.FuncMap{"asset": manifest.URL}).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+	// The worker itself is part of the release too
+	sum := sha256.Sum256(append([]byte(manifest.Version), source...))
+	precache := append([]string{"/offline"}, manifest.URLs("css", "js", "icons")...)
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"Version":  hex.EncodeToString(sum[:6]),
+		"Precache": precache,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PWAHandler{Assets: manifest, serviceWorker: buf.Bytes()}, nil
+}
+
+// webAppManifest is the subset of https://www.w3.org/TR/appmanifest/ we use
+type webAppManifest struct {
+	Name            string           `json:"name"`
+	ShortName       string           `json:"short_name"`
+	Description     string           `json:"description"`
+	Lang            string           `json:"lang"`
+	StartURL        string           `json:"start_url"`
+	Scope           string           `json:"scope"`
+	Display         string           `json:"display"`
+	BackgroundColor string           `json:"background_color"`
+	ThemeColor      string           `json:"theme_color"`
+	Icons           []webAppIcon     `json:"icons"`
+	Shortcuts       []webAppShortcut `json:"shortcuts,omitempty"`
+}
+
+type webAppIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+type webAppShortcut struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// GET /manifest.webmanifest
+// The web app manifest, in the request's language
+func (h *PWAHandler) Manifest(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("Content-Type", "application/manifest+json")
+	c.JSON(http.StatusOK, webAppManifest{
+		Name:            tr(c, "Civic Infrastructure Reporting"),
+		ShortName:       tr(c, "Help Govern"),
+		Description:     tr(c, "Report potholes, broken streetlights and other civic issues"),
+		Lang:            middleware.Lang(c),
+		StartURL:        "/?lang=" + middleware.Lang(c),
+		Scope:           "/",
+		Display:         "standalone",
+		BackgroundColor: "#f7f7f7",
+		ThemeColor:      "#2c3e50",
+		Icons: []webAppIcon{
+			{Src: h.Assets.URL("icons/icon.svg"), Sizes: "any", Type: "image/svg+xml", Purpose: "any"},
+			{Src: h.Assets.URL("icons/icon-maskable.svg"), Sizes: "any", Type: "image/svg+xml", Purpose: "maskable"},
+		},
+		Shortcuts: []webAppShortcut{
+			{Name: tr(c, "Report an Issue"), URL: "/#report-form"},
+		},
+	})
+}
+
+// GET /sw.js
+// Served from the root so the worker controls the whole site. It must not
+// be cached long, or browsers would miss new releases.
+func (h *PWAHandler) ServiceWorker(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Service-Worker-Allowed", "/")
+	c.Data(http.StatusOK, "text/javascript; charset=utf-8", h.serviceWorker)
+}
+
+// GET /offline
+// Shown by the service worker when a page cannot be loaded
+func (h *PWAHandler) Offline(c *gin.Context) {
+	c.HTML(http.StatusOK, "offline.html", pageData(c, gin.H{}))
+}
+
+// GET /assets/*file
+// Static files under their hashed names, see package assets
+func (h *PWAHandler) Asset(c *gin.Context) {
+	file, ok := h.Assets.File(strings.TrimPrefix(c.Param("file"), "/"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "File not found.")})
+		return
+	}
+	c.Header("Cache-Control", assetCacheControl)
+	c.File(file)
+}
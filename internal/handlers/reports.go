@@ -1,33 +1,48 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
 	"github.com/projects-for-public/help-govern/internal/models"
 	"github.com/projects-for-public/help-govern/internal/services"
 	"github.com/projects-for-public/help-govern/internal/utils"
 )
 
 type ReportHandler struct {
-	Service *services.ReportService
+	Service      *services.ReportService
+	Images       *services.ImageService
+	Tracking     *services.TrackingService
+	Translations *services.ReportTranslationService
+
+	// PublicBaseURL is used to build absolute share links
+	PublicBaseURL string
 }
 
-func NewReportHandler(service *services.ReportService) *ReportHandler {
-	return &ReportHandler{Service: service}
+func NewReportHandler(service *services.ReportService, images *services.ImageService, tracking *services.TrackingService, translations *services.ReportTranslationService, publicBaseURL string) *ReportHandler {
+	return &ReportHandler{Service: service, Images: images, Tracking: tracking, Translations: translations, PublicBaseURL: publicBaseURL}
 }
 
 // ReportCreateRequest is the expected payload for report submission
 // Only fields relevant to submission are included
 // Validation tags are used for Gin binding
-// Category is validated against the DB in the handler
+// Coordinates and category are checked by ReportService.ValidateNewReport
 // Images are ignored for now
+// IgnoreDuplicates is set once the reporter has seen the duplicate candidates
+// and confirmed their issue is a new one
+// Track asks for a tracking token to follow the report anonymously
 type ReportCreateRequest struct {
-	Category    string  `json:"category" binding:"required"`
-	Latitude    float64 `json:"latitude" binding:"required"`
-	Longitude   float64 `json:"longitude" binding:"required"`
-	Description string  `json:"description"`
+	Category         string  `json:"category" binding:"required"`
+	Latitude         float64 `json:"latitude" binding:"required"`
+	Longitude        float64 `json:"longitude" binding:"required"`
+	Description      string  `json:"description"`
+	IgnoreDuplicates bool    `json:"ignore_duplicates"`
+	Track            bool    `json:"track"`
 }
 
 // POST /reports
@@ -37,59 +52,144 @@ func (h *ReportHandler) CreateReport(c *gin.Context) {
 		utils.Error("POST /reports - validation failed: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "VALIDATION_ERROR",
-			"details": err.Error(),
+			"details": tr(c, err.Error()),
 		})
 		return
 	}
-	// Validate latitude/longitude bounds
-	if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
-		utils.Error("POST /reports - invalid coordinates: lat=%v, lng=%v", req.Latitude, req.Longitude)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "VALIDATION_ERROR",
-			"details": "Invalid latitude or longitude.",
-		})
+	report, ok := h.newReport(c, "POST /reports", req)
+	if !ok {
 		return
 	}
-	// Validate category exists
-	exists, err := h.Service.CategoryExists(c.Request.Context(), req.Category)
+	// Offer nearby open reports of the same category before creating a new pin
+	if !req.IgnoreDuplicates {
+		candidates, err := h.Service.FindDuplicateCandidates(c.Request.Context(), req.Category, req.Latitude, req.Longitude)
+		if err != nil {
+			utils.Error("POST /reports - failed to check duplicates: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "INTERNAL_ERROR",
+				"details": tr(c, "Could not check for duplicate reports."),
+			})
+			return
+		}
+		if len(candidates) > 0 {
+			utils.Info("POST /reports - %d possible duplicates for %s at %v,%v", len(candidates), req.Category, req.Latitude, req.Longitude)
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "POSSIBLE_DUPLICATE",
+				"details":    tr(c, "Similar issues have already been reported nearby. Is this the same issue?"),
+				"candidates": candidates,
+			})
+			return
+		}
+	}
+	// Create the report
+	editToken, err := h.Service.CreateReport(c.Request.Context(), &report)
 	if err != nil {
-		utils.Error("POST /reports - failed to check category: %v", err)
+		utils.Error("POST /reports - failed to create report: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "INTERNAL_ERROR",
-			"details": "Could not validate category.",
+			"details": tr(c, err.Error()),
 		})
 		return
 	}
-	if !exists {
-		utils.Error("POST /reports - category not found: %s", req.Category)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "VALIDATION_ERROR",
-			"details": "Invalid category.",
-		})
+	c.JSON(http.StatusCreated, h.submittedResponse(c, "POST /reports", &report, editToken, req.Track))
+}
+
+// ReportDraftRequest is a report drafted offline and synced later, see
+// POST /reports/drafts. SubmissionID is generated by the client when the
+// draft is saved and stays the same across retries.
+type ReportDraftRequest struct {
+	ReportCreateRequest
+	SubmissionID string `json:"submission_id" binding:"required,uuid"`
+}
+
+// POST /reports/drafts
+// Background sync of reports drafted offline. Sending the same submission
+// ID again returns the report created the first time, without its edit
+// token, and never creates a second one. Nobody is around to look at
+// possible duplicates, so they are left to moderators to merge.
+func (h *ReportHandler) SyncDraft(c *gin.Context) {
+	var req ReportDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /reports/drafts - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
 		return
 	}
-	// Create the report
-	report := models.Report{
+	report, ok := h.newReport(c, "POST /reports/drafts", req.ReportCreateRequest)
+	if !ok {
+		return
+	}
+	editToken, created, err := h.Service.CreateDraftReport(c.Request.Context(), &report, req.SubmissionID)
+	if errors.Is(err, services.ErrSubmissionConflict) {
+		utils.Info("POST /reports/drafts - submission ID belongs to a volunteer's report")
+		c.JSON(http.StatusConflict, gin.H{"error": "SUBMISSION_CONFLICT", "details": tr(c, "This submission ID was already used for another report.")})
+		return
+	}
+	if err != nil {
+		utils.Error("POST /reports/drafts - failed to create report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not save report.")})
+		return
+	}
+	if !created {
+		utils.Info("POST /reports/drafts - submission already received as report %d", report.ID)
+		c.JSON(http.StatusOK, h.submittedResponse(c, "POST /reports/drafts", &report, editToken, req.Track))
+		return
+	}
+	c.JSON(http.StatusCreated, h.submittedResponse(c, "POST /reports/drafts", &report, editToken, req.Track))
+}
+
+// newReport builds and validates a report from a submission. It writes the
+// error response and returns ok=false when the submission is invalid.
+func (h *ReportHandler) newReport(c *gin.Context, path string, req ReportCreateRequest) (report models.Report, ok bool) {
+	report = models.Report{
 		Category:    req.Category,
 		Latitude:    req.Latitude,
 		Longitude:   req.Longitude,
 		Description: req.Description,
+		ReporterIP:  c.ClientIP(),
 		Status:      "pending",
 	}
-	if err := h.Service.CreateReport(c.Request.Context(), &report); err != nil {
-		utils.Error("POST /reports - failed to create report: %v", err)
+	if err := h.Service.ValidateNewReport(c.Request.Context(), &report); err != nil {
+		if errors.Is(err, services.ErrInvalidReport) {
+			utils.Error("%s - %v: category=%s, lat=%v, lng=%v", path, err, req.Category, req.Latitude, req.Longitude)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "VALIDATION_ERROR",
+				"details": tr(c, err.Error()),
+			})
+			return report, false
+		}
+		utils.Error("%s - failed to validate report: %v", path, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "INTERNAL_ERROR",
-			"details": err.Error(),
+			"details": tr(c, "Could not validate report."),
 		})
-		return
+		return report, false
 	}
-	shareURL := report.GenerateShareURL()
-	c.JSON(http.StatusCreated, gin.H{
-		"id":        report.ID,
-		"share_url": shareURL,
-		"message":   "Report submitted successfully",
-	})
+	return report, true
+}
+
+// submittedResponse is the reply to a submitted report. edit_token and
+// tracking_token are only ever returned here; the client must keep them.
+func (h *ReportHandler) submittedResponse(c *gin.Context, path string, report *models.Report, editToken string, track bool) gin.H {
+	resp := gin.H{
+		"id":         report.ID,
+		"share_url":  report.GenerateShareURL(h.PublicBaseURL),
+		"share_slug": report.ShareSlug,
+		"message":    tr(c, "Report submitted successfully"),
+	}
+	// A replayed submission gets no token; the first response had it
+	if editToken != "" {
+		resp["edit_token"] = editToken
+	}
+	if track {
+		trackingToken, err := h.Tracking.IssueToken(c.Request.Context(), report.ID)
+		if err != nil {
+			// The report exists; don't fail the submission over follow-up
+			utils.Error("%s - failed to issue tracking token for report %d: %v", path, report.ID, err)
+		} else {
+			resp["tracking_token"] = trackingToken
+		}
+	}
+	return resp
 }
 
 // GET /reports/:id
@@ -103,7 +203,7 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 	report, err := h.Service.GetReportByID(c.Request.Context(), id)
 	if err != nil {
 		utils.Error("GET /reports/:id - failed to fetch report: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report", "details": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report", "details": tr(c, err.Error())})
 		return
 	}
 	if report == nil {
@@ -111,55 +211,347 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 		return
 	}
-	c.JSON(http.StatusOK, report)
+	if report.MergedIntoID != nil {
+		c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("/reports/%d", *report.MergedIntoID))
+		return
+	}
+	if report.Status == "withdrawn" {
+		c.JSON(http.StatusGone, gin.H{"error": "GONE", "details": tr(c, "This report was withdrawn by its reporter.")})
+		return
+	}
+	resp := LocalizedReport{Report: report, Description: report.Description}
+	translation, err := h.Translations.Localize(c.Request.Context(), report, middleware.Lang(c))
+	if err != nil {
+		// The original description is still useful; serve it
+		utils.Error("GET /reports/:id - failed to load translation of report %d: %v", id, err)
+	}
+	if translation != nil {
+		resp.Description = translation.Description
+		resp.OriginalDescription = &report.Description
+		resp.TranslatedTo = translation.Locale
+		resp.TranslationEngine = translation.Engine
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// LocalizedReport is a report with its description machine translated into
+// the reader's language when it was written in another one.
+// OriginalDescription then keeps the text as submitted.
+type LocalizedReport struct {
+	*models.Report
+	Description         string  `json:"description"`
+	OriginalDescription *string `json:"original_description,omitempty"`
+	TranslatedTo        string  `json:"translated_to,omitempty"`
+	TranslationEngine   string  `json:"translation_engine,omitempty"`
+}
+
+// AddImagesRequest is the payload for POST /reports/:id/images
+type AddImagesRequest struct {
+	Images []string `json:"images" binding:"required,min=1"`
+}
+
+// POST /reports/:id/images
+// Reporter-only: requires the edit token in the X-Edit-Token header
+func (h *ReportHandler) AddImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /reports/:id/images - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	var req AddImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /reports/:id/images - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	report, ok := h.authorizeReporter(c, id)
+	if !ok {
+		return
+	}
+	images, err := services.DecodeImages(req.Images)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	saved, err := h.Images.AddReportImages(c.Request.Context(), report, images)
+	switch {
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open.")})
+		return
+	case errors.Is(err, services.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /reports/:id/images - failed to add images to report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not upload images.")})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"images": saved})
+}
+
+// WithdrawReportRequest is the optional payload for POST /reports/:id/withdraw
+type WithdrawReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+// POST /reports/:id/withdraw
+// Reporter-only: requires the edit token in the X-Edit-Token header
+func (h *ReportHandler) WithdrawReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /reports/:id/withdraw - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	var req WithdrawReportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+			return
+		}
+	}
+	report, ok := h.authorizeReporter(c, id)
+	if !ok {
+		return
+	}
+	report, err = h.Service.WithdrawReport(c.Request.Context(), report, req.Reason)
+	switch {
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open.")})
+		return
+	case err != nil:
+		utils.Error("POST /reports/:id/withdraw - failed to withdraw report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not withdraw report.")})
+		return
+	}
+	utils.Info("POST /reports/:id/withdraw - report %d withdrawn by reporter", id)
+	c.JSON(http.StatusOK, gin.H{"id": report.ID, "status": report.Status})
+}
+
+// authorizeReporter checks the X-Edit-Token header against the report and
+// writes the error response when it doesn't match
+func (h *ReportHandler) authorizeReporter(c *gin.Context, id int) (*models.Report, bool) {
+	report, err := h.Service.AuthorizeReporter(c.Request.Context(), id, c.GetHeader("X-Edit-Token"))
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return nil, false
+	case errors.Is(err, services.ErrInvalidToken):
+		c.JSON(http.StatusForbidden, gin.H{"error": "FORBIDDEN", "details": tr(c, "Invalid edit token.")})
+		return nil, false
+	case err != nil:
+		utils.Error("%s %s - failed to authorize reporter for report %d: %v", c.Request.Method, c.FullPath(), id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not verify edit token.")})
+		return nil, false
+	}
+	return report, true
 }
 
 // GET /reports
+// Query params: category, status, min_confirmations, sort (created_at|confirmations|priority), limit
 func (h *ReportHandler) ListReports(c *gin.Context) {
-	reports, err := h.Service.ListReports(c.Request.Context())
+	filter := services.ReportFilter{
+		Category: c.Query("category"),
+		Status:   c.Query("status"),
+		Sort:     c.DefaultQuery("sort", "created_at"),
+	}
+	switch filter.Sort {
+	case "created_at", "confirmations", "priority":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid sort.")})
+		return
+	}
+	var err error
+	if filter.MinConfirmations, err = queryInt(c, "min_confirmations", 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid min_confirmations.")})
+		return
+	}
+	if filter.Limit, err = queryInt(c, "limit", 100); err != nil || filter.Limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid limit.")})
+		return
+	}
+	reports, err := h.Service.ListReports(c.Request.Context(), filter)
 	if err != nil {
 		utils.Error("GET /reports - failed to list reports: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list reports", "details": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list reports", "details": tr(c, err.Error())})
 		return
 	}
 	c.JSON(http.StatusOK, reports)
 }
 
+// POST /reports/:id/confirmations
+// Anonymous "this affects me too". A device is identified by the optional
+// X-Device-ID header, falling back to the client IP.
+func (h *ReportHandler) ConfirmReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /reports/:id/confirmations - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	// The device ID only adds a key: a new one from a known IP still counts
+	// once
+	var device string
+	if d := c.GetHeader("X-Device-ID"); d != "" {
+		device = utils.HashToken("device:" + d)
+	}
+	count, created, err := h.Service.ConfirmReport(c.Request.Context(), id, utils.HashToken("ip:"+c.ClientIP()), device)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open.")})
+		return
+	case err != nil:
+		utils.Error("POST /reports/:id/confirmations - failed to confirm report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not record confirmation.")})
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"id":                 id,
+		"confirmation_count": count,
+		"already_confirmed":  !created,
+	})
+}
+
+// ReportUpdateRequest is the payload for PUT /reports/:id. Omitted fields
+// are left unchanged.
+type ReportUpdateRequest struct {
+	Description *string `json:"description"`
+	Category    *string `json:"category"`
+}
+
 // PUT /reports/:id
+// Requires the edit token in the X-Edit-Token header, or the admin token
 func (h *ReportHandler) UpdateReport(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.Error("PUT /reports/:id - invalid report ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
 		return
 	}
-	var req models.Report
+	var req ReportUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error("PUT /reports/:id - validation failed: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
 		return
 	}
-	req.ID = id
-	if err := h.Service.UpdateReport(c.Request.Context(), &req); err != nil {
-		utils.Error("PUT /reports/:id - failed to update report: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report", "details": err.Error()})
+	report, ok := h.authorizeEditor(c, id)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, req)
+	report, err = h.Service.UpdateReport(c.Request.Context(), report, services.ReportChanges{
+		Description: req.Description,
+		Category:    req.Category,
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidReport):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open.")})
+		return
+	case err != nil:
+		utils.Error("PUT /reports/:id - failed to update report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not update report.")})
+		return
+	}
+	utils.Info("PUT /reports/:id - report %d updated", id)
+	c.JSON(http.StatusOK, report)
 }
 
 // DELETE /reports/:id
+// Admin only; reporters take their report down with POST /reports/:id/withdraw
 func (h *ReportHandler) DeleteReport(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.Error("DELETE /reports/:id - invalid report ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	err = h.Service.DeleteReport(c.Request.Context(), id)
+	if errors.Is(err, services.ErrReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
 		return
 	}
-	if err := h.Service.DeleteReport(c.Request.Context(), id); err != nil {
-		utils.Error("DELETE /reports/:id - failed to delete report: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report", "details": err.Error()})
+	if err != nil {
+		utils.Error("DELETE /reports/:id - failed to delete report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not delete report.")})
 		return
 	}
-	c.JSON(http.StatusNoContent, nil)
+	utils.Info("DELETE /reports/:id - report %d deleted", id)
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeEditor lets admins through and otherwise checks the reporter's
+// edit token like authorizeReporter
+func (h *ReportHandler) authorizeEditor(c *gin.Context, id int) (*models.Report, bool) {
+	if !middleware.IsAdmin(c) {
+		return h.authorizeReporter(c, id)
+	}
+	report, err := h.Service.GetReportByID(c.Request.Context(), id)
+	switch {
+	case err != nil:
+		utils.Error("%s %s - failed to get report %d: %v", c.Request.Method, c.FullPath(), id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not get report.")})
+		return nil, false
+	case report == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return nil, false
+	}
+	return report, true
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(c *gin.Context, key string, fallback int) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// reportFilterFromQuery reads the filters shared by export and search:
+// category, status, state, city, bbox=minLng,minLat,maxLng,maxLat, and
+// since/until as RFC 3339 or YYYY-MM-DD
+func reportFilterFromQuery(c *gin.Context) (services.ReportFilter, error) {
+	filter := services.ReportFilter{
+		Category: c.Query("category"),
+		Status:   c.Query("status"),
+		State:    c.Query("state"),
+		City:     c.Query("city"),
+	}
+	if v := c.Query("bbox"); v != "" {
+		bbox, err := services.ParseBBox(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.BBox = bbox
+	}
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func queryTime(c *gin.Context, key string) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := utils.ParseTimeOrDate(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or YYYY-MM-DD", key)
+	}
+	return &t, nil
 }
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type ResolutionHandler struct {
+	Service *services.ResolutionService
+}
+
+func NewResolutionHandler(service *services.ResolutionService) *ResolutionHandler {
+	return &ResolutionHandler{Service: service}
+}
+
+// ResolutionClaimRequest is the payload for POST /reports/:id/resolution-claims
+// Images are base64 encoded resolution photos (at least one)
+type ResolutionClaimRequest struct {
+	Note   string   `json:"note"`
+	Images []string `json:"images" binding:"required,min=1"`
+}
+
+// POST /reports/:id/resolution-claims
+func (h *ResolutionHandler) CreateClaim(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /reports/:id/resolution-claims - invalid report ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	var req ResolutionClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /reports/:id/resolution-claims - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	images, err := services.DecodeImages(req.Images)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	claim, err := h.Service.CreateClaim(c.Request.Context(), id, req.Note, c.ClientIP(), images)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open.")})
+		return
+	case err != nil:
+		utils.Error("POST /reports/:id/resolution-claims - failed to create claim for report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not submit resolution claim.")})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      claim.ID,
+		"status":  claim.Status,
+		"message": tr(c, "Resolution claim submitted for review"),
+	})
+}
+
+// GET /admin/resolution-claims?status=pending
+func (h *ResolutionHandler) ListClaims(c *gin.Context) {
+	claims, err := h.Service.ListClaims(c.Request.Context(), c.DefaultQuery("status", "pending"))
+	if err != nil {
+		utils.Error("GET /admin/resolution-claims - failed to list claims: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list resolution claims.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"claims": claims})
+}
+
+// ReviewClaimRequest is the payload for PUT /admin/resolution-claims/:id
+type ReviewClaimRequest struct {
+	Status string `json:"status" binding:"required,oneof=verified rejected"`
+	Notes  string `json:"notes"`
+}
+
+// PUT /admin/resolution-claims/:id
+func (h *ResolutionHandler) ReviewClaim(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("PUT /admin/resolution-claims/:id - invalid claim ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid claim ID.")})
+		return
+	}
+	var req ReviewClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("PUT /admin/resolution-claims/:id - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	claim, err := h.Service.ReviewClaim(c.Request.Context(), id, req.Status, req.Notes, nil)
+	switch {
+	case errors.Is(err, services.ErrClaimNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Resolution claim not found.")})
+		return
+	case errors.Is(err, services.ErrClaimReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": "ALREADY_REVIEWED", "details": tr(c, "This claim has already been reviewed.")})
+		return
+	case errors.Is(err, services.ErrReportClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "REPORT_CLOSED", "details": tr(c, "This report is no longer open; reject the claim instead.")})
+		return
+	case err != nil:
+		utils.Error("PUT /admin/resolution-claims/:id - failed to review claim %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not review resolution claim.")})
+		return
+	}
+	utils.Info("PUT /admin/resolution-claims/:id - claim %d %s", claim.ID, claim.Status)
+	c.JSON(http.StatusOK, claim)
+}
@@ -1,37 +1,166 @@
 package handlers
 
 import (
+	"html/template"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/assets"
+	"github.com/projects-for-public/help-govern/internal/i18n"
+	"github.com/projects-for-public/help-govern/internal/middleware"
 )
 
 // Handlers holds all handler dependencies for route registration.
 type Handlers struct {
-	Report *ReportHandler
+	Report     *ReportHandler
+	Admin      *AdminHandler
+	Categories *CategoryHandler
+	Pages      *PageHandler
+	Feeds      *FeedHandler
+	Sitemaps   *SitemapHandler
+	PWA        *PWAHandler
+
+	Resolution    *ResolutionHandler
+	Tracking      *TrackingHandler
+	Push          *PushHandler
+	Subscriptions *SubscriptionHandler
+	Webhooks      *WebhookHandler
+	Open311       *Open311Handler
+	Volunteers    *VolunteerHandler
+	Stats         *StatsHandler
+	Tiles         *TileHandler
+	Translations  *TranslationHandler
+
+	// Catalog translates API messages and templates
+	Catalog *i18n.Catalog
+	// Assets names static files by content hash
+	Assets *assets.Manifest
+
+	// AdminAuth guards everything under /admin
+	AdminAuth gin.HandlerFunc
+	// OptionalAdmin recognises the admin token on routes reporters can use
+	// too
+	OptionalAdmin gin.HandlerFunc
+	// Idempotency replays responses to retried requests with an
+	// Idempotency-Key header
+	Idempotency gin.HandlerFunc
+	// VolunteerAuth guards batch uploads by field volunteers
+	VolunteerAuth gin.HandlerFunc
 	// Add other handlers here as needed, e.g. Auth *AuthHandler, Image *ImageHandler, etc.
 }
 
 // RegisterRoutes registers all application routes to the Gin engine.
 // Pass in a Handlers struct with all required handlers.
 func RegisterRoutes(r *gin.Engine, h *Handlers) {
+	r.Use(middleware.Locale(h.Catalog))
+
+	// Templates translate with {{ t .Lang "English text" }} and link static
+	// files with {{ asset "css/style.css" }}
+	r.SetFuncMap(template.FuncMap{"t": h.Catalog.T, "asset": h.Assets.URL})
 	r.LoadHTMLGlob("web/templates/*.html")
 
 	r.GET("/", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "index.html", gin.H{})
+		c.HTML(http.StatusOK, "index.html", pageData(c, gin.H{}))
 	})
 
 	r.Static("/static", "web/static")
+	r.GET("/assets/*file", h.PWA.Asset)
+	r.GET("/manifest.webmanifest", h.PWA.Manifest)
+	r.GET("/sw.js", h.PWA.ServiceWorker)
+	r.GET("/offline", h.PWA.Offline)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
-	r.POST("/reports", h.Report.CreateReport)
+	r.GET("/categories", h.Categories.ListCategories)
+
+	r.POST("/reports", h.Idempotency, h.Report.CreateReport)
+	r.POST("/reports/drafts", middleware.RateLimit(10, time.Minute), h.Report.SyncDraft)
+	r.POST("/reports/batch", h.VolunteerAuth, middleware.RateLimit(30, time.Hour), h.Volunteers.BatchReports)
 	r.GET("/reports/:id", h.Report.GetReport)
 	r.GET("/reports", h.Report.ListReports)
-	r.PUT("/reports/:id", h.Report.UpdateReport)
-	r.DELETE("/reports/:id", h.Report.DeleteReport)
+	r.GET("/reports/search", middleware.RateLimit(30, time.Minute), h.Report.SearchReports)
+	r.PUT("/reports/:id", h.OptionalAdmin, middleware.RateLimit(10, time.Minute), h.Report.UpdateReport)
+	r.DELETE("/reports/:id", h.AdminAuth, h.Report.DeleteReport)
+	r.POST("/reports/:id/confirmations", middleware.RateLimit(10, time.Minute), h.Report.ConfirmReport)
+	r.POST("/reports/:id/resolution-claims", middleware.RateLimit(5, time.Hour), h.Resolution.CreateClaim)
+	// Retries are answered before they count against the rate limit
+	r.POST("/reports/:id/images", h.Idempotency, middleware.RateLimit(5, time.Hour), h.Report.AddImages)
+	r.POST("/reports/:id/withdraw", middleware.RateLimit(10, time.Minute), h.Report.WithdrawReport)
+	r.GET("/reports/:id/map.png", h.Tiles.ReportMap)
+	r.GET("/r/:slug", h.Pages.ReportPage)
+
+	r.POST("/reports/mine", middleware.RateLimit(10, time.Minute), h.Tracking.MyReports)
+	r.POST("/tracking/subscriptions", middleware.RateLimit(10, time.Minute), h.Tracking.Subscribe)
+	r.POST("/subscriptions/areas", middleware.RateLimit(10, time.Minute), h.Subscriptions.CreateArea)
+	r.GET("/subscriptions/:token", h.Subscriptions.GetSubscription)
+	r.DELETE("/subscriptions/:token", h.Tracking.Unsubscribe)
+	r.GET("/subscriptions/:token/unsubscribe", h.Tracking.UnsubscribeLink)
+	r.GET("/subscriptions/:token/confirm", h.Subscriptions.ConfirmLink)
+
+	r.GET("/push/vapid-public-key", h.Push.VAPIDKey)
+	r.POST("/push/subscriptions", middleware.RateLimit(10, time.Minute), h.Push.Subscribe)
+	r.DELETE("/push/subscriptions", h.Push.Unsubscribe)
+
+	for _, format := range ExportFormats {
+		r.GET("/export/reports."+format, middleware.RateLimit(10, time.Hour), h.Report.ExportReports(format))
+	}
+
+	// One limit across the feed formats
+	feedLimit := middleware.RateLimit(60, time.Minute)
+	for _, format := range FeedFormats {
+		r.GET("/feeds/reports."+format, feedLimit, h.Feeds.ReportsFeed(format))
+	}
+	r.GET("/feeds/reports", feedLimit, h.Feeds.ReportsFeed(FeedAtom))
+
+	r.GET("/robots.txt", h.Sitemaps.Robots)
+	r.GET("/sitemap.xml", h.Sitemaps.Index)
+	r.GET("/sitemaps/:file", h.Sitemaps.Chunk)
+
+	r.GET("/stats/summary", h.Stats.Summary)
+	r.GET("/stats/resolution-times", h.Stats.ResolutionTimes)
+	r.GET("/stats/trends", h.Stats.Trends)
+	r.GET("/stats/authorities", h.Stats.Authorities)
+
+	r.GET("/tiles/heat/:z/:x/:y", middleware.RateLimit(300, time.Minute), h.Tiles.HeatTile)
+	r.GET("/tiles/reports/:z/:x/:y", h.Tiles.ReportsTile)
+
+	// Open311 GeoReport v2; every endpoint takes a .json or .xml suffix
+	open311 := r.Group("/open311/v2")
+	for _, format := range []string{"json", "xml"} {
+		open311.GET("/services."+format, h.Open311.ListServices)
+		open311.GET("/requests."+format, h.Open311.ListRequests)
+		open311.POST("/requests."+format, middleware.RateLimit(10, time.Minute), h.Open311.CreateRequest)
+	}
+	open311.GET("/services/:code", h.Open311.GetService)
+	open311.GET("/requests/:id", h.Open311.GetRequest)
+
+	admin := r.Group("/admin", h.AdminAuth)
+	admin.GET("/reports/search", h.Admin.SearchReports)
+	admin.POST("/reports/:id/merge", h.Admin.MergeReport)
+	admin.PUT("/reports/:id/status", h.Admin.UpdateStatus)
+	admin.GET("/resolution-claims", h.Resolution.ListClaims)
+	admin.PUT("/resolution-claims/:id", h.Resolution.ReviewClaim)
+	admin.PUT("/images/:id/moderate", h.Admin.ModerateImage)
+
+	admin.GET("/translations", h.Translations.ListTranslations)
+	admin.PUT("/translations", h.Translations.SaveTranslation)
+	admin.GET("/translations/completeness", h.Translations.Completeness)
+	admin.DELETE("/translations/:id", h.Translations.DeleteTranslation)
+
+	admin.GET("/volunteer-keys", h.Volunteers.ListKeys)
+	admin.POST("/volunteer-keys", h.Volunteers.CreateKey)
+	admin.DELETE("/volunteer-keys/:id", h.Volunteers.RevokeKey)
+
+	admin.GET("/webhooks", h.Webhooks.ListWebhooks)
+	admin.POST("/webhooks", h.Webhooks.CreateWebhook)
+	admin.GET("/webhooks/:id", h.Webhooks.GetWebhook)
+	admin.PUT("/webhooks/:id", h.Webhooks.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", h.Webhooks.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", h.Webhooks.ListDeliveries)
+	admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.Webhooks.Redeliver)
 
 	// Future: Add more routes for other handlers here
 }
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// searchReports serves GET /reports/search and GET /admin/reports/search
+func searchReports(c *gin.Context, reports *services.ReportService, admin bool, path string) {
+	filter, err := reportFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if filter.Limit, err = queryInt(c, "limit", 20); err != nil || filter.Limit < 1 || filter.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "limit must be between 1 and 100.")})
+		return
+	}
+	results, err := reports.SearchReports(c.Request.Context(), c.Query("q"), filter, admin)
+	switch {
+	case errors.Is(err, services.ErrInvalidSearch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("%s - search failed: %v", path, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not search reports.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GET /reports/search
+func (h *ReportHandler) SearchReports(c *gin.Context) {
+	searchReports(c, h.Service, false, "GET /reports/search")
+}
+
+// GET /admin/reports/search
+// Also matches and returns admin notes.
+func (h *AdminHandler) SearchReports(c *gin.Context) {
+	searchReports(c, h.Reports, true, "GET /admin/reports/search")
+}
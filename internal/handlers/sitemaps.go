@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// sitemapCacheControl matches how often stale sitemap chunks are rebuilt
+const sitemapCacheControl = "public, max-age=300"
+
+// robotsDisallow are the paths crawlers have no business in: private or
+// per-user endpoints and bulk downloads
+var robotsDisallow = []string{"/admin/", "/export/", "/push/", "/subscriptions/", "/tracking/"}
+
+// SitemapHandler serves robots.txt and the sitemap of report pages
+type SitemapHandler struct {
+	Sitemaps *services.SitemapService
+
+	// PublicBaseURL is used for the sitemap location in robots.txt
+	PublicBaseURL string
+}
+
+func NewSitemapHandler(sitemaps *services.SitemapService, publicBaseURL string) *SitemapHandler {
+	return &SitemapHandler{Sitemaps: sitemaps, PublicBaseURL: strings.TrimRight(publicBaseURL, "/")}
+}
+
+// GET /robots.txt
+func (h *SitemapHandler) Robots(c *gin.Context) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, path := range robotsDisallow {
+		fmt.Fprintf(&b, "Disallow: %s\n", path)
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s/sitemap.xml\n", h.PublicBaseURL)
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.String(http.StatusOK, b.String())
+}
+
+// GET /sitemap.xml
+// The sitemap index, with one entry per chunk of reports
+func (h *SitemapHandler) Index(c *gin.Context) {
+	index, err := h.Sitemaps.Index(c.Request.Context())
+	if err != nil {
+		utils.Error("GET /sitemap.xml - failed to build sitemap: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not build sitemap.")})
+		return
+	}
+	serveSitemap(c, index)
+}
+
+// GET /sitemaps/reports-:n.xml
+func (h *SitemapHandler) Chunk(c *gin.Context) {
+	name := c.Param("file")
+	if !strings.HasPrefix(name, "reports-") || !strings.HasSuffix(name, ".xml") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Sitemap not found.")})
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "reports-"), ".xml"))
+	if err != nil || n < 1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Sitemap not found.")})
+		return
+	}
+	chunk, err := h.Sitemaps.Chunk(c.Request.Context(), n)
+	if err != nil {
+		utils.Error("GET /sitemaps/:file - failed to build sitemap: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not build sitemap.")})
+		return
+	}
+	if chunk == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Sitemap not found.")})
+		return
+	}
+	serveSitemap(c, chunk)
+}
+
+func serveSitemap(c *gin.Context, doc *services.SitemapDocument) {
+	c.Header("Cache-Control", sitemapCacheControl)
+	if notModified(c, doc.ETag, doc.LastMod) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", doc.XML)
+}
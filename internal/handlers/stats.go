@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Stats are served from periodically refreshed aggregates, so let browsers
+// and proxies reuse them for a while
+const statsCacheControl = "public, max-age=300"
+
+type StatsHandler struct {
+	Service      *services.StatsService
+	Translations *services.TranslationService
+}
+
+func NewStatsHandler(service *services.StatsService, translations *services.TranslationService) *StatsHandler {
+	return &StatsHandler{Service: service, Translations: translations}
+}
+
+func (h *StatsHandler) ok(c *gin.Context, body gin.H) {
+	body["refreshed_at"] = h.Service.RefreshedAt()
+	c.Header("Cache-Control", statsCacheControl)
+	c.JSON(http.StatusOK, body)
+}
+
+// GET /stats/summary
+func (h *StatsHandler) Summary(c *gin.Context) {
+	summary, err := h.Service.Summary(c.Request.Context(), c.Query("category"))
+	if err != nil {
+		utils.Error("GET /stats/summary - failed to load counts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load statistics.")})
+		return
+	}
+	h.ok(c, gin.H{
+		"total":       summary.Total,
+		"by_category": summary.ByCategory,
+		"by_status":   summary.ByStatus,
+		"by_state":    summary.ByState,
+	})
+}
+
+// GET /stats/resolution-times
+func (h *StatsHandler) ResolutionTimes(c *gin.Context) {
+	by := c.DefaultQuery("by", "all")
+	switch by {
+	case "all", "category", "state":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "by must be all, category or state.")})
+		return
+	}
+	times, err := h.Service.ResolutionTimes(c.Request.Context(), by)
+	if err != nil {
+		utils.Error("GET /stats/resolution-times - failed to load resolution times: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load statistics.")})
+		return
+	}
+	if by == "all" {
+		var overall *services.ResolutionTimes
+		if len(times) > 0 {
+			overall = &times[0]
+		}
+		h.ok(c, gin.H{"overall": overall})
+		return
+	}
+	h.ok(c, gin.H{"by": by, "groups": times})
+}
+
+// GET /stats/trends
+func (h *StatsHandler) Trends(c *gin.Context) {
+	months, err := queryInt(c, "months", 12)
+	if err != nil || months < 1 || months > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "months must be between 1 and 120.")})
+		return
+	}
+	points, err := h.Service.Trends(c.Request.Context(), months, c.Query("category"))
+	if err != nil {
+		utils.Error("GET /stats/trends - failed to load trends: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load statistics.")})
+		return
+	}
+	h.ok(c, gin.H{"months": points})
+}
+
+// GET /stats/authorities
+func (h *StatsHandler) Authorities(c *gin.Context) {
+	minReports, err := queryInt(c, "min_reports", 5)
+	if err != nil || minReports < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid min_reports.")})
+		return
+	}
+	board, err := h.Service.AuthorityLeaderboard(c.Request.Context(), minReports)
+	if err != nil {
+		utils.Error("GET /stats/authorities - failed to load leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load statistics.")})
+		return
+	}
+	lang := middleware.Lang(c)
+	for i, a := range board {
+		board[i].AuthorityName = h.Translations.Translate(services.EntityAuthority, strconv.Itoa(a.AuthorityID), "authority_name", lang, a.AuthorityName)
+	}
+	h.ok(c, gin.H{"authorities": board})
+}
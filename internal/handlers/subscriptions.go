@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type SubscriptionHandler struct {
+	Service       *services.SubscriptionService
+	Notifications *services.NotificationService
+}
+
+func NewSubscriptionHandler(service *services.SubscriptionService, notifications *services.NotificationService) *SubscriptionHandler {
+	return &SubscriptionHandler{Service: service, Notifications: notifications}
+}
+
+// AreaRequest describes an area to follow: either a circle (latitude,
+// longitude, radius_m) or a polygon of [lat, lng] vertices. Geometry limits
+// are checked by the service.
+type AreaRequest struct {
+	Latitude   *float64     `json:"latitude"`
+	Longitude  *float64     `json:"longitude"`
+	RadiusM    *int         `json:"radius_m"`
+	Polygon    [][2]float64 `json:"polygon"`
+	Categories []string     `json:"categories"`
+	// Digest batches notifications into one message a day
+	Digest bool `json:"digest"`
+}
+
+func (a *AreaRequest) apply(sub *models.Subscription) {
+	sub.CenterLat, sub.CenterLng, sub.RadiusM = a.Latitude, a.Longitude, a.RadiusM
+	sub.Polygon = a.Polygon
+	sub.Categories = a.Categories
+	sub.Delivery = models.DeliveryInstant
+	if a.Digest {
+		sub.Delivery = models.DeliveryDaily
+	}
+}
+
+// AreaSubscribeRequest follows every report in an area by email or web push
+type AreaSubscribeRequest struct {
+	Area         AreaRequest       `json:"area" binding:"required"`
+	Channel      string            `json:"channel" binding:"required,oneof=email webpush"`
+	Email        string            `json:"email" binding:"omitempty,email"`
+	Subscription *PushSubscription `json:"subscription"`
+	Locale       string            `json:"locale" binding:"omitempty,max=10"`
+}
+
+// POST /subscriptions/areas
+func (h *SubscriptionHandler) CreateArea(c *gin.Context) {
+	var req AreaSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /subscriptions/areas - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if !knownLocale(c, req.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Unsupported locale.")})
+		return
+	}
+	sub := models.Subscription{Channel: req.Channel, Locale: req.Locale}
+	switch req.Channel {
+	case models.ChannelEmail:
+		if req.Email == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Email is required.")})
+			return
+		}
+		if !h.Notifications.Supports(models.ChannelEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Email notifications are not available.")})
+			return
+		}
+		email := strings.ToLower(req.Email)
+		sub.Email = &email
+	case models.ChannelWebPush:
+		if req.Subscription == nil || !strings.HasPrefix(req.Subscription.Endpoint, "https://") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "A push subscription with an https endpoint is required.")})
+			return
+		}
+		sub.PushEndpoint = &req.Subscription.Endpoint
+		sub.PushP256dh = &req.Subscription.Keys.P256dh
+		sub.PushAuth = &req.Subscription.Keys.Auth
+	}
+	req.Area.apply(&sub)
+	err := h.Service.SubscribeArea(c.Request.Context(), &sub)
+	switch {
+	case errors.Is(err, services.ErrInvalidArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /subscriptions/areas - failed to subscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not save subscription.")})
+		return
+	}
+	if err := h.Notifications.RequestConfirmation(c.Request.Context(), &sub); err != nil {
+		utils.Error("POST /subscriptions/areas - failed to send confirmation for subscription %d: %v", sub.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not send confirmation email.")})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription":      sub,
+		"unsubscribe_token": sub.UnsubscribeToken,
+	})
+}
+
+// GET /subscriptions/:token
+func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
+	sub, err := h.Service.GetByToken(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Subscription not found.")})
+		return
+	case err != nil:
+		utils.Error("GET /subscriptions/:token - failed to load subscription: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load subscription.")})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// GET /subscriptions/:token/confirm
+// Link sent in the confirmation email of an email subscription
+func (h *SubscriptionHandler) ConfirmLink(c *gin.Context) {
+	_, err := h.Service.Confirm(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		c.String(http.StatusNotFound, tr(c, "This confirmation link is invalid or was already used."))
+		return
+	case err != nil:
+		utils.Error("GET /subscriptions/:token/confirm - failed to confirm subscription: %v", err)
+		c.String(http.StatusInternalServerError, tr(c, "Could not confirm subscription. Please try again later."))
+		return
+	}
+	c.String(http.StatusOK, tr(c, "Your subscription is confirmed. You will now receive updates."))
+}
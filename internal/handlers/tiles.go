@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Tiles are cached on disk and invalidated when reports change, so a short
+// browser cache is enough
+const tileCacheControl = "public, max-age=300"
+
+// Report maps only change when the base map does
+const staticMapCacheControl = "public, max-age=86400"
+
+type TileHandler struct {
+	Heatmap    *services.HeatmapService
+	Vector     *services.VectorTileService
+	StaticMaps *services.StaticMapService
+}
+
+func NewTileHandler(heatmap *services.HeatmapService, vector *services.VectorTileService, staticMaps *services.StaticMapService) *TileHandler {
+	return &TileHandler{Heatmap: heatmap, Vector: vector, StaticMaps: staticMaps}
+}
+
+// parseTile reads the :z, :x and :y params, where :y carries the format
+// extension (e.g. "215.png"). It writes the error response and returns
+// ok=false when the tile is invalid.
+func parseTile(c *gin.Context, formats ...string) (z, x, y int, format string, ok bool) {
+	yParam := c.Param("y")
+	dot := strings.LastIndexByte(yParam, '.')
+	if dot < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Tile not found.")})
+		return 0, 0, 0, "", false
+	}
+	format = yParam[dot+1:]
+	known := false
+	for _, f := range formats {
+		known = known || f == format
+	}
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	y, errY := strconv.Atoi(yParam[:dot])
+	if !known || errZ != nil || errX != nil || errY != nil || !utils.ValidTile(z, x, y) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Tile not found.")})
+		return 0, 0, 0, "", false
+	}
+	return z, x, y, format, true
+}
+
+// GET /tiles/heat/:z/:x/:y.png and .json
+func (h *TileHandler) HeatTile(c *gin.Context) {
+	z, x, y, format, ok := parseTile(c, services.HeatmapPNG, services.HeatmapJSON)
+	if !ok {
+		return
+	}
+	filter := services.HeatmapFilter{Category: c.Query("category"), Status: c.Query("status")}
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	data, err := h.Heatmap.Tile(c.Request.Context(), z, x, y, format, filter)
+	switch {
+	case errors.Is(err, services.ErrInvalidHeatmapFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("GET /tiles/heat/:z/:x/:y - failed to render tile %d/%d/%d: %v", z, x, y, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not render tile.")})
+		return
+	}
+	contentType := "image/png"
+	if format == services.HeatmapJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Cache-Control", tileCacheControl)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GET /tiles/reports/:z/:x/:y.mvt
+func (h *TileHandler) ReportsTile(c *gin.Context) {
+	z, x, y, _, ok := parseTile(c, "mvt")
+	if !ok {
+		return
+	}
+	data, err := h.Vector.ReportsTile(c.Request.Context(), z, x, y)
+	if err != nil {
+		utils.Error("GET /tiles/reports/:z/:x/:y - failed to render tile %d/%d/%d: %v", z, x, y, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not render tile.")})
+		return
+	}
+	c.Header("Cache-Control", tileCacheControl)
+	c.Data(http.StatusOK, "application/vnd.mapbox-vector-tile", data)
+}
+
+// GET /reports/:id/map.png
+// Map snapshot of a report for previews, centred on the report with a marker
+// in its category's colour
+func (h *TileHandler) ReportMap(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid report ID.")})
+		return
+	}
+	data, err := h.StaticMaps.ReportMap(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Report not found.")})
+		return
+	case err != nil:
+		utils.Error("GET /reports/:id/map.png - failed to render map of report %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not render map.")})
+		return
+	}
+	c.Header("Cache-Control", staticMapCacheControl)
+	c.Data(http.StatusOK, "image/png", data)
+}
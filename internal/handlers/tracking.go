@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type TrackingHandler struct {
+	Service       *services.TrackingService
+	Notifications *services.NotificationService
+
+	// PublicBaseURL is used to build absolute share links
+	PublicBaseURL string
+}
+
+func NewTrackingHandler(service *services.TrackingService, notifications *services.NotificationService, publicBaseURL string) *TrackingHandler {
+	return &TrackingHandler{Service: service, Notifications: notifications, PublicBaseURL: publicBaseURL}
+}
+
+// MyReportsRequest carries the tracking tokens stored on the reporter's device.
+// Sent in a POST body so tokens stay out of URLs and access logs.
+type MyReportsRequest struct {
+	Tokens []string `json:"tokens" binding:"required,min=1"`
+}
+
+// TrackedReportResponse is the reporter-facing view of a tracked report
+type TrackedReportResponse struct {
+	Token     string                `json:"token"`
+	ID        int                   `json:"id"`
+	Category  string                `json:"category"`
+	Status    string                `json:"status"`
+	ShareURL  string                `json:"share_url"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Timeline  []models.StatusUpdate `json:"timeline"`
+}
+
+// POST /reports/mine
+func (h *TrackingHandler) MyReports(c *gin.Context) {
+	var req MyReportsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /reports/mine - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if len(req.Tokens) > services.MaxTrackedTokens {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Too many tokens.")})
+		return
+	}
+	tracked, err := h.Service.MyReports(c.Request.Context(), req.Tokens)
+	if err != nil {
+		utils.Error("POST /reports/mine - failed to look up reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not look up reports.")})
+		return
+	}
+	reports := make([]TrackedReportResponse, 0, len(tracked))
+	for _, t := range tracked {
+		updated := t.Report.CreatedAt
+		for _, u := range t.Report.StatusUpdates {
+			if u.UpdatedAt.After(updated) {
+				updated = u.UpdatedAt
+			}
+		}
+		reports = append(reports, TrackedReportResponse{
+			Token:     t.Token,
+			ID:        t.Report.ID,
+			Category:  t.Report.Category,
+			Status:    t.Report.Status,
+			ShareURL:  t.Report.GenerateShareURL(h.PublicBaseURL),
+			CreatedAt: t.Report.CreatedAt,
+			UpdatedAt: updated,
+			Timeline:  t.Report.StatusUpdates,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// PushSubscription mirrors the browser's PushSubscription.toJSON()
+type PushSubscription struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys"`
+}
+
+// SubscribeRequest opts a tracking token into email or web push updates
+type SubscribeRequest struct {
+	Token        string            `json:"token" binding:"required"`
+	Channel      string            `json:"channel" binding:"required,oneof=email webpush"`
+	Email        string            `json:"email" binding:"omitempty,email"`
+	Subscription *PushSubscription `json:"subscription"`
+	Locale       string            `json:"locale" binding:"omitempty,max=10"`
+}
+
+// POST /tracking/subscriptions
+func (h *TrackingHandler) Subscribe(c *gin.Context) {
+	var req SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /tracking/subscriptions - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	if !knownLocale(c, req.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Unsupported locale.")})
+		return
+	}
+	sub := models.Subscription{Channel: req.Channel, Locale: req.Locale}
+	switch req.Channel {
+	case models.ChannelEmail:
+		if req.Email == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Email is required.")})
+			return
+		}
+		if !h.Notifications.Supports(models.ChannelEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Email notifications are not available.")})
+			return
+		}
+		email := strings.ToLower(req.Email)
+		sub.Email = &email
+	case models.ChannelWebPush:
+		if req.Subscription == nil || !strings.HasPrefix(req.Subscription.Endpoint, "https://") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "A push subscription with an https endpoint is required.")})
+			return
+		}
+		sub.PushEndpoint = &req.Subscription.Endpoint
+		sub.PushP256dh = &req.Subscription.Keys.P256dh
+		sub.PushAuth = &req.Subscription.Keys.Auth
+	}
+	err := h.Service.Subscribe(c.Request.Context(), req.Token, &sub)
+	switch {
+	case errors.Is(err, services.ErrTrackingTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Unknown tracking token.")})
+		return
+	case err != nil:
+		utils.Error("POST /tracking/subscriptions - failed to subscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not save subscription.")})
+		return
+	}
+	if err := h.Notifications.RequestConfirmation(c.Request.Context(), &sub); err != nil {
+		utils.Error("POST /tracking/subscriptions - failed to send confirmation for subscription %d: %v", sub.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not send confirmation email.")})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                sub.ID,
+		"report_id":         sub.ReportID,
+		"channel":           sub.Channel,
+		"confirmed":         sub.ConfirmedAt != nil,
+		"unsubscribe_token": sub.UnsubscribeToken,
+	})
+}
+
+// DELETE /subscriptions/:token
+func (h *TrackingHandler) Unsubscribe(c *gin.Context) {
+	err := h.Service.Unsubscribe(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Subscription not found.")})
+		return
+	case err != nil:
+		utils.Error("DELETE /subscriptions/:token - failed to unsubscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not remove subscription.")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /subscriptions/:token/unsubscribe
+// One-click unsubscribe link used in emails
+func (h *TrackingHandler) UnsubscribeLink(c *gin.Context) {
+	err := h.Service.Unsubscribe(c.Request.Context(), c.Param("token"))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		c.String(http.StatusNotFound, "This subscription does not exist or was already removed.")
+		return
+	case err != nil:
+		utils.Error("GET /subscriptions/:token/unsubscribe - failed to unsubscribe: %v", err)
+		c.String(http.StatusInternalServerError, "Could not remove subscription. Please try again later.")
+		return
+	}
+	c.String(http.StatusOK, "You have been unsubscribed.")
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type TranslationHandler struct {
+	Service *services.TranslationService
+}
+
+func NewTranslationHandler(service *services.TranslationService) *TranslationHandler {
+	return &TranslationHandler{Service: service}
+}
+
+// TranslationRequest is the payload for PUT /admin/translations
+type TranslationRequest struct {
+	Entity   string `json:"entity" binding:"required"`
+	EntityID string `json:"entity_id" binding:"required,max=100"`
+	Field    string `json:"field" binding:"required"`
+	Locale   string `json:"locale" binding:"required,max=10"`
+	Value    string `json:"value" binding:"required"`
+}
+
+// GET /admin/translations
+func (h *TranslationHandler) ListTranslations(c *gin.Context) {
+	translations, err := h.Service.ListTranslations(c.Request.Context(), services.TranslationFilter{
+		Entity:   c.Query("entity"),
+		EntityID: c.Query("entity_id"),
+		Locale:   c.Query("locale"),
+	})
+	if err != nil {
+		utils.Error("GET /admin/translations - failed to list translations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list translations.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"translations": translations, "fields": services.TranslatableFields})
+}
+
+// PUT /admin/translations
+// Creates the translation or replaces the existing one for the same entity,
+// field and locale.
+func (h *TranslationHandler) SaveTranslation(c *gin.Context) {
+	var req TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("PUT /admin/translations - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	t := &models.Translation{
+		Entity:   req.Entity,
+		EntityID: req.EntityID,
+		Field:    req.Field,
+		Locale:   req.Locale,
+		Value:    req.Value,
+	}
+	err := h.Service.SaveTranslation(c.Request.Context(), t)
+	switch {
+	case errors.Is(err, services.ErrInvalidTranslation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("PUT /admin/translations - failed to save translation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not save translation.")})
+		return
+	}
+	utils.Info("PUT /admin/translations - saved %s %s %s in %s", t.Entity, t.EntityID, t.Field, t.Locale)
+	c.JSON(http.StatusOK, gin.H{"translation": t})
+}
+
+// DELETE /admin/translations/:id
+func (h *TranslationHandler) DeleteTranslation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("DELETE /admin/translations/:id - invalid translation ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid translation ID.")})
+		return
+	}
+	err = h.Service.DeleteTranslation(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrTranslationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Translation not found.")})
+		return
+	case err != nil:
+		utils.Error("DELETE /admin/translations/:id - failed to delete translation %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not delete translation.")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /admin/translations/completeness
+// Every locale's coverage; ?locale= narrows to one locale and lists what is
+// missing.
+func (h *TranslationHandler) Completeness(c *gin.Context) {
+	locales, err := h.Service.Completeness(c.Request.Context(), c.Query("locale"))
+	if err != nil {
+		utils.Error("GET /admin/translations/completeness - failed to compute completeness: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not compute translation completeness.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"locales": locales})
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/projects-for-public/help-govern/internal/middleware"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Limits for POST /reports/batch. A line of NDJSON holds one report with
+// up to three base64 photos.
+const (
+	maxBatchBytes     = 256 << 20
+	maxBatchLineBytes = (services.MaxImageBytes*services.MaxImagesPerReport/3+1)*4 + 1<<20
+)
+
+// Outcomes of a report in a batch
+const (
+	BatchCreated  = "created"
+	BatchExisting = "existing" // already received with the same submission_id
+	BatchFailed   = "failed"
+)
+
+type VolunteerHandler struct {
+	Volunteers *services.VolunteerService
+	Reports    *services.ReportService
+
+	// PublicBaseURL is used for the share links of created reports
+	PublicBaseURL string
+}
+
+func NewVolunteerHandler(volunteers *services.VolunteerService, reports *services.ReportService, publicBaseURL string) *VolunteerHandler {
+	return &VolunteerHandler{Volunteers: volunteers, Reports: reports, PublicBaseURL: publicBaseURL}
+}
+
+// BatchReportItem is one report, one line of NDJSON, in POST /reports/batch.
+// Images are base64 as for POST /reports; ImageFiles name file parts of a
+// multipart upload.
+type BatchReportItem struct {
+	SubmissionID string    `json:"submission_id" binding:"omitempty,uuid"`
+	Category     string    `json:"category" binding:"required"`
+	Latitude     float64   `json:"latitude" binding:"required"`
+	Longitude    float64   `json:"longitude" binding:"required"`
+	Description  string    `json:"description"`
+	CapturedAt   time.Time `json:"captured_at" binding:"required"`
+	Images       []string  `json:"images"`
+	ImageFiles   []string  `json:"image_files"`
+}
+
+// BatchItemResult is the outcome of one report in a batch. Line is the
+// report's line in the NDJSON, counting from 1.
+type BatchItemResult struct {
+	Line         int    `json:"line"`
+	SubmissionID string `json:"submission_id,omitempty"`
+	Status       string `json:"status"`
+	ID           int    `json:"id,omitempty"`
+	ShareURL     string `json:"share_url,omitempty"`
+	EditToken    string `json:"edit_token,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Details      string `json:"details,omitempty"`
+}
+
+// POST /reports/batch
+// Volunteer-only (API key as bearer token). Uploads a survey of many
+// reports, as NDJSON or as multipart with a "reports" NDJSON part and the
+// photos as file parts. Each report is validated and created on its own,
+// with its photos in one transaction, so one bad report does not fail the
+// rest; the response lists the outcome of every line.
+func (h *VolunteerHandler) BatchReports(c *gin.Context) {
+	volunteer := middleware.Volunteer(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBytes)
+
+	var (
+		lines io.ReadCloser
+		form  *multipart.Form
+	)
+	switch c.ContentType() {
+	case "application/x-ndjson":
+		lines = c.Request.Body
+	case "multipart/form-data":
+		var err error
+		form, err = c.MultipartForm()
+		if err != nil {
+			utils.Error("POST /reports/batch - failed to read upload: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Could not read batch upload.")})
+			return
+		}
+		defer form.RemoveAll()
+		lines, err = batchReportsPart(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "The upload has no reports part.")})
+			return
+		}
+		defer lines.Close()
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "UNSUPPORTED_MEDIA_TYPE",
+			"details": tr(c, "Send reports as application/x-ndjson or multipart/form-data."),
+		})
+		return
+	}
+
+	scanner := bufio.NewScanner(lines)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxBatchLineBytes)
+	results := []BatchItemResult{}
+	counts := map[string]int{BatchCreated: 0, BatchExisting: 0, BatchFailed: 0}
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var result BatchItemResult
+		if len(results) >= services.MaxBatchReports {
+			result = BatchItemResult{Line: n, Status: BatchFailed, Error: "TOO_MANY_REPORTS",
+				Details: tr(c, "A batch can have at most %d reports.", services.MaxBatchReports)}
+		} else {
+			result = h.batchItem(c, volunteer, n, line, form)
+		}
+		counts[result.Status]++
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		// Reports before the unreadable line are already saved
+		utils.Error("POST /reports/batch - failed to read batch after %d reports: %v", len(results), err)
+		counts[BatchFailed]++
+		results = append(results, BatchItemResult{Status: BatchFailed, Error: "VALIDATION_ERROR",
+			Details: tr(c, "Could not read the rest of the batch.")})
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "The batch contains no reports.")})
+		return
+	}
+	utils.Info("POST /reports/batch - volunteer key %d: %d created, %d existing, %d failed",
+		volunteer.ID, counts[BatchCreated], counts[BatchExisting], counts[BatchFailed])
+	c.JSON(http.StatusOK, gin.H{
+		"results":  results,
+		"created":  counts[BatchCreated],
+		"existing": counts[BatchExisting],
+		"failed":   counts[BatchFailed],
+	})
+}
+
+// batchReportsPart is the NDJSON of a multipart upload, sent as a file or
+// as a plain field
+func batchReportsPart(form *multipart.Form) (io.ReadCloser, error) {
+	if files := form.File["reports"]; len(files) == 1 {
+		return files[0].Open()
+	}
+	if values := form.Value["reports"]; len(values) == 1 {
+		return io.NopCloser(strings.NewReader(values[0])), nil
+	}
+	return nil, errors.New("no reports part")
+}
+
+// batchItem validates and creates one report of a batch
+func (h *VolunteerHandler) batchItem(c *gin.Context, volunteer *models.VolunteerKey, n int, line []byte, form *multipart.Form) BatchItemResult {
+	result := BatchItemResult{Line: n, Status: BatchFailed}
+	fail := func(code, details string) BatchItemResult {
+		result.Error = code
+		result.Details = tr(c, details)
+		return result
+	}
+	var item BatchReportItem
+	if err := json.Unmarshal(line, &item); err != nil {
+		return fail("VALIDATION_ERROR", err.Error())
+	}
+	result.SubmissionID = item.SubmissionID
+	if err := binding.Validator.ValidateStruct(&item); err != nil {
+		return fail("VALIDATION_ERROR", err.Error())
+	}
+	if err := services.ValidateCapturedAt(item.CapturedAt); err != nil {
+		return fail("VALIDATION_ERROR", err.Error())
+	}
+	images, err := batchImages(item, form)
+	if err != nil {
+		return fail("VALIDATION_ERROR", err.Error())
+	}
+
+	capturedAt := item.CapturedAt.UTC()
+	report := models.Report{
+		Category:    item.Category,
+		Latitude:    item.Latitude,
+		Longitude:   item.Longitude,
+		Description: item.Description,
+		ReporterIP:  c.ClientIP(),
+		Status:      "pending",
+		CapturedAt:  &capturedAt,
+	}
+	ctx := c.Request.Context()
+	if err := h.Reports.ValidateNewReport(ctx, &report); err != nil {
+		if errors.Is(err, services.ErrInvalidReport) {
+			return fail("VALIDATION_ERROR", err.Error())
+		}
+		utils.Error("POST /reports/batch - failed to validate report on line %d: %v", n, err)
+		return fail("INTERNAL_ERROR", "Could not validate report.")
+	}
+	editToken, created, err := h.Volunteers.SubmitReport(ctx, volunteer, &report, item.SubmissionID, images)
+	if errors.Is(err, services.ErrSubmissionConflict) {
+		return fail("SUBMISSION_CONFLICT", "This submission ID was already used for another report.")
+	}
+	if err != nil {
+		utils.Error("POST /reports/batch - failed to create report on line %d: %v", n, err)
+		return fail("INTERNAL_ERROR", "Could not save report.")
+	}
+	result.Status = BatchCreated
+	if !created {
+		result.Status = BatchExisting
+	}
+	result.ID = report.ID
+	result.ShareURL = report.GenerateShareURL(h.PublicBaseURL)
+	result.EditToken = editToken
+	return result
+}
+
+// batchImages decodes a report's base64 photos and reads its file parts
+func batchImages(item BatchReportItem, form *multipart.Form) ([]services.UploadedImage, error) {
+	if len(item.Images)+len(item.ImageFiles) > services.MaxImagesPerReport {
+		return nil, fmt.Errorf("%w: at most %d images are allowed", services.ErrInvalidImage, services.MaxImagesPerReport)
+	}
+	images, err := services.DecodeImages(item.Images)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range item.ImageFiles {
+		if form == nil || len(form.File[name]) != 1 {
+			return nil, fmt.Errorf("%w: no file part named %q", services.ErrInvalidImage, name)
+		}
+		f, err := form.File[name][0].Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(f, services.MaxImageBytes+1))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		img, err := services.ValidateImage(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, name)
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// VolunteerKeyRequest is the payload for POST /admin/volunteer-keys
+type VolunteerKeyRequest struct {
+	Name         string `json:"name" binding:"required,max=100"`
+	Organization string `json:"organization" binding:"max=255"`
+}
+
+// GET /admin/volunteer-keys
+func (h *VolunteerHandler) ListKeys(c *gin.Context) {
+	keys, err := h.Volunteers.ListKeys(c.Request.Context())
+	if err != nil {
+		utils.Error("GET /admin/volunteer-keys - failed to list keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list volunteer keys.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"volunteer_keys": keys})
+}
+
+// POST /admin/volunteer-keys
+// The API key is only returned here.
+func (h *VolunteerHandler) CreateKey(c *gin.Context) {
+	var req VolunteerKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /admin/volunteer-keys - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	key, token, err := h.Volunteers.CreateKey(c.Request.Context(), req.Name, req.Organization)
+	switch {
+	case errors.Is(err, services.ErrInvalidVolunteerKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /admin/volunteer-keys - failed to create key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not create volunteer key.")})
+		return
+	}
+	utils.Info("POST /admin/volunteer-keys - created key %d for %s", key.ID, key.Name)
+	c.JSON(http.StatusCreated, gin.H{"volunteer_key": key, "api_key": token})
+}
+
+// DELETE /admin/volunteer-keys/:id
+// Revokes the key; reports uploaded with it stay attributed to it
+func (h *VolunteerHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("DELETE /admin/volunteer-keys/:id - invalid key ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid volunteer key ID.")})
+		return
+	}
+	err = h.Volunteers.RevokeKey(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrVolunteerKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Volunteer key not found.")})
+		return
+	case err != nil:
+		utils.Error("DELETE /admin/volunteer-keys/:id - failed to revoke key %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not revoke volunteer key.")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
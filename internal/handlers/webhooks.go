@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+type WebhookHandler struct {
+	Service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+// WebhookRequest is the payload for creating and updating webhooks.
+// On update, omitted fields are left unchanged.
+type WebhookRequest struct {
+	URL          *string  `json:"url" binding:"omitempty,url"`
+	Description  *string  `json:"description" binding:"omitempty,max=255"`
+	EventTypes   []string `json:"event_types"`
+	IsActive     *bool    `json:"is_active"`
+	RotateSecret bool     `json:"rotate_secret"`
+}
+
+func (r *WebhookRequest) input() services.WebhookInput {
+	return services.WebhookInput{
+		URL:          r.URL,
+		Description:  r.Description,
+		EventTypes:   r.EventTypes,
+		IsActive:     r.IsActive,
+		RotateSecret: r.RotateSecret,
+	}
+}
+
+// GET /admin/webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.Service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		utils.Error("GET /admin/webhooks - failed to list webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list webhooks.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "event_types": services.WebhookEventTypes})
+}
+
+// POST /admin/webhooks
+// The signing secret is only returned here and when rotated.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("POST /admin/webhooks - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	webhook, err := h.Service.CreateWebhook(c.Request.Context(), req.input())
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("POST /admin/webhooks - failed to create webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not create webhook.")})
+		return
+	}
+	utils.Info("POST /admin/webhooks - created webhook %d for %s", webhook.ID, webhook.URL)
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": webhook.Secret})
+}
+
+// GET /admin/webhooks/:id
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("GET /admin/webhooks/:id - invalid webhook ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid webhook ID.")})
+		return
+	}
+	webhook, err := h.Service.GetWebhook(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Webhook not found.")})
+		return
+	case err != nil:
+		utils.Error("GET /admin/webhooks/:id - failed to load webhook %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not load webhook.")})
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// PUT /admin/webhooks/:id
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("PUT /admin/webhooks/:id - invalid webhook ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid webhook ID.")})
+		return
+	}
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error("PUT /admin/webhooks/:id - validation failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	}
+	webhook, err := h.Service.UpdateWebhook(c.Request.Context(), id, req.input())
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Webhook not found.")})
+		return
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, err.Error())})
+		return
+	case err != nil:
+		utils.Error("PUT /admin/webhooks/:id - failed to update webhook %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not update webhook.")})
+		return
+	}
+	resp := gin.H{"webhook": webhook}
+	if req.RotateSecret {
+		resp["secret"] = webhook.Secret
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DELETE /admin/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("DELETE /admin/webhooks/:id - invalid webhook ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid webhook ID.")})
+		return
+	}
+	err = h.Service.DeleteWebhook(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Webhook not found.")})
+		return
+	case err != nil:
+		utils.Error("DELETE /admin/webhooks/:id - failed to delete webhook %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not delete webhook.")})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /admin/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("GET /admin/webhooks/:id/deliveries - invalid webhook ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid webhook ID.")})
+		return
+	}
+	limit, err := queryInt(c, "limit", 50)
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "limit must be between 1 and 500.")})
+		return
+	}
+	deliveries, err := h.Service.ListDeliveries(c.Request.Context(), id, limit)
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Webhook not found.")})
+		return
+	case err != nil:
+		utils.Error("GET /admin/webhooks/:id/deliveries - failed to list deliveries for webhook %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not list deliveries.")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// POST /admin/webhooks/:id/deliveries/:delivery_id/redeliver
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error("POST /admin/webhooks/:id/deliveries/:delivery_id/redeliver - invalid webhook ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid webhook ID.")})
+		return
+	}
+	deliveryID, err := strconv.Atoi(c.Param("delivery_id"))
+	if err != nil {
+		utils.Error("POST /admin/webhooks/:id/deliveries/:delivery_id/redeliver - invalid delivery ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VALIDATION_ERROR", "details": tr(c, "Invalid delivery ID.")})
+		return
+	}
+	delivery, err := h.Service.Redeliver(c.Request.Context(), id, deliveryID)
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Webhook not found.")})
+		return
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "NOT_FOUND", "details": tr(c, "Delivery not found.")})
+		return
+	case errors.Is(err, services.ErrWebhookDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "WEBHOOK_DISABLED", "details": tr(c, "Re-enable the webhook before redelivering.")})
+		return
+	case err != nil:
+		utils.Error("POST /admin/webhooks/:id/deliveries/:delivery_id/redeliver - failed to redeliver %d: %v", deliveryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "INTERNAL_ERROR", "details": tr(c, "Could not redeliver.")})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
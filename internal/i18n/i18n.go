@@ -0,0 +1,193 @@
+// Package i18n holds the message catalog for API responses and
+// server-rendered pages, and Accept-Language negotiation.
+//
+// Messages are keyed by their English text, gettext style, so English needs
+// no catalog file and an untranslated message falls back to English as is.
+// Each other locale is one JSON file, <dir>/<locale>.json, mapping English
+// text to its translation; adding a language means adding a file.
+//
+// A locale may fall back to another before English, e.g. mr → hi → en, so a
+// Marathi reader sees Hindi rather than English where Marathi is missing. A
+// locale with a fallback is supported even without a catalog file.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the language messages are written in
+const DefaultLocale = "en"
+
+// Catalog maps English messages to their translations per locale
+type Catalog struct {
+	messages  map[string]map[string]string
+	fallbacks map[string]string
+}
+
+// Load reads every <locale>.json file in dir. fallbacks lists the fallback
+// chains as "locale:fallback" pairs, e.g. "mr:hi,gu:hi".
+func Load(dir, fallbacks string) (*Catalog, error) {
+	c := &Catalog{
+		messages:  map[string]map[string]string{DefaultLocale: {}},
+		fallbacks: map[string]string{},
+	}
+	for _, pair := range strings.Split(fallbacks, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		locale, fallback, ok := strings.Cut(pair, ":")
+		locale, fallback = baseLanguage(locale), baseLanguage(fallback)
+		if !ok || locale == "" || fallback == "" || locale == DefaultLocale {
+			return nil, fmt.Errorf("invalid locale fallback %q", pair)
+		}
+		c.fallbacks[locale] = fallback
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		c.messages[strings.TrimSuffix(filepath.Base(f), ".json")] = msgs
+	}
+	for locale := range c.fallbacks {
+		if c.Chain(locale) == nil {
+			return nil, fmt.Errorf("locale fallbacks for %q form a cycle", locale)
+		}
+	}
+	return c, nil
+}
+
+// Locales lists the supported locales, DefaultLocale first
+func (c *Catalog) Locales() []string {
+	seen := map[string]bool{DefaultLocale: true}
+	var locales []string
+	for l := range c.messages {
+		if !seen[l] {
+			seen[l] = true
+			locales = append(locales, l)
+		}
+	}
+	for l := range c.fallbacks {
+		if !seen[l] {
+			seen[l] = true
+			locales = append(locales, l)
+		}
+	}
+	sort.Strings(locales)
+	return append([]string{DefaultLocale}, locales...)
+}
+
+// Supports reports whether there is a catalog or a fallback for locale
+func (c *Catalog) Supports(locale string) bool {
+	_, ok := c.messages[locale]
+	_, fallback := c.fallbacks[locale]
+	return ok || fallback
+}
+
+// Chain is the order in which translations for locale are looked up, always
+// ending in DefaultLocale: "mr" gives [mr hi en]. It is nil when the
+// configured fallbacks loop.
+func (c *Catalog) Chain(locale string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	for l := locale; l != DefaultLocale; {
+		if seen[l] {
+			return nil
+		}
+		seen[l] = true
+		chain = append(chain, l)
+		next, ok := "", false
+		if c != nil {
+			next, ok = c.fallbacks[l]
+		}
+		if !ok {
+			next = DefaultLocale
+		}
+		l = next
+	}
+	return append(chain, DefaultLocale)
+}
+
+// Messages returns every translation for locale, including those it falls
+// back to, e.g. for client-side scripts
+func (c *Catalog) Messages(locale string) map[string]string {
+	if c == nil {
+		return nil
+	}
+	merged := map[string]string{}
+	chain := c.Chain(locale)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for msg, t := range c.messages[chain[i]] {
+			if t != "" {
+				merged[msg] = t
+			}
+		}
+	}
+	return merged
+}
+
+// T translates msg into locale, then formats it with args if any. Missing
+// messages follow the locale's fallback chain and end up as msg itself.
+func (c *Catalog) T(locale, msg string, args ...interface{}) string {
+	if c != nil {
+		for _, l := range c.Chain(locale) {
+			if t, ok := c.messages[l][msg]; ok && t != "" {
+				msg = t
+				break
+			}
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Negotiate picks the locale for a request: an explicit choice (e.g. a ?lang=
+// parameter) if supported, otherwise the best supported entry of an
+// Accept-Language header, otherwise DefaultLocale. Region subtags are
+// ignored, so "hi-IN" selects "hi".
+func (c *Catalog) Negotiate(explicit, acceptLanguage string) string {
+	if l := baseLanguage(explicit); l != "" && c.Supports(l) {
+		return l
+	}
+	best, bestQ := DefaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if l := baseLanguage(tag); q > bestQ && c.Supports(l) {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// baseLanguage turns "hi-IN" or "HI_in" into "hi"
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
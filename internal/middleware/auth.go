@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+const adminKey = "admin"
+
+// AdminAuth protects admin routes with a shared bearer token.
+// This is a stopgap until JWT-based authentication is implemented.
+// An empty token disables the admin routes entirely.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			utils.Error("%s %s - admin token not configured", c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "FORBIDDEN",
+				"details": T(c, "Admin access is not configured."),
+			})
+			return
+		}
+		if !validAdminToken(c, token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "UNAUTHORIZED",
+				"details": T(c, "Missing or invalid admin token."),
+			})
+			return
+		}
+		c.Set(adminKey, true)
+		c.Next()
+	}
+}
+
+// OptionalAdmin recognises the admin token on routes that are open to
+// others too, such as reporters holding an edit token, and never rejects
+// the request. Handlers check the result with IsAdmin.
+func OptionalAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" && validAdminToken(c, token) {
+			c.Set(adminKey, true)
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin reports whether the request carried the admin token
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool(adminKey)
+}
+
+func validAdminToken(c *gin.Context, token string) bool {
+	got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// IdempotencyKeyHeader carries a client-generated key, such as a UUID, that
+// stays the same when the client retries a request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodyBytes bounds the request body buffered for fingerprinting:
+// a report with its photos, base64-encoded in JSON, and room for the form
+const maxIdempotentBodyBytes = services.MaxImagesPerReport*services.MaxImageBytes*4/3 + 1<<20
+
+// Idempotency makes requests sent with an Idempotency-Key header safe to
+// retry. The first successful (2xx) response is recorded and returned again,
+// with an Idempotent-Replayed header, for retries with the same key and
+// request. Failed requests are not recorded and can be retried for real.
+// Requests without the header are passed through.
+func Idempotency(store *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		path := c.Request.Method + " " + c.FullPath()
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "VALIDATION_ERROR",
+				"details": T(c, "Idempotency-Key must be at most 255 characters."),
+			})
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "VALIDATION_ERROR",
+				"details": T(c, "Request body is too large."),
+			})
+			return
+		}
+		if err != nil {
+			utils.Error("%s - failed to read request body: %v", path, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "VALIDATION_ERROR",
+				"details": T(c, "Could not read request body."),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.Request.URL.Path
+		recorded, err := store.Begin(c.Request.Context(), scope, key, requestFingerprint(c, body))
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "IDEMPOTENCY_KEY_REUSED",
+				"details": T(c, "This Idempotency-Key was already used for a different request."),
+			})
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInFlight):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error":   "IDEMPOTENCY_KEY_IN_USE",
+				"details": T(c, "A request with this Idempotency-Key is still being processed."),
+			})
+			return
+		case err != nil:
+			utils.Error("%s - failed to check idempotency key: %v", path, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "INTERNAL_ERROR",
+				"details": T(c, "Could not process request."),
+			})
+			return
+		case recorded != nil:
+			utils.Info("%s - replaying response for idempotency key", path)
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(recorded.StatusCode, recorded.ContentType, recorded.Body)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		// The client may have gone away, which is when retries happen
+		ctx := context.Background()
+		status := w.Status()
+		if status < 200 || status > 299 {
+			if err := store.Release(ctx, scope, key); err != nil {
+				utils.Error("%s - failed to release idempotency key: %v", path, err)
+			}
+			return
+		}
+		err = store.Complete(ctx, scope, key, services.IdempotentResponse{
+			StatusCode:  status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        w.body.Bytes(),
+		})
+		if err != nil {
+			utils.Error("%s - failed to record response for idempotency key: %v", path, err)
+		}
+	}
+}
+
+// requestFingerprint identifies what a request asks for. JSON bodies are
+// compared by content, so a client re-encoding the same payload is not
+// taken for a different request. The edit token is included, so a key
+// cannot be replayed with someone else's token.
+func requestFingerprint(c *gin.Context, body []byte) string {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if dec.Decode(&v) == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	h := sha256.New()
+	h.Write(body)
+	h.Write([]byte{0})
+	h.Write([]byte(c.GetHeader("X-Edit-Token")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+const testIdempotencyKey = "0b6e2f6a-3c1d-4a51-9a43-6f1c2d7e8b90"
+
+var idempotencyKeyColumns = []string{"id", "scope", "key_hash", "request_hash", "status_code", "content_type", "response_body"}
+
+// idempotentRouter serves POST /reports behind the Idempotency middleware.
+// The handler answers with status and counts its calls.
+func idempotentRouter(t *testing.T, status *int, calls *int) (*gin.Engine, *fakedb.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, fake := fakedb.New(t)
+	r := gin.New()
+	r.POST("/reports", Idempotency(services.NewIdempotencyService(db, time.Hour)), func(c *gin.Context) {
+		*calls++
+		c.JSON(*status, gin.H{"id": 12})
+	})
+	return r, fake
+}
+
+func postReport(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, testIdempotencyKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// fingerprint is the request hash Idempotency records for body
+func fingerprint(body string) string {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/reports", nil)
+	return requestFingerprint(c, []byte(body))
+}
+
+func TestIdempotencyReplaysSameRequest(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r, fake := idempotentRouter(t, &status, &calls)
+	fake.SetReturning("idempotency_keys", []string{"id"}, []driver.Value{int64(1)})
+	// The retry finds the key taken
+	fake.SetReturning("idempotency_keys", []string{"id"})
+
+	first := postReport(r, `{"category":"potholes","latitude":26.9}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: %d %s", first.Code, first.Body)
+	}
+	updates := fake.Executed(`UPDATE "idempotency_keys"`)
+	if len(updates) != 1 {
+		t.Fatalf("%d responses recorded, want 1", len(updates))
+	}
+	var sealed []byte
+	for _, a := range updates[0].Args {
+		if b, ok := a.([]byte); ok {
+			sealed = b
+		}
+	}
+	fake.SetRows("idempotency_keys", idempotencyKeyColumns, []driver.Value{
+		int64(1), "POST /reports", utils.HashToken(testIdempotencyKey),
+		fingerprint(`{"category":"potholes","latitude":26.9}`),
+		int64(http.StatusCreated), first.Header().Get("Content-Type"), sealed,
+	})
+
+	// Same payload, encoded differently
+	retry := postReport(r, `{ "latitude": 26.9, "category": "potholes" }`)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry: %d, Idempotent-Replayed %q; want the recorded 201 replayed", retry.Code, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if !bytes.Equal(retry.Body.Bytes(), first.Body.Bytes()) {
+		t.Errorf("replayed body %s, want %s", retry.Body, first.Body)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want once", calls)
+	}
+}
+
+func TestIdempotencyRejectsKeyReuse(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r, fake := idempotentRouter(t, &status, &calls)
+	fake.SetRows("idempotency_keys", idempotencyKeyColumns, []driver.Value{
+		int64(1), "POST /reports", utils.HashToken(testIdempotencyKey),
+		fingerprint(`{"category":"garbage_heap"}`), int64(http.StatusCreated), "application/json", []byte{},
+	})
+
+	w := postReport(r, `{"category":"potholes"}`)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "IDEMPOTENCY_KEY_REUSED") {
+		t.Errorf("got %d %s, want 422 IDEMPOTENCY_KEY_REUSED", w.Code, w.Body)
+	}
+	if calls != 0 {
+		t.Errorf("handler ran %d times", calls)
+	}
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r, fake := idempotentRouter(t, &status, &calls)
+	// Claimed for the same request, with no response recorded yet
+	fake.SetRows("idempotency_keys", idempotencyKeyColumns, []driver.Value{
+		int64(1), "POST /reports", utils.HashToken(testIdempotencyKey),
+		fingerprint(`{"category":"potholes"}`), nil, "", nil,
+	})
+
+	w := postReport(r, `{"category":"potholes"}`)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("got %d, Retry-After %q; want 409 with Retry-After: 1", w.Code, w.Header().Get("Retry-After"))
+	}
+	if calls != 0 {
+		t.Errorf("handler ran %d times", calls)
+	}
+}
+
+func TestIdempotencyReleasesFailedRequest(t *testing.T) {
+	status, calls := http.StatusBadRequest, 0
+	r, fake := idempotentRouter(t, &status, &calls)
+	fake.SetReturning("idempotency_keys", []string{"id"}, []driver.Value{int64(1)})
+
+	if w := postReport(r, `{"category":"potholes"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want the handler's 400", w.Code)
+	}
+	if n := len(fake.Executed(`UPDATE "idempotency_keys"`)); n != 0 {
+		t.Errorf("recorded %d failed responses", n)
+	}
+	deletes := fake.Executed(`DELETE FROM "idempotency_keys"`)
+	if len(deletes) == 0 || !strings.Contains(deletes[len(deletes)-1].Query, "status_code IS NULL") {
+		t.Fatalf("key not released: %v", deletes)
+	}
+
+	// The retry runs for real
+	status = http.StatusCreated
+	if w := postReport(r, `{"category":"potholes"}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry: %d, Idempotent-Replayed %q; want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want twice", calls)
+	}
+}
+
+func TestIdempotencyLimitsBody(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r, fake := idempotentRouter(t, &status, &calls)
+
+	w := postReport(r, strings.Repeat("x", maxIdempotentBodyBytes+1))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d, want 413", w.Code)
+	}
+	if calls != 0 || len(fake.Queried(`INSERT INTO "idempotency_keys"`)) != 0 {
+		t.Errorf("oversized request was processed")
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/i18n"
+)
+
+const (
+	localeKey  = "locale"
+	catalogKey = "i18n"
+)
+
+// Locale negotiates the response language from ?lang= or Accept-Language
+// and stores it, with the catalog, on the context for T and Lang
+func Locale(catalog *i18n.Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := catalog.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language"))
+		c.Set(localeKey, locale)
+		c.Set(catalogKey, catalog)
+		c.Header("Content-Language", locale)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// Lang is the locale negotiated for the request, i18n.DefaultLocale when
+// the Locale middleware did not run
+func Lang(c *gin.Context) string {
+	if l := c.GetString(localeKey); l != "" {
+		return l
+	}
+	return i18n.DefaultLocale
+}
+
+// T translates msg into the request's locale
+func T(c *gin.Context, msg string, args ...interface{}) string {
+	return Catalog(c).T(Lang(c), msg, args...)
+}
+
+// Catalog is the message catalog set by the Locale middleware, nil when it
+// did not run
+func Catalog(c *gin.Context) *i18n.Catalog {
+	catalog, _ := c.Get(catalogKey)
+	cat, _ := catalog.(*i18n.Catalog)
+	return cat
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit allows at most limit requests per client IP in each fixed window.
+// Counters live in memory, so limits are per server instance.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	type bucket struct {
+		count int
+		reset time.Time
+	}
+	var (
+		mu      sync.Mutex
+		buckets = make(map[string]*bucket)
+	)
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := c.ClientIP()
+
+		mu.Lock()
+		// Drop expired buckets once the map grows, so idle IPs don't pile up
+		if len(buckets) > 10000 {
+			for k, b := range buckets {
+				if now.After(b.reset) {
+					delete(buckets, k)
+				}
+			}
+		}
+		b, ok := buckets[key]
+		if !ok || now.After(b.reset) {
+			b = &bucket{reset: now.Add(window)}
+			buckets[key] = b
+		}
+		b.count++
+		count, reset := b.count, b.reset
+		mu.Unlock()
+
+		if count > limit {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "RATE_LIMITED",
+				"details": T(c, "Too many requests. Please try again later."),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/services"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+const volunteerKey = "volunteer"
+
+// VolunteerAuth requires a volunteer API key as a bearer token and stores
+// the key on the context for Volunteer
+func VolunteerAuth(volunteers *services.VolunteerService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		key, err := volunteers.Authenticate(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, services.ErrVolunteerKeyNotFound):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "UNAUTHORIZED",
+				"details": T(c, "Missing or invalid volunteer API key."),
+			})
+			return
+		case err != nil:
+			utils.Error("%s %s - failed to check volunteer key: %v", c.Request.Method, c.FullPath(), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "INTERNAL_ERROR",
+				"details": T(c, "Could not check volunteer API key."),
+			})
+			return
+		}
+		c.Set(volunteerKey, key)
+		c.Next()
+	}
+}
+
+// Volunteer is the key authenticated by VolunteerAuth, nil when it did not
+// run
+func Volunteer(c *gin.Context) *models.VolunteerKey {
+	key, _ := c.Get(volunteerKey)
+	v, _ := key.(*models.VolunteerKey)
+	return v
+}
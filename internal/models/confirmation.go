@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReportConfirmation records an anonymous "this affects me too" on a report.
+// Fingerprint is a hash of the client IP and DeviceFingerprint of the device
+// ID, when sent; a confirmation matching either is not counted again.
+type ReportConfirmation struct {
+	ID                int       `json:"id" gorm:"primaryKey"`
+	ReportID          int       `json:"report_id" gorm:"not null"`
+	Fingerprint       string    `json:"-" gorm:"type:varchar(64);not null"`
+	DeviceFingerprint *string   `json:"-" gorm:"type:varchar(64)"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (ReportConfirmation) TableName() string {
+	return "report_confirmations"
+}
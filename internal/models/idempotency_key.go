@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey records the response to a request sent with an
+// Idempotency-Key header until ExpiresAt. The key itself is not stored, and
+// the response, which can hold edit tokens, is sealed with a key derived
+// from it.
+type IdempotencyKey struct {
+	ID           int    `gorm:"primaryKey"`
+	Scope        string `gorm:"not null"`
+	KeyHash      string `gorm:"type:varchar(64);not null"`
+	RequestHash  string `gorm:"type:varchar(64);not null"`
+	StatusCode   *int   // nil while the first request is still running
+	ContentType  string `gorm:"not null"`
+	ResponseBody []byte `gorm:"type:bytea"`
+	CreatedAt    time.Time
+	ExpiresAt    time.Time `gorm:"not null"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
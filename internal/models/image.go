@@ -1,15 +1,34 @@
 package models
 
-// TODO: move to JSON model definition as in report.go
+import "time"
+
+// Image types: photos of the issue itself, and photos showing it fixed
+const (
+	ImageTypeReport     = "report"
+	ImageTypeResolution = "resolution"
+)
+
 type Image struct {
-	ID                 int     `db:"id" json:"id"`
-	ReportID           int     `db:"report_id" json:"report_id"`
-	CloudinaryURL      string  `db:"cloudinary_url" json:"cloudinary_url"`
-	CloudinaryPublicID string  `db:"cloudinary_public_id" json:"cloudinary_public_id"`
-	ImageType          string  `db:"image_type" json:"image_type"`
-	ModerationStatus   string  `db:"moderation_status" json:"moderation_status"`
-	ModerationNotes    *string `db:"moderation_notes" json:"moderation_notes,omitempty"`
-	UploadedAt         string  `db:"uploaded_at" json:"uploaded_at"`
-	ModeratedAt        *string `db:"moderated_at" json:"moderated_at,omitempty"`
-	ModeratedBy        *int    `db:"moderated_by" json:"moderated_by,omitempty"`
+	ID                 int        `json:"id" gorm:"primaryKey"`
+	ReportID           int        `json:"report_id" gorm:"not null"`
+	ResolutionClaimID  *int       `json:"resolution_claim_id,omitempty"`
+	CloudinaryURL      string     `json:"cloudinary_url" gorm:"column:cloudinary_url;not null"`
+	CloudinaryPublicID string     `json:"cloudinary_public_id" gorm:"column:cloudinary_public_id;not null"`
+	ImageType          string     `json:"image_type" gorm:"default:report"`
+	ModerationStatus   string     `json:"moderation_status" gorm:"default:pending"`
+	ModerationNotes    *string    `json:"moderation_notes,omitempty" gorm:"type:text"`
+	UploadedAt         time.Time  `json:"uploaded_at" gorm:"autoCreateTime"`
+	ModeratedAt        *time.Time `json:"moderated_at,omitempty"`
+	ModeratedBy        *int       `json:"moderated_by,omitempty"`
+}
+
+func (Image) TableName() string {
+	return "images"
+}
+
+// ImagePair puts a photo of the issue next to a photo of it fixed.
+// Either side may be missing when the counts don't match.
+type ImagePair struct {
+	Before *Image `json:"before,omitempty"`
+	After  *Image `json:"after,omitempty"`
 }
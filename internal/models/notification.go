@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Notification is a queued outbound message. The queue worker delivers it
+// through the notifier for its channel and retries failures with backoff.
+type Notification struct {
+	ID             int        `json:"id" gorm:"primaryKey"`
+	Channel        string     `json:"channel" gorm:"not null"`
+	Recipient      string     `json:"recipient" gorm:"not null"` // email address or push endpoint
+	SubscriptionID *int       `json:"subscription_id,omitempty"`
+	Event          string     `json:"event" gorm:"not null"`
+	Locale         string     `json:"locale" gorm:"default:en"`
+	Subject        string     `json:"subject"`
+	BodyText       string     `json:"body_text" gorm:"type:text"`
+	BodyHTML       string     `json:"body_html" gorm:"type:text"`
+	Status         string     `json:"status" gorm:"default:pending"` // pending, sending, sent, failed
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	LastError      *string    `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
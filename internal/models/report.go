@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Report struct {
 	ID            int        `json:"id" gorm:"primaryKey"`
@@ -21,17 +25,108 @@ type Report struct {
 	TwitterPosted bool       `json:"twitter_posted"`
 	TwitterPostID *string    `json:"twitter_post_id,omitempty"`
 
+	// ShareSlug is the random, unguessable identifier used in public links.
+	// EditTokenHash is the hash of the token handed once to the anonymous
+	// reporter so they can add photos or withdraw the report.
+	ShareSlug     string  `json:"share_slug" gorm:"type:varchar(32);uniqueIndex;not null"`
+	EditTokenHash *string `json:"-" gorm:"type:varchar(64)"`
+
+	// SubmissionHash is the hash of the client-generated ID of a report
+	// drafted offline, which makes syncing the draft idempotent
+	SubmissionHash *string `json:"-" gorm:"type:varchar(64)"`
+
+	// Reports surveyed by field volunteers and uploaded in batches carry
+	// the volunteer's API key and when the issue was seen on the device
+	VolunteerKeyID *int       `json:"-"`
+	CapturedAt     *time.Time `json:"captured_at,omitempty"`
+
+	// LastActivityAt is when the report was created or last changed status
+	LastActivityAt time.Time `json:"last_activity_at" gorm:"not null"`
+
+	// MergedIntoID points at the canonical report once this one has been
+	// folded into it as a duplicate.
+	MergedIntoID   *int `json:"merged_into_id,omitempty"`
+	DuplicateCount int  `json:"duplicate_count" gorm:"not null;default:0"`
+
+	// ConfirmationCount is the number of distinct people who said
+	// "this affects me too".
+	ConfirmationCount int `json:"confirmation_count" gorm:"not null;default:0"`
+
+	// DescriptionLanguage is detected at submission; nil when the
+	// description has no letters to go by
+	DescriptionLanguage *string `json:"description_language,omitempty"`
+
 	Images        []Image        `json:"images" gorm:"foreignKey:ReportID"`
 	StatusUpdates []StatusUpdate `json:"timeline" gorm:"foreignKey:ReportID"`
+
+	// BeforeAfter pairs approved report photos with approved resolution photos
+	BeforeAfter []ImagePair `json:"before_after,omitempty" gorm:"-"`
 }
 
 func (Report) TableName() string {
 	return "reports"
 }
 
-func (r *Report) GenerateShareURL() string {
-	// TODO: Implement shareable URL generation
-	return ""
+// IsOpen reports whether the issue is still awaiting resolution.
+func (r *Report) IsOpen() bool {
+	switch r.Status {
+	case "pending", "verified", "in_progress":
+		return r.MergedIntoID == nil
+	}
+	return false
+}
+
+// Priority ranks how many people an issue affects: the reporter, everyone who
+// confirmed it and every duplicate report folded into it. Sent to webhook
+// partners, who route reports to authorities, and used by
+// GET /reports?sort=priority.
+func (r *Report) Priority() int {
+	return 1 + r.ConfirmationCount + r.DuplicateCount
+}
+
+// BeforeAfterPairs pairs approved photos of the issue with approved
+// resolution photos, in upload order.
+func (r *Report) BeforeAfterPairs() []ImagePair {
+	var before, after []*Image
+	for i := range r.Images {
+		img := &r.Images[i]
+		if img.ModerationStatus != "approved" {
+			continue
+		}
+		switch img.ImageType {
+		case ImageTypeReport:
+			before = append(before, img)
+		case ImageTypeResolution:
+			after = append(after, img)
+		}
+	}
+	if len(after) == 0 {
+		return nil
+	}
+	n := len(before)
+	if len(after) > n {
+		n = len(after)
+	}
+	pairs := make([]ImagePair, n)
+	for i := range pairs {
+		if i < len(before) {
+			pairs[i].Before = before[i]
+		}
+		if i < len(after) {
+			pairs[i].After = after[i]
+		}
+	}
+	return pairs
+}
+
+// GenerateShareURL returns the public link to the report page under baseURL
+func (r *Report) GenerateShareURL(baseURL string) string {
+	return strings.TrimRight(baseURL, "/") + "/r/" + r.ShareSlug
+}
+
+// MapImageURL returns the report's static map image under baseURL
+func (r *Report) MapImageURL(baseURL string) string {
+	return strings.TrimRight(baseURL, "/") + "/reports/" + strconv.Itoa(r.ID) + "/map.png"
 }
 
 func (r *Report) CanBeModifiedBy(userRole string) bool {
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReportTranslation is a machine translation of a report's description into
+// one language. The original stays on the report.
+type ReportTranslation struct {
+	ID          int       `json:"-" gorm:"primaryKey"`
+	ReportID    int       `json:"-" gorm:"not null"`
+	Locale      string    `json:"locale" gorm:"not null"`
+	Description string    `json:"description" gorm:"type:text;not null"`
+	Engine      string    `json:"engine" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (ReportTranslation) TableName() string {
+	return "report_translations"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ResolutionClaim is a citizen's report that an issue has been fixed,
+// backed by resolution photos. Moderators verify or reject it.
+type ResolutionClaim struct {
+	ID          int        `json:"id" gorm:"primaryKey"`
+	ReportID    int        `json:"report_id" gorm:"not null"`
+	Note        string     `json:"note" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"default:pending"` // pending, verified, rejected
+	ClaimantIP  string     `json:"-" gorm:"type:inet"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy  *int       `json:"reviewed_by,omitempty"`
+	ReviewNotes *string    `json:"review_notes,omitempty" gorm:"type:text"`
+
+	Images []Image `json:"images" gorm:"foreignKey:ResolutionClaimID"`
+}
+
+func (ResolutionClaim) TableName() string {
+	return "resolution_claims"
+}
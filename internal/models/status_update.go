@@ -1,12 +1,17 @@
 package models
 
-// TODO: move to JSON model definition as in report.go
+import "time"
+
 type StatusUpdate struct {
-	ID        int     `db:"id" json:"id"`
-	ReportID  int     `db:"report_id" json:"report_id"`
-	OldStatus *string `db:"old_status" json:"old_status,omitempty"`
-	NewStatus string  `db:"new_status" json:"new_status"`
-	Notes     *string `db:"notes" json:"notes,omitempty"`
-	UpdatedBy *int    `db:"updated_by" json:"updated_by,omitempty"`
-	UpdatedAt string  `db:"updated_at" json:"updated_at"`
+	ID        int       `json:"id" gorm:"primaryKey"`
+	ReportID  int       `json:"report_id" gorm:"not null"`
+	OldStatus *string   `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status" gorm:"not null"`
+	Notes     *string   `json:"notes,omitempty" gorm:"type:text"`
+	UpdatedBy *int      `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (StatusUpdate) TableName() string {
+	return "status_updates"
 }
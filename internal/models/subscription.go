@@ -0,0 +1,158 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Notification channels a subscriber can choose
+const (
+	ChannelEmail   = "email"
+	ChannelWebPush = "webpush"
+)
+
+// Delivery modes of area subscriptions
+const (
+	DeliveryInstant = "instant"
+	DeliveryDaily   = "daily"
+)
+
+// TrackingToken lets an anonymous reporter follow their report. Only the
+// hash is stored; the token itself lives on the reporter's device.
+type TrackingToken struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	ReportID  int       `json:"report_id" gorm:"not null"`
+	TokenHash string    `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (TrackingToken) TableName() string {
+	return "tracking_tokens"
+}
+
+// Subscription is an opt-in notification channel for someone following a
+// report, or an area when ReportID is nil. An area is either a circle
+// (Center*, RadiusM) or a Polygon; the Min/Max bounds enclose it and are
+// used to prefilter matches.
+// Email subscriptions set Email; web push subscriptions set the Push* fields.
+// Only confirmed subscriptions are notified: email addresses confirm through
+// a link, web push subscriptions are confirmed by the browser's permission.
+type Subscription struct {
+	ID               int            `json:"id" gorm:"primaryKey"`
+	ReportID         *int           `json:"report_id,omitempty"`
+	TrackingTokenID  *int           `json:"-"`
+	CenterLat        *float64       `json:"center_lat,omitempty" gorm:"type:decimal(10,8)"`
+	CenterLng        *float64       `json:"center_lng,omitempty" gorm:"type:decimal(11,8)"`
+	RadiusM          *int           `json:"radius_m,omitempty"`
+	Polygon          Polygon        `json:"polygon,omitempty" gorm:"type:jsonb"`
+	MinLat           *float64       `json:"-" gorm:"type:decimal(10,8)"`
+	MinLng           *float64       `json:"-" gorm:"type:decimal(11,8)"`
+	MaxLat           *float64       `json:"-" gorm:"type:decimal(10,8)"`
+	MaxLng           *float64       `json:"-" gorm:"type:decimal(11,8)"`
+	Categories       pq.StringArray `json:"categories,omitempty" gorm:"type:text[]"`
+	Delivery         string         `json:"delivery" gorm:"not null;default:instant"`
+	LastDigestAt     *time.Time     `json:"last_digest_at,omitempty"`
+	Channel          string         `json:"channel" gorm:"not null"`
+	Email            *string        `json:"email,omitempty"`
+	PushEndpoint     *string        `json:"-" gorm:"type:text"`
+	PushP256dh       *string        `json:"-"`
+	PushAuth         *string        `json:"-"`
+	Locale           string         `json:"locale" gorm:"default:en"`
+	UnsubscribeToken string         `json:"-" gorm:"uniqueIndex;not null"`
+	ConfirmedAt      *time.Time     `json:"confirmed_at,omitempty"`
+	ConfirmTokenHash *string        `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// Covers reports whether an area subscription includes the point
+func (s *Subscription) Covers(lat, lng float64) bool {
+	if len(s.Polygon) > 0 {
+		return utils.PointInPolygon(lat, lng, s.Polygon)
+	}
+	if s.CenterLat == nil || s.CenterLng == nil || s.RadiusM == nil {
+		return false
+	}
+	return utils.HaversineMeters(*s.CenterLat, *s.CenterLng, lat, lng) <= float64(*s.RadiusM)
+}
+
+// Matches reports whether an area subscription wants to hear about the report
+func (s *Subscription) Matches(report *Report) bool {
+	if len(s.Categories) > 0 {
+		found := false
+		for _, c := range s.Categories {
+			if c == report.Category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return s.Covers(report.Latitude, report.Longitude)
+}
+
+// SetBounds computes the bounding box of the subscribed area
+func (s *Subscription) SetBounds() {
+	var minLat, minLng, maxLat, maxLng float64
+	switch {
+	case len(s.Polygon) > 0:
+		minLat, minLng, maxLat, maxLng = utils.PolygonBounds(s.Polygon)
+	case s.CenterLat != nil && s.CenterLng != nil && s.RadiusM != nil:
+		minLat, minLng, maxLat, maxLng = utils.BoundingBox(*s.CenterLat, *s.CenterLng, float64(*s.RadiusM))
+	default:
+		return
+	}
+	s.MinLat, s.MinLng, s.MaxLat, s.MaxLng = &minLat, &minLng, &maxLat, &maxLng
+}
+
+// Polygon is a ring of [lat, lng] vertices, stored as JSON
+type Polygon [][2]float64
+
+func (p Polygon) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (p *Polygon) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into Polygon", src)
+	}
+}
+
+// DigestItem is an event held back for a subscription's next daily digest
+type DigestItem struct {
+	ID             int       `json:"id" gorm:"primaryKey"`
+	SubscriptionID int       `json:"subscription_id" gorm:"not null"`
+	ReportID       int       `json:"report_id" gorm:"not null"`
+	Event          string    `json:"event" gorm:"not null"`
+	Status         string    `json:"status" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (DigestItem) TableName() string {
+	return "digest_items"
+}
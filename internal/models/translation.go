@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Translation is one field of an entity in one language, e.g. the Marathi
+// name of category 3. EntityID is the row ID as text, or the event name for
+// notification templates.
+type Translation struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Entity    string    `json:"entity" gorm:"not null"`
+	EntityID  string    `json:"entity_id" gorm:"not null"`
+	Field     string    `json:"field" gorm:"not null"`
+	Locale    string    `json:"locale" gorm:"not null"`
+	Value     string    `json:"value" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Translation) TableName() string {
+	return "translations"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// VolunteerKey is an API key issued to a field volunteer for uploading
+// report batches. Only the hash of the key is stored.
+type VolunteerKey struct {
+	ID           int        `json:"id" gorm:"primaryKey"`
+	Name         string     `json:"name" gorm:"not null"`
+	Organization string     `json:"organization"`
+	KeyHash      string     `json:"-" gorm:"type:varchar(64);not null"`
+	IsActive     bool       `json:"is_active" gorm:"not null;default:true"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+
+	// ReportCount is filled in for the admin listing
+	ReportCount int `json:"report_count" gorm:"->;-:migration"`
+}
+
+func (VolunteerKey) TableName() string {
+	return "volunteer_keys"
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook is a partner endpoint that receives report lifecycle events.
+// An empty EventTypes list means every event.
+type Webhook struct {
+	ID                  int            `json:"id" gorm:"primaryKey"`
+	URL                 string         `json:"url" gorm:"type:text;not null"`
+	Description         string         `json:"description"`
+	Secret              string         `json:"-" gorm:"not null"`
+	EventTypes          pq.StringArray `json:"event_types" gorm:"type:text[]"`
+	IsActive            bool           `json:"is_active" gorm:"not null;default:true"`
+	ConsecutiveFailures int            `json:"consecutive_failures" gorm:"not null;default:0"`
+	DisabledAt          *time.Time     `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Wants reports whether the webhook subscribes to the event type
+func (w *Webhook) Wants(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one attempt sequence to deliver an event to a webhook.
+// It doubles as the delivery log shown to admins.
+type WebhookDelivery struct {
+	ID             int        `json:"id" gorm:"primaryKey"`
+	WebhookID      int        `json:"webhook_id" gorm:"not null"`
+	EventID        string     `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:jsonb;not null"`
+	Status         string     `json:"status" gorm:"default:pending"` // pending, sending, succeeded, failed
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	ResponseBody   *string    `json:"response_body,omitempty" gorm:"type:text"`
+	LastError      *string    `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
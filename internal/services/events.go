@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// Report lifecycle event types
+const (
+	EventReportCreated       = "report.created"
+	EventReportStatusChanged = "report.status_changed"
+	EventReportMerged        = "report.merged"
+	EventReportDeleted       = "report.deleted"
+	EventImageApproved       = "image.approved"
+	EventImageRejected       = "image.rejected"
+)
+
+// Event describes something that happened to a report. Only the fields
+// relevant to the event type are set.
+type Event struct {
+	Type         string
+	Report       *models.Report
+	StatusUpdate *models.StatusUpdate
+	// Image is the moderated photo for image.* events
+	Image *models.Image
+	// MergedID is the duplicate folded into Report for report.merged
+	MergedID   int
+	OccurredAt time.Time
+}
+
+// EventHandler reacts to an event. Handlers run synchronously after the change
+// is committed, so they should only do quick work such as queueing a job.
+type EventHandler func(ctx context.Context, e Event)
+
+// EventBus fans report lifecycle events out to interested subsystems
+// (notifications, webhooks, ...).
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler for all events
+func (b *EventBus) Subscribe(h EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers the event to every handler. A panicking handler is logged
+// and does not stop the others.
+func (b *EventBus) Publish(ctx context.Context, e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	b.mu.RLock()
+	handlers := append([]EventHandler(nil), b.handlers...)
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					utils.Error("event handler panicked on %s: %v", e.Type, r)
+				}
+			}()
+			h(ctx, e)
+		}()
+	}
+}
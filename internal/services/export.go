@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+// Export formats
+const (
+	ExportCSV     = "csv"
+	ExportGeoJSON = "geojson"
+	ExportKML     = "kml"
+)
+
+var ErrInvalidExportFormat = errors.New("unsupported export format")
+
+// exportColumns is everything an export may contain. reporter_ip,
+// admin_notes and the edit token are deliberately never selected.
+var exportColumns = []string{
+	"id", "category", "latitude", "longitude", "description", "status",
+	"state", "city", "share_slug", "confirmation_count", "duplicate_count",
+	"created_at", "verified_at", "started_at", "resolved_at",
+}
+
+// ExportReports streams every report matching filter to w. Rows are read
+// from a database cursor one at a time, so the export never holds more than
+// a single report in memory. Sort and Limit are ignored; rows come out in ID
+// order.
+func (s *ReportService) ExportReports(ctx context.Context, w io.Writer, format string, filter ReportFilter) (int, error) {
+	var enc reportEncoder
+	switch format {
+	case ExportCSV:
+		enc = &csvEncoder{w: csv.NewWriter(w)}
+	case ExportGeoJSON:
+		enc = &geoJSONEncoder{w: w}
+	case ExportKML:
+		enc = &kmlEncoder{w: w, enc: xml.NewEncoder(w)}
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidExportFormat, format)
+	}
+
+	q := applyReportFilter(s.db.WithContext(ctx).Model(&models.Report{}), filter)
+	rows, err := q.Select(exportColumns).Order("id").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if err := enc.begin(); err != nil {
+		return 0, err
+	}
+	baseURL := s.cfg.PublicBaseURL
+	n := 0
+	for rows.Next() {
+		var report models.Report
+		if err := s.db.ScanRows(rows, &report); err != nil {
+			return n, err
+		}
+		if err := enc.write(&report, report.GenerateShareURL(baseURL)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, enc.end()
+}
+
+// ParseBBox parses "minLng,minLat,maxLng,maxLat", the order used by GeoJSON
+// and most map tools
+func ParseBBox(s string) (*BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox: %w", err)
+		}
+		v[i] = f
+	}
+	b := &BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLng > b.MaxLng || b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 {
+		return nil, errors.New("bbox is out of range")
+	}
+	return b, nil
+}
+
+type reportEncoder interface {
+	begin() error
+	write(r *models.Report, shareURL string) error
+	end() error
+}
+
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func exportString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) begin() error {
+	return e.w.Write([]string{
+		"id", "category", "latitude", "longitude", "description", "status",
+		"state", "city", "share_url", "confirmation_count", "duplicate_count",
+		"created_at", "verified_at", "started_at", "resolved_at",
+	})
+}
+
+func (e *csvEncoder) write(r *models.Report, shareURL string) error {
+	return e.w.Write([]string{
+		strconv.Itoa(r.ID),
+		r.Category,
+		strconv.FormatFloat(r.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(r.Longitude, 'f', -1, 64),
+		r.Description,
+		r.Status,
+		exportString(r.State),
+		exportString(r.City),
+		shareURL,
+		strconv.Itoa(r.ConfirmationCount),
+		strconv.Itoa(r.DuplicateCount),
+		exportTime(&r.CreatedAt),
+		exportTime(r.VerifiedAt),
+		exportTime(r.StartedAt),
+		exportTime(r.ResolvedAt),
+	})
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	ID         int               `json:"id"`
+	Geometry   geoJSONPoint      `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // lng, lat
+}
+
+type geoJSONProperties struct {
+	Category          string     `json:"category"`
+	Description       string     `json:"description"`
+	Status            string     `json:"status"`
+	State             *string    `json:"state"`
+	City              *string    `json:"city"`
+	ShareURL          string     `json:"share_url"`
+	ConfirmationCount int        `json:"confirmation_count"`
+	DuplicateCount    int        `json:"duplicate_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	StartedAt         *time.Time `json:"started_at"`
+	ResolvedAt        *time.Time `json:"resolved_at"`
+}
+
+// geoJSONEncoder writes the FeatureCollection envelope by hand so features
+// can be emitted as they are read
+type geoJSONEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *geoJSONEncoder) begin() error {
+	_, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (e *geoJSONEncoder) write(r *models.Report, shareURL string) error {
+	b, err := json.Marshal(geoJSONFeature{
+		Type:     "Feature",
+		ID:       r.ID,
+		Geometry: geoJSONPoint{Type: "Point", Coordinates: [2]float64{r.Longitude, r.Latitude}},
+		Properties: geoJSONProperties{
+			Category:          r.Category,
+			Description:       r.Description,
+			Status:            r.Status,
+			State:             r.State,
+			City:              r.City,
+			ShareURL:          shareURL,
+			ConfirmationCount: r.ConfirmationCount,
+			DuplicateCount:    r.DuplicateCount,
+			CreatedAt:         r.CreatedAt,
+			VerifiedAt:        r.VerifiedAt,
+			StartedAt:         r.StartedAt,
+			ResolvedAt:        r.ResolvedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *geoJSONEncoder) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+type kmlPlacemark struct {
+	XMLName      xml.Name       `xml:"Placemark"`
+	ID           string         `xml:"id,attr"`
+	Name         string         `xml:"name"`
+	Description  string         `xml:"description"`
+	ExtendedData []kmlData      `xml:"ExtendedData>Data"`
+	Point        kmlCoordinates `xml:"Point"`
+	TimeStamp    *kmlTimeStamp  `xml:"TimeStamp,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+// kmlEncoder writes the Document envelope by hand and encodes one
+// Placemark per report
+type kmlEncoder struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+func (e *kmlEncoder) begin() error {
+	_, err := io.WriteString(e.w, xml.Header+
+		`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Reports</name>`+"\n")
+	return err
+}
+
+func (e *kmlEncoder) write(r *models.Report, shareURL string) error {
+	p := kmlPlacemark{
+		ID:          "report-" + strconv.Itoa(r.ID),
+		Name:        fmt.Sprintf("#%d %s", r.ID, r.Category),
+		Description: r.Description,
+		ExtendedData: []kmlData{
+			{Name: "status", Value: r.Status},
+			{Name: "state", Value: exportString(r.State)},
+			{Name: "city", Value: exportString(r.City)},
+			{Name: "share_url", Value: shareURL},
+			{Name: "confirmation_count", Value: strconv.Itoa(r.ConfirmationCount)},
+			{Name: "duplicate_count", Value: strconv.Itoa(r.DuplicateCount)},
+			{Name: "verified_at", Value: exportTime(r.VerifiedAt)},
+			{Name: "started_at", Value: exportTime(r.StartedAt)},
+			{Name: "resolved_at", Value: exportTime(r.ResolvedAt)},
+		},
+		// KML wants lng,lat
+		Point: kmlCoordinates{Coordinates: strconv.FormatFloat(r.Longitude, 'f', -1, 64) + "," +
+			strconv.FormatFloat(r.Latitude, 'f', -1, 64)},
+		TimeStamp: &kmlTimeStamp{When: exportTime(&r.CreatedAt)},
+	}
+	if err := e.enc.Encode(p); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "\n")
+	return err
+}
+
+func (e *kmlEncoder) end() error {
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
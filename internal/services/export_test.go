@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+)
+
+// exportRow carries private fields too, as if the allowlist were bypassed,
+// to show the encoders never write them
+func exportRow(fake *fakedb.DB) {
+	fake.SetRows("reports",
+		[]string{"id", "category", "latitude", "longitude", "description", "status", "city", "share_slug", "created_at",
+			"reporter_ip", "admin_notes", "edit_token_hash", "reporter_email"},
+		[]driver.Value{int64(12), "potholes", 26.9124, 75.7873, "Deep pothole", "verified", "Jaipur", "Xk3u9PzQaL1m", time.Now(),
+			"203.0.113.9", "caller sounded upset", "5e884898da28047151d0e56f8dc62927", "someone@example.org"},
+	)
+}
+
+var exportPrivate = []string{"reporter_ip", "203.0.113.9", "admin_notes", "caller sounded upset", "edit_token", "5e884898da28", "someone@example.org"}
+
+func exportFormat(t *testing.T, format string) (string, *fakedb.DB) {
+	t.Helper()
+	db, fake := fakedb.New(t)
+	exportRow(fake)
+	s := NewReportService(db, &config.Config{PublicBaseURL: "https://helpgovern.example"}, NewEventBus())
+	var out bytes.Buffer
+	n, err := s.ExportReports(context.Background(), &out, format, ReportFilter{})
+	if err != nil || n != 1 {
+		t.Fatalf("exported %d reports: %v", n, err)
+	}
+	for _, p := range exportPrivate {
+		if strings.Contains(out.String(), p) {
+			t.Errorf("%s export leaks %q:\n%s", format, p, out.String())
+		}
+	}
+	return out.String(), fake
+}
+
+func TestExportSelectsOnlyAllowedColumns(t *testing.T) {
+	_, fake := exportFormat(t, ExportCSV)
+	q := fake.Queried(`SELECT `)[0].Query
+	if strings.Contains(q, "*") {
+		t.Fatalf("export selects every column: %s", q)
+	}
+	selected := strings.TrimPrefix(q[:strings.Index(q, " FROM ")], "SELECT ")
+	for _, col := range strings.Split(selected, ",") {
+		col = strings.Trim(col, `"`)
+		allowed := false
+		for _, a := range exportColumns {
+			allowed = allowed || a == col
+		}
+		if !allowed {
+			t.Errorf("export selects %s", col)
+		}
+	}
+	for _, col := range exportColumns {
+		if col == "reporter_ip" || col == "admin_notes" || strings.HasPrefix(col, "edit_token") || strings.HasPrefix(col, "reporter_") {
+			t.Errorf("private column %s is allowed in exports", col)
+		}
+	}
+}
+
+func TestExportCSVColumns(t *testing.T) {
+	out, _ := exportFormat(t, ExportCSV)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id,category,latitude,longitude,description,status,state,city,share_url,confirmation_count,duplicate_count,created_at,verified_at,started_at,resolved_at"
+	if len(records) != 2 || strings.Join(records[0], ",") != want {
+		t.Fatalf("header %v, want %s", records[0], want)
+	}
+	if records[1][0] != "12" || records[1][8] != "https://helpgovern.example/r/Xk3u9PzQaL1m" {
+		t.Errorf("row %v", records[1])
+	}
+}
+
+func TestExportGeoJSONProperties(t *testing.T) {
+	out, _ := exportFormat(t, ExportGeoJSON)
+	var fc struct {
+		Features []struct {
+			ID         int                    `json:"id"`
+			Geometry   geoJSONPoint           `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal([]byte(out), &fc); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	if len(fc.Features) != 1 || fc.Features[0].Geometry.Coordinates != [2]float64{75.7873, 26.9124} {
+		t.Fatalf("features %+v, want report 12 at lng,lat", fc.Features)
+	}
+	allowed := map[string]bool{"category": true, "description": true, "status": true, "state": true, "city": true,
+		"share_url": true, "confirmation_count": true, "duplicate_count": true,
+		"created_at": true, "verified_at": true, "started_at": true, "resolved_at": true}
+	for k := range fc.Features[0].Properties {
+		if !allowed[k] {
+			t.Errorf("GeoJSON property %s is not allowed", k)
+		}
+	}
+}
+
+func TestExportKMLData(t *testing.T) {
+	out, _ := exportFormat(t, ExportKML)
+	var doc struct {
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid KML: %v", err)
+	}
+	if len(doc.Placemarks) != 1 || doc.Placemarks[0].Point.Coordinates != "75.7873,26.9124" {
+		t.Fatalf("placemarks %+v, want report 12 at lng,lat", doc.Placemarks)
+	}
+	allowed := map[string]bool{"status": true, "state": true, "city": true, "share_url": true, "confirmation_count": true,
+		"duplicate_count": true, "verified_at": true, "started_at": true, "resolved_at": true}
+	for _, d := range doc.Placemarks[0].ExtendedData {
+		if !allowed[d.Name] {
+			t.Errorf("KML data %s is not allowed", d.Name)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Heatmap tile formats
+const (
+	HeatmapPNG  = "png"
+	HeatmapJSON = "json"
+)
+
+const (
+	heatLayer = "heat"
+	// heatCellPx is the resolution reports are binned at before rendering
+	heatCellPx = 4
+	// heatJSONCellPx is the cell size of the JSON variant
+	heatJSONCellPx = 8
+	// heatRadiusPx is the radius of the blob drawn around each report; it
+	// must stay within tileCacheBufferPx
+	heatRadiusPx = 20
+	// heatSaturation is the kernel-weighted report count at which the ramp
+	// reaches full red
+	heatSaturation = 40.0
+)
+
+var ErrInvalidHeatmapFilter = errors.New("invalid heatmap filter")
+
+// HeatmapFilter narrows the reports counted in a heatmap tile
+type HeatmapFilter struct {
+	Category string
+	Status   string
+	Since    *time.Time
+	Until    *time.Time
+}
+
+// cacheable reports whether tiles for these filters go to the disk cache.
+// Categories and statuses are few, but date ranges are not, so tiles for a
+// date range are always rendered afresh.
+func (f HeatmapFilter) cacheable() bool {
+	return f.Since == nil && f.Until == nil
+}
+
+// variant names the cache entry for these filters and format
+func (f HeatmapFilter) variant(format string) string {
+	if f.Category == "" && f.Status == "" {
+		return "all." + format
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s", f.Category, f.Status)
+	return hex.EncodeToString(h.Sum(nil))[:16] + "." + format
+}
+
+// HeatmapService renders report density tiles
+type HeatmapService struct {
+	db    *gorm.DB
+	cache *TileCache
+}
+
+func NewHeatmapService(db *gorm.DB, cache *TileCache) *HeatmapService {
+	return &HeatmapService{db: db, cache: cache}
+}
+
+// heatBin is the number of reports in one cell, in cell coordinates
+// relative to the tile's top-left corner
+type heatBin struct {
+	CX int `gorm:"column:cx"`
+	CY int `gorm:"column:cy"`
+	N  int `gorm:"column:n"`
+}
+
+// bins counts reports per cellPx cell over the tile grown by bufferPx.
+// Binning happens in SQL so a tile costs the same at country scale as at
+// street level.
+func (s *HeatmapService) bins(ctx context.Context, z, x, y int, f HeatmapFilter, cellPx, bufferPx float64) ([]heatBin, error) {
+	minLat, minLng, maxLat, maxLng := utils.TileBounds(z, x, y, bufferPx)
+	filter := ReportFilter{
+		Category: f.Category,
+		Status:   f.Status,
+		Since:    f.Since,
+		Until:    f.Until,
+		BBox:     &BBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng},
+	}
+	args := tilePixelArgs(z, x, y)
+	args["cell"] = cellPx
+
+	var bins []heatBin
+	err := applyReportFilter(s.db.WithContext(ctx).Model(&models.Report{}), filter).
+		Select("floor("+tilePixelX+" / @cell)::int AS cx, floor("+tilePixelY+" / @cell)::int AS cy, COUNT(*) AS n", args).
+		Group("cx, cy").
+		Scan(&bins).Error
+	return bins, err
+}
+
+// validate rejects unknown categories and statuses, so only real ones get
+// a cache variant
+func (s *HeatmapService) validate(ctx context.Context, f HeatmapFilter) error {
+	if f.Status != "" && !validStatuses[f.Status] {
+		return fmt.Errorf("%w: unknown status", ErrInvalidHeatmapFilter)
+	}
+	if f.Category != "" {
+		var count int64
+		err := s.db.WithContext(ctx).Model(&models.Category{}).Where("name = ? AND is_active = TRUE", f.Category).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: unknown category", ErrInvalidHeatmapFilter)
+		}
+	}
+	return nil
+}
+
+// Tile returns heatmap tile z/x/y as a PNG or as JSON, from the disk cache
+// when possible
+func (s *HeatmapService) Tile(ctx context.Context, z, x, y int, format string, f HeatmapFilter) ([]byte, error) {
+	if err := s.validate(ctx, f); err != nil {
+		return nil, err
+	}
+	variant := f.variant(format)
+	if f.cacheable() {
+		if data, ok := s.cache.Get(heatLayer, z, x, y, variant); ok {
+			return data, nil
+		}
+	}
+	var data []byte
+	var err error
+	switch format {
+	case HeatmapPNG:
+		data, err = s.renderPNG(ctx, z, x, y, f)
+	case HeatmapJSON:
+		data, err = s.renderJSON(ctx, z, x, y, f)
+	default:
+		return nil, fmt.Errorf("unsupported heatmap format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.cacheable() {
+		if err := s.cache.Put(heatLayer, z, x, y, variant, data); err != nil {
+			utils.Error("heatmap: failed to cache tile %d/%d/%d: %v", z, x, y, err)
+		}
+	}
+	return data, nil
+}
+
+// heatKernel is a quartic falloff over heatRadiusPx, indexed by [dy][dx]
+var heatKernel = func() [][]float64 {
+	size := 2*heatRadiusPx + 1
+	k := make([][]float64, size)
+	for dy := range k {
+		k[dy] = make([]float64, size)
+		for dx := range k[dy] {
+			d2 := float64((dx-heatRadiusPx)*(dx-heatRadiusPx)+(dy-heatRadiusPx)*(dy-heatRadiusPx)) / (heatRadiusPx * heatRadiusPx)
+			if d2 < 1 {
+				k[dy][dx] = (1 - d2) * (1 - d2)
+			}
+		}
+	}
+	return k
+}()
+
+func (s *HeatmapService) renderPNG(ctx context.Context, z, x, y int, f HeatmapFilter) ([]byte, error) {
+	bins, err := s.bins(ctx, z, x, y, f, heatCellPx, heatRadiusPx)
+	if err != nil {
+		return nil, err
+	}
+	const size = utils.TileSize
+	density := make([]float64, size*size)
+	for _, b := range bins {
+		cx := b.CX*heatCellPx + heatCellPx/2
+		cy := b.CY*heatCellPx + heatCellPx/2
+		for dy := -heatRadiusPx; dy <= heatRadiusPx; dy++ {
+			py := cy + dy
+			if py < 0 || py >= size {
+				continue
+			}
+			row := heatKernel[dy+heatRadiusPx]
+			for dx := -heatRadiusPx; dx <= heatRadiusPx; dx++ {
+				px := cx + dx
+				if px < 0 || px >= size {
+					continue
+				}
+				density[py*size+px] += float64(b.N) * row[dx+heatRadiusPx]
+			}
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	norm := math.Log1p(heatSaturation)
+	for i, d := range density {
+		if d <= 0 {
+			continue
+		}
+		img.SetNRGBA(i%size, i/size, heatColor(math.Min(1, math.Log1p(d)/norm)))
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// heatRamp runs from blue through cyan, green and yellow to red
+var heatRamp = []color.NRGBA{
+	{0, 0, 255, 0},
+	{0, 128, 255, 140},
+	{0, 220, 120, 170},
+	{255, 230, 0, 200},
+	{255, 0, 0, 220},
+}
+
+// heatColor maps an intensity in [0, 1] onto heatRamp
+func heatColor(v float64) color.NRGBA {
+	pos := v * float64(len(heatRamp)-1)
+	i := int(pos)
+	if i >= len(heatRamp)-1 {
+		return heatRamp[len(heatRamp)-1]
+	}
+	t := pos - float64(i)
+	a, b := heatRamp[i], heatRamp[i+1]
+	mix := func(p, q uint8) uint8 { return uint8(float64(p) + (float64(q)-float64(p))*t) }
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// HeatmapCell is one JSON heatmap cell: its centre and report count
+type HeatmapCell [3]float64
+
+// HeatmapGrid is the JSON variant of a heatmap tile. Cells only cover the
+// tile itself, so neighbouring tiles never count a report twice.
+type HeatmapGrid struct {
+	Z      int           `json:"z"`
+	X      int           `json:"x"`
+	Y      int           `json:"y"`
+	CellPx int           `json:"cell_px"`
+	Max    int           `json:"max"`
+	Cells  []HeatmapCell `json:"cells"` // [lat, lng, count]
+}
+
+func (s *HeatmapService) renderJSON(ctx context.Context, z, x, y int, f HeatmapFilter) ([]byte, error) {
+	bins, err := s.bins(ctx, z, x, y, f, heatJSONCellPx, 0)
+	if err != nil {
+		return nil, err
+	}
+	grid := HeatmapGrid{Z: z, X: x, Y: y, CellPx: heatJSONCellPx, Cells: []HeatmapCell{}}
+	const cells = utils.TileSize / heatJSONCellPx
+	for _, b := range bins {
+		// The bbox prefilter is a little generous at the edges
+		if b.CX < 0 || b.CX >= cells || b.CY < 0 || b.CY >= cells {
+			continue
+		}
+		px := float64(x*utils.TileSize + b.CX*heatJSONCellPx + heatJSONCellPx/2)
+		py := float64(y*utils.TileSize + b.CY*heatJSONCellPx + heatJSONCellPx/2)
+		lat, lng := utils.PixelLatLng(px, py, z)
+		grid.Cells = append(grid.Cells, HeatmapCell{lat, lng, float64(b.N)})
+		if b.N > grid.Max {
+			grid.Max = b.N
+		}
+	}
+	return json.Marshal(grid)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyLockTimeout is how long a key stays claimed by a request that
+// never finished, e.g. because the server restarted mid-request
+const idempotencyLockTimeout = 2 * time.Minute
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotentResponse is the response recorded for an idempotency key
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyService records responses by client-supplied idempotency key,
+// so retried requests are answered without running again
+type IdempotencyService struct {
+	db *gorm.DB
+
+	// window is how long a response is kept for retries
+	window time.Duration
+}
+
+func NewIdempotencyService(db *gorm.DB, window time.Duration) *IdempotencyService {
+	return &IdempotencyService{db: db, window: window}
+}
+
+// Begin claims key for a request within scope (method and path). requestHash
+// fingerprints the request, so the key cannot be reused for another one.
+//
+// It returns the recorded response when the same request already completed,
+// ErrIdempotencyKeyReused when the key came with a different request and
+// ErrIdempotencyKeyInFlight while the first request is still running.
+// Otherwise the key is claimed and the caller must Complete or Release it.
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key, requestHash string) (*IdempotentResponse, error) {
+	keyHash := utils.HashToken(key)
+	now := time.Now()
+	claimed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Expired and abandoned keys are free to claim again
+		err := tx.Where("scope = ? AND key_hash = ?", scope, keyHash).
+			Where("expires_at <= ? OR (status_code IS NULL AND created_at <= ?)", now, now.Add(-idempotencyLockTimeout)).
+			Delete(&models.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.IdempotencyKey{
+			Scope:       scope,
+			KeyHash:     keyHash,
+			RequestHash: requestHash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.window),
+		})
+		claimed = res.RowsAffected == 1
+		return res.Error
+	})
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	var existing models.IdempotencyKey
+	err = s.db.WithContext(ctx).Where("scope = ? AND key_hash = ?", scope, keyHash).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released by the first request in the meantime
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == nil {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	body, err := openIdempotentResponse(key, existing.ResponseBody)
+	if err != nil {
+		return nil, err
+	}
+	return &IdempotentResponse{StatusCode: *existing.StatusCode, ContentType: existing.ContentType, Body: body}, nil
+}
+
+// Complete records the response to the request that claimed key
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, resp IdempotentResponse) error {
+	sealed, err := sealIdempotentResponse(key, resp.Body)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key_hash = ?", scope, utils.HashToken(key)).
+		Updates(map[string]interface{}{
+			"status_code":   resp.StatusCode,
+			"content_type":  resp.ContentType,
+			"response_body": sealed,
+		}).Error
+}
+
+// Release frees key after a request that should not be replayed, such as a
+// failed one, so the client can retry it for real
+func (s *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.db.WithContext(ctx).
+		Where("scope = ? AND key_hash = ? AND status_code IS NULL", scope, utils.HashToken(key)).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// Purge deletes expired keys
+func (s *IdempotencyService) Purge(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{}).Error
+}
+
+// Run purges expired keys immediately and then every interval until ctx is
+// cancelled
+func (s *IdempotencyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Purge(ctx); err != nil {
+			utils.Error("idempotency key purge: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Recorded responses are sealed with AES-256-GCM under a key derived from
+// the idempotency key, which only the client knows. The nonce is prepended.
+func idempotencyCipher(key string) (cipher.AEAD, error) {
+	k := sha256.Sum256([]byte("idempotent-response\x00" + key))
+	block, err := aes.NewCipher(k[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealIdempotentResponse(key string, body []byte) ([]byte, error) {
+	gcm, err := idempotencyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, body, nil), nil
+}
+
+func openIdempotentResponse(key string, sealed []byte) ([]byte, error) {
+	gcm, err := idempotencyCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("recorded response is truncated")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Limits from the API spec
+const (
+	MaxImagesPerReport = 3
+	MaxImageBytes      = 5 << 20
+)
+
+var (
+	ErrInvalidImage  = errors.New("invalid image")
+	ErrImageNotFound = errors.New("image not found")
+)
+
+type ImageService struct {
+	db     *gorm.DB
+	store  ImageStore
+	events *EventBus
+}
+
+func NewImageService(db *gorm.DB, store ImageStore, events *EventBus) *ImageService {
+	return &ImageService{db: db, store: store, events: events}
+}
+
+// UploadedImage is a decoded and validated image ready to be stored
+type UploadedImage struct {
+	Data        []byte
+	ContentType string
+}
+
+// DecodeImages validates base64 images as sent by clients (optionally as
+// data: URLs). Supported formats: JPEG, PNG, WebP; max 5MB each, 3 per request.
+func DecodeImages(encoded []string) ([]UploadedImage, error) {
+	if len(encoded) > MaxImagesPerReport {
+		return nil, fmt.Errorf("%w: at most %d images are allowed", ErrInvalidImage, MaxImagesPerReport)
+	}
+	images := make([]UploadedImage, 0, len(encoded))
+	for i, e := range encoded {
+		if idx := strings.Index(e, ","); strings.HasPrefix(e, "data:") && idx >= 0 {
+			e = e[idx+1:]
+		}
+		data, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w: image %d is not valid base64", ErrInvalidImage, i+1)
+		}
+		img, err := ValidateImage(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w (image %d)", err, i+1)
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// ValidateImage checks size and format of raw image bytes
+func ValidateImage(data []byte) (UploadedImage, error) {
+	if len(data) == 0 {
+		return UploadedImage{}, fmt.Errorf("%w: image is empty", ErrInvalidImage)
+	}
+	if len(data) > MaxImageBytes {
+		return UploadedImage{}, fmt.Errorf("%w: image exceeds 5MB", ErrInvalidImage)
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return UploadedImage{}, fmt.Errorf("%w: unsupported format %s", ErrInvalidImage, contentType)
+	}
+	return UploadedImage{Data: data, ContentType: contentType}, nil
+}
+
+// StoreImages uploads the images and records them against the report inside tx.
+// claimID is set for resolution photos submitted with a resolution claim.
+// On failure any files already uploaded are removed again; if tx fails to
+// commit afterwards, the caller must remove them with RemoveImages.
+func (s *ImageService) StoreImages(ctx context.Context, tx *gorm.DB, reportID int, claimID *int, imageType string, images []UploadedImage) ([]models.Image, error) {
+	saved := make([]models.Image, 0, len(images))
+	for _, img := range images {
+		url, publicID, err := s.store.Save(ctx, img.Data, img.ContentType)
+		if err != nil {
+			s.RemoveImages(ctx, saved)
+			return nil, err
+		}
+		saved = append(saved, models.Image{
+			ReportID:           reportID,
+			ResolutionClaimID:  claimID,
+			CloudinaryURL:      url,
+			CloudinaryPublicID: publicID,
+			ImageType:          imageType,
+			ModerationStatus:   "pending",
+		})
+	}
+	if len(saved) == 0 {
+		return saved, nil
+	}
+	if err := tx.WithContext(ctx).Create(&saved).Error; err != nil {
+		s.RemoveImages(ctx, saved)
+		return nil, err
+	}
+	return saved, nil
+}
+
+// RemoveImages deletes the stored files of images whose rows were never
+// committed. It runs even when ctx is done, since a cancelled request is a
+// common reason for the rollback.
+func (s *ImageService) RemoveImages(ctx context.Context, images []models.Image) {
+	ctx = context.WithoutCancel(ctx)
+	for _, img := range images {
+		if err := s.store.Delete(ctx, img.CloudinaryPublicID); err != nil {
+			utils.Error("failed to remove orphaned image %s: %v", img.CloudinaryPublicID, err)
+		}
+	}
+}
+
+// AddReportImages attaches more photos of the issue to an open report, keeping
+// the per-report limit across uploads.
+func (s *ImageService) AddReportImages(ctx context.Context, report *models.Report, images []UploadedImage) ([]models.Image, error) {
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+	var saved []models.Image
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.Image{}).
+			Where("report_id = ? AND image_type = ?", report.ID, models.ImageTypeReport).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if int(existing)+len(images) > MaxImagesPerReport {
+			return fmt.Errorf("%w: a report can have at most %d images", ErrInvalidImage, MaxImagesPerReport)
+		}
+		var err error
+		saved, err = s.StoreImages(ctx, tx, report.ID, nil, models.ImageTypeReport, images)
+		return err
+	})
+	if err != nil {
+		s.RemoveImages(ctx, saved)
+		return nil, err
+	}
+	return saved, nil
+}
+
+// ModerateImage approves or rejects a photo and publishes image.approved or
+// image.rejected
+func (s *ImageService) ModerateImage(ctx context.Context, id int, status, notes string, moderatedBy *int) (*models.Image, error) {
+	var img models.Image
+	if err := s.db.WithContext(ctx).First(&img, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+	now := time.Now()
+	img.ModerationStatus = status
+	img.ModeratedAt = &now
+	img.ModeratedBy = moderatedBy
+	if notes != "" {
+		img.ModerationNotes = &notes
+	}
+	if err := s.db.WithContext(ctx).Model(&img).Updates(map[string]interface{}{
+		"moderation_status": img.ModerationStatus,
+		"moderation_notes":  img.ModerationNotes,
+		"moderated_at":      img.ModeratedAt,
+		"moderated_by":      img.ModeratedBy,
+	}).Error; err != nil {
+		return nil, err
+	}
+	s.publishModeration(ctx, &img)
+	return &img, nil
+}
+
+// publishModeration announces a moderation decision. Call it after the
+// change is committed.
+func (s *ImageService) publishModeration(ctx context.Context, img *models.Image) {
+	eventType := EventImageRejected
+	if img.ModerationStatus == "approved" {
+		eventType = EventImageApproved
+	}
+	var report models.Report
+	if err := s.db.WithContext(ctx).First(&report, img.ReportID).Error; err != nil {
+		utils.Error("failed to load report %d for %s event: %v", img.ReportID, eventType, err)
+		return
+	}
+	s.events.Publish(ctx, Event{Type: eventType, Report: &report, Image: img})
+}
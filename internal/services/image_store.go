@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// ImageStore persists uploaded image bytes and returns where they can be
+// fetched from. Cloudinary will implement this once integrated; until then
+// images are kept on local disk.
+type ImageStore interface {
+	Save(ctx context.Context, data []byte, contentType string) (url, publicID string, err error)
+	Delete(ctx context.Context, publicID string) error
+}
+
+// LocalImageStore writes images into Dir and serves them under URLPrefix
+type LocalImageStore struct {
+	Dir       string
+	URLPrefix string
+}
+
+func NewLocalImageStore(dir, urlPrefix string) (*LocalImageStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	return &LocalImageStore{Dir: dir, URLPrefix: urlPrefix}, nil
+}
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+func (s *LocalImageStore) Save(ctx context.Context, data []byte, contentType string) (string, string, error) {
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported image type %q", contentType)
+	}
+	name, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	name += ext
+	if err := os.WriteFile(filepath.Join(s.Dir, name), data, 0o644); err != nil {
+		return "", "", err
+	}
+	return s.URLPrefix + "/" + name, name, nil
+}
+
+func (s *LocalImageStore) Delete(ctx context.Context, publicID string) error {
+	err := os.Remove(filepath.Join(s.Dir, filepath.Base(publicID)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
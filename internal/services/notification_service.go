@@ -0,0 +1,580 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Queue tuning: a failed notification is retried with exponential backoff
+// (2, 4, 8, 16 minutes ...) until maxNotificationAttempts is reached.
+const (
+	maxNotificationAttempts = 6
+	notificationBatchSize   = 20
+
+	// notificationLease is how long a claimed batch has to be sent before
+	// another worker may claim it again
+	notificationLease = 5 * time.Minute
+
+	// digestInterval is how often a daily-delivery subscription gets its digest
+	digestInterval = 24 * time.Hour
+)
+
+// statusLabels are the reader-facing names of report statuses
+var statusLabels = map[string]map[string]string{
+	"en": {
+		"pending":     "Pending",
+		"verified":    "Verified",
+		"in_progress": "In progress",
+		"resolved":    "Resolved",
+		"rejected":    "Rejected",
+		"withdrawn":   "Withdrawn",
+	},
+	"hi": {
+		"pending":     "लंबित",
+		"verified":    "सत्यापित",
+		"in_progress": "प्रगति पर",
+		"resolved":    "हल हो गया",
+		"rejected":    "अस्वीकृत",
+		"withdrawn":   "वापस लिया गया",
+	},
+}
+
+// statusLabel names status in locale, following the locale's fallback chain
+func (s *NotificationService) statusLabel(locale, status string) string {
+	for _, l := range s.templates.translations.Chain(locale) {
+		if label, ok := statusLabels[l][status]; ok {
+			return label
+		}
+	}
+	return status
+}
+
+// StatusChangedMessage is the data passed to the status_changed templates
+type StatusChangedMessage struct {
+	ReportID       int
+	Category       string
+	OldStatus      string
+	NewStatus      string
+	Notes          string
+	ShareURL       string
+	MapURL         string
+	UnsubscribeURL string
+	// Area is set when the recipient follows the report's area rather than the report
+	Area bool
+}
+
+// NewReportMessage is the data passed to the new_report templates
+type NewReportMessage struct {
+	ReportID       int
+	Category       string
+	Description    string
+	City           string
+	ShareURL       string
+	MapURL         string
+	UnsubscribeURL string
+}
+
+// DigestMessage is the data passed to the digest templates
+type DigestMessage struct {
+	Count          int
+	Items          []DigestEntry
+	UnsubscribeURL string
+}
+
+// DigestEntry is one event in a digest
+type DigestEntry struct {
+	ReportID int
+	Category string
+	Event    string // new_report or status_changed
+	Status   string
+	ShareURL string
+}
+
+// ConfirmSubscriptionMessage is the data passed to the confirm_subscription
+// templates
+type ConfirmSubscriptionMessage struct {
+	ReportID       int
+	ConfirmURL     string
+	UnsubscribeURL string
+	// Area is set for area subscriptions
+	Area bool
+}
+
+// NotificationService renders notifications for subscribers, queues them in
+// the database and delivers them through the registered notifiers.
+type NotificationService struct {
+	db            *gorm.DB
+	templates     *NotificationTemplates
+	subscriptions *SubscriptionService
+	notifiers     map[string]Notifier
+	baseURL       string
+}
+
+func NewNotificationService(db *gorm.DB, templates *NotificationTemplates, subscriptions *SubscriptionService, publicBaseURL string) *NotificationService {
+	return &NotificationService{
+		db:            db,
+		templates:     templates,
+		subscriptions: subscriptions,
+		notifiers:     make(map[string]Notifier),
+		baseURL:       strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// RegisterNotifier enables delivery over a channel. Notifications for
+// channels without a notifier are not queued.
+func (s *NotificationService) RegisterNotifier(channel string, n Notifier) {
+	s.notifiers[channel] = n
+}
+
+// Supports reports whether a notifier is registered for channel
+func (s *NotificationService) Supports(channel string) bool {
+	_, ok := s.notifiers[channel]
+	return ok
+}
+
+// HandleEvent is subscribed to the EventBus
+func (s *NotificationService) HandleEvent(ctx context.Context, e Event) {
+	switch e.Type {
+	case EventReportStatusChanged:
+		if e.StatusUpdate == nil {
+			return
+		}
+		if err := s.notifyStatusChanged(ctx, e.Report, e.StatusUpdate); err != nil {
+			utils.Error("failed to queue status notifications for report %d: %v", e.Report.ID, err)
+		}
+		if err := s.notifyArea(ctx, e.Report, e.StatusUpdate); err != nil {
+			utils.Error("failed to queue area notifications for report %d: %v", e.Report.ID, err)
+		}
+	case EventReportCreated:
+		if err := s.notifyArea(ctx, e.Report, nil); err != nil {
+			utils.Error("failed to queue area notifications for report %d: %v", e.Report.ID, err)
+		}
+	}
+}
+
+// notifyStatusChanged queues a message for every subscriber of the report
+func (s *NotificationService) notifyStatusChanged(ctx context.Context, report *models.Report, update *models.StatusUpdate) error {
+	var subs []models.Subscription
+	if err := s.db.WithContext(ctx).Where("report_id = ? AND confirmed_at IS NOT NULL", report.ID).Find(&subs).Error; err != nil {
+		return err
+	}
+	for i := range subs {
+		sub := &subs[i]
+		if _, ok := s.notifiers[sub.Channel]; !ok {
+			continue
+		}
+		n, err := s.statusChangedNotification(report, update, sub)
+		if err != nil {
+			return err
+		}
+		if err := s.Enqueue(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) statusChangedNotification(report *models.Report, update *models.StatusUpdate, sub *models.Subscription) (*models.Notification, error) {
+	msg := StatusChangedMessage{
+		ReportID:       report.ID,
+		Category:       strings.ReplaceAll(report.Category, "_", " "),
+		NewStatus:      s.statusLabel(sub.Locale, update.NewStatus),
+		ShareURL:       report.GenerateShareURL(s.baseURL),
+		MapURL:         report.MapImageURL(s.baseURL),
+		UnsubscribeURL: s.unsubscribeURL(sub),
+		Area:           sub.ReportID == nil,
+	}
+	if update.OldStatus != nil {
+		msg.OldStatus = s.statusLabel(sub.Locale, *update.OldStatus)
+	}
+	if update.Notes != nil {
+		msg.Notes = *update.Notes
+	}
+	n := &models.Notification{
+		Channel:        sub.Channel,
+		SubscriptionID: &sub.ID,
+		Event:          "status_changed",
+		Locale:         sub.Locale,
+	}
+	push := PushMessage{
+		Body:     msg.NewStatus,
+		URL:      msg.ShareURL,
+		ReportID: report.ID,
+		Status:   update.NewStatus,
+	}
+	if msg.Notes != "" {
+		push.Body += ": " + msg.Notes
+	}
+	if err := s.render(n, sub, msg, push); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// notifyArea queues a message for every area subscription matching the
+// report: a new_report message when update is nil, otherwise a status change.
+// Daily-delivery subscriptions get a digest item instead.
+func (s *NotificationService) notifyArea(ctx context.Context, report *models.Report, update *models.StatusUpdate) error {
+	subs, err := s.subscriptions.AreaSubscriptionsMatching(ctx, report)
+	if err != nil {
+		return err
+	}
+	for i := range subs {
+		sub := &subs[i]
+		if _, ok := s.notifiers[sub.Channel]; !ok {
+			continue
+		}
+		if sub.Delivery == models.DeliveryDaily {
+			item := &models.DigestItem{
+				SubscriptionID: sub.ID,
+				ReportID:       report.ID,
+				Event:          "new_report",
+				Status:         report.Status,
+			}
+			if update != nil {
+				item.Event, item.Status = "status_changed", update.NewStatus
+			}
+			if err := s.db.WithContext(ctx).Create(item).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		var n *models.Notification
+		if update != nil {
+			n, err = s.statusChangedNotification(report, update, sub)
+		} else {
+			n, err = s.newReportNotification(report, sub)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.Enqueue(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) newReportNotification(report *models.Report, sub *models.Subscription) (*models.Notification, error) {
+	msg := NewReportMessage{
+		ReportID:       report.ID,
+		Category:       strings.ReplaceAll(report.Category, "_", " "),
+		Description:    report.Description,
+		ShareURL:       report.GenerateShareURL(s.baseURL),
+		MapURL:         report.MapImageURL(s.baseURL),
+		UnsubscribeURL: s.unsubscribeURL(sub),
+	}
+	if report.City != nil {
+		msg.City = *report.City
+	}
+	n := &models.Notification{
+		Channel:        sub.Channel,
+		SubscriptionID: &sub.ID,
+		Event:          "new_report",
+		Locale:         sub.Locale,
+	}
+	push := PushMessage{
+		Body:     report.Description,
+		URL:      msg.ShareURL,
+		ReportID: report.ID,
+		Status:   report.Status,
+	}
+	if err := s.render(n, sub, msg, push); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// SendDigests queues a digest for every daily-delivery subscription that has
+// pending items and has not had one in the last digestInterval.
+func (s *NotificationService) SendDigests(ctx context.Context) (int, error) {
+	var subs []models.Subscription
+	err := s.db.WithContext(ctx).
+		Where("delivery = ? AND COALESCE(last_digest_at, created_at) <= ?", models.DeliveryDaily, time.Now().Add(-digestInterval)).
+		Where("EXISTS (SELECT 1 FROM digest_items d WHERE d.subscription_id = subscriptions.id)").
+		Find(&subs).Error
+	if err != nil {
+		return 0, err
+	}
+	var sent int
+	for i := range subs {
+		if _, ok := s.notifiers[subs[i].Channel]; !ok {
+			continue
+		}
+		if err := s.sendDigest(ctx, &subs[i]); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendDigest renders and queues one subscription's digest, then clears its items
+func (s *NotificationService) sendDigest(ctx context.Context, sub *models.Subscription) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var items []models.DigestItem
+		if err := tx.Where("subscription_id = ?", sub.ID).Order("created_at, id").Find(&items).Error; err != nil {
+			return err
+		}
+		reportIDs := make([]int, 0, len(items))
+		for _, item := range items {
+			reportIDs = append(reportIDs, item.ReportID)
+		}
+		var reports []models.Report
+		if err := tx.Select("id", "category", "share_slug").Where("id IN ?", reportIDs).Find(&reports).Error; err != nil {
+			return err
+		}
+		byID := make(map[int]*models.Report, len(reports))
+		for i := range reports {
+			byID[reports[i].ID] = &reports[i]
+		}
+
+		msg := DigestMessage{UnsubscribeURL: s.unsubscribeURL(sub)}
+		for _, item := range items {
+			report, ok := byID[item.ReportID]
+			if !ok {
+				continue
+			}
+			msg.Items = append(msg.Items, DigestEntry{
+				ReportID: item.ReportID,
+				Category: strings.ReplaceAll(report.Category, "_", " "),
+				Event:    item.Event,
+				Status:   s.statusLabel(sub.Locale, item.Status),
+				ShareURL: report.GenerateShareURL(s.baseURL),
+			})
+		}
+		msg.Count = len(msg.Items)
+
+		if msg.Count > 0 {
+			n := &models.Notification{
+				Channel:        sub.Channel,
+				SubscriptionID: &sub.ID,
+				Event:          "digest",
+				Locale:         sub.Locale,
+			}
+			lines := make([]string, 0, msg.Count)
+			for _, e := range msg.Items {
+				lines = append(lines, fmt.Sprintf("#%d %s: %s", e.ReportID, e.Category, e.Status))
+			}
+			push := PushMessage{Body: strings.Join(lines, "\n"), URL: s.baseURL + "/"}
+			if err := s.render(n, sub, msg, push); err != nil {
+				return err
+			}
+			if err := s.withDB(tx).Enqueue(ctx, n); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&models.DigestItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(sub).Update("last_digest_at", time.Now()).Error
+	})
+}
+
+// withDB returns a shallow copy of the service that writes through db,
+// e.g. a transaction
+func (s *NotificationService) withDB(db *gorm.DB) *NotificationService {
+	clone := *s
+	clone.db = db
+	return &clone
+}
+
+// render fills in the notification content for the subscriber's channel.
+// Emails use the full templates; push messages take the template subject as
+// their title and the given short body.
+func (s *NotificationService) render(n *models.Notification, sub *models.Subscription, data interface{}, push PushMessage) error {
+	subject, text, html, err := s.templates.Render(n.Event, sub.Locale, data)
+	if err != nil {
+		return err
+	}
+	switch sub.Channel {
+	case models.ChannelEmail:
+		if sub.Email == nil {
+			return fmt.Errorf("email subscription %d has no address", sub.ID)
+		}
+		n.Recipient, n.Subject, n.BodyText, n.BodyHTML = *sub.Email, subject, text, html
+	case models.ChannelWebPush:
+		if sub.PushEndpoint == nil {
+			return fmt.Errorf("push subscription %d has no endpoint", sub.ID)
+		}
+		push.Title = subject
+		push.Body = utils.Truncate(push.Body, 500)
+		payload, err := json.Marshal(push)
+		if err != nil {
+			return err
+		}
+		n.Recipient, n.Subject, n.BodyText = *sub.PushEndpoint, subject, string(payload)
+	default:
+		return fmt.Errorf("unsupported channel %q", sub.Channel)
+	}
+	return nil
+}
+
+func (s *NotificationService) unsubscribeURL(sub *models.Subscription) string {
+	return s.baseURL + "/subscriptions/" + sub.UnsubscribeToken + "/unsubscribe"
+}
+
+// RequestConfirmation emails the owner of an unconfirmed email subscription
+// a link that activates it. Asking again replaces the previous link.
+func (s *NotificationService) RequestConfirmation(ctx context.Context, sub *models.Subscription) error {
+	if sub.ConfirmedAt != nil || sub.Channel != models.ChannelEmail {
+		return nil
+	}
+	token, err := utils.RandomToken(24)
+	if err != nil {
+		return err
+	}
+	msg := ConfirmSubscriptionMessage{
+		ConfirmURL:     s.baseURL + "/subscriptions/" + token + "/confirm",
+		UnsubscribeURL: s.unsubscribeURL(sub),
+		Area:           sub.ReportID == nil,
+	}
+	if sub.ReportID != nil {
+		msg.ReportID = *sub.ReportID
+	}
+	n := &models.Notification{
+		Channel:        sub.Channel,
+		SubscriptionID: &sub.ID,
+		Event:          "confirm_subscription",
+		Locale:         sub.Locale,
+	}
+	if err := s.render(n, sub, msg, PushMessage{}); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(sub).Update("confirm_token_hash", utils.HashToken(token)).Error; err != nil {
+			return err
+		}
+		return s.withDB(tx).Enqueue(ctx, n)
+	})
+}
+
+// Enqueue stores a notification for the queue worker
+func (s *NotificationService) Enqueue(ctx context.Context, n *models.Notification) error {
+	if _, ok := s.notifiers[n.Channel]; !ok {
+		return fmt.Errorf("no notifier registered for channel %q", n.Channel)
+	}
+	n.Status = "pending"
+	if n.NextAttemptAt.IsZero() {
+		n.NextAttemptAt = time.Now()
+	}
+	return s.db.WithContext(ctx).Create(n).Error
+}
+
+// ProcessQueue delivers due notifications. A batch is claimed first and then
+// sent outside of any transaction, so slow mail servers hold no locks.
+func (s *NotificationService) ProcessQueue(ctx context.Context) (int, error) {
+	due, err := s.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var processed int
+	for i := range due {
+		if err := s.deliver(ctx, &due[i]); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// claim leases a batch of due notifications to this worker by marking them
+// sending until notificationLease has passed. Rows are locked with SKIP
+// LOCKED so several workers can share the queue; a worker that dies leaves
+// its batch to be claimed again once the lease runs out.
+func (s *NotificationService) claim(ctx context.Context) ([]models.Notification, error) {
+	now := time.Now()
+	var due []models.Notification
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{"pending", "sending"}, now).
+			Order("next_attempt_at").Limit(notificationBatchSize).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		ids := make([]int, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+			due[i].Attempts++
+		}
+		return tx.Model(&models.Notification{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          "sending",
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(notificationLease),
+		}).Error
+	})
+	return due, err
+}
+
+// deliver sends one claimed notification and records the outcome
+func (s *NotificationService) deliver(ctx context.Context, n *models.Notification) error {
+	now := time.Now()
+	updates := map[string]interface{}{}
+	notifier, ok := s.notifiers[n.Channel]
+	var sendErr error
+	if !ok {
+		sendErr = fmt.Errorf("no notifier registered for channel %q", n.Channel)
+	} else {
+		sendErr = notifier.Send(ctx, n)
+	}
+	switch {
+	case sendErr == nil:
+		updates["status"] = "sent"
+		updates["sent_at"] = now
+		updates["last_error"] = nil
+	case errors.Is(sendErr, ErrRecipientGone):
+		utils.Info("notification %d: recipient gone, dropping subscription", n.ID)
+		updates["status"] = "failed"
+		updates["last_error"] = sendErr.Error()
+		if n.SubscriptionID != nil {
+			if err := s.db.WithContext(ctx).Delete(&models.Subscription{}, *n.SubscriptionID).Error; err != nil {
+				return err
+			}
+		}
+	case !ok || n.Attempts >= maxNotificationAttempts:
+		utils.Error("notification %d failed permanently: %v", n.ID, sendErr)
+		updates["status"] = "failed"
+		updates["last_error"] = sendErr.Error()
+	default:
+		utils.Error("notification %d failed (attempt %d), will retry: %v", n.ID, n.Attempts, sendErr)
+		updates["status"] = "pending"
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = now.Add(time.Minute << uint(n.Attempts))
+	}
+	return s.db.WithContext(ctx).Model(n).Updates(updates).Error
+}
+
+// Run processes the queue every interval, and checks for due digests every
+// hour, until ctx is cancelled
+func (s *NotificationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	digests := time.NewTicker(time.Hour)
+	defer digests.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ProcessQueue(ctx); err != nil {
+				utils.Error("notification queue: %v", err)
+			}
+		case <-digests.C:
+			if _, err := s.SendDigests(ctx); err != nil {
+				utils.Error("notification digests: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used when a template is missing for the requested locale
+const DefaultLocale = "en"
+
+// NotificationTemplates holds the HTML and text templates per event and locale.
+// Files are named <event>.<locale>.html and <event>.<locale>.txt; the text
+// template also defines the "subject" block.
+//
+// With translations set, a template stored as a translation takes precedence
+// over a file for the same locale, and a locale without either follows its
+// fallback chain (mr → hi → en).
+type NotificationTemplates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+	// source keeps the English template files, the text translators work from
+	source map[string]string
+
+	translations *TranslationService
+}
+
+// LoadNotificationTemplates parses every template file in dir
+func LoadNotificationTemplates(dir string) (*NotificationTemplates, error) {
+	t := &NotificationTemplates{
+		html:   make(map[string]*htmltemplate.Template),
+		text:   make(map[string]*texttemplate.Template),
+		source: make(map[string]string),
+	}
+	htmlFiles, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range htmlFiles {
+		tmpl, err := htmltemplate.ParseFiles(f)
+		if err != nil {
+			return nil, err
+		}
+		t.html[templateKey(f, ".html")] = tmpl
+		if err := t.keepSource(f, ".html", "html"); err != nil {
+			return nil, err
+		}
+	}
+	textFiles, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range textFiles {
+		tmpl, err := texttemplate.ParseFiles(f)
+		if err != nil {
+			return nil, err
+		}
+		t.text[templateKey(f, ".txt")] = tmpl
+		if err := t.keepSource(f, ".txt", "text"); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *NotificationTemplates) keepSource(path, ext, field string) error {
+	event, locale, _ := strings.Cut(templateKey(path, ext), ".")
+	if locale != DefaultLocale {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t.source[event+"."+field] = string(data)
+	return nil
+}
+
+// SetTranslations makes Render prefer templates stored as translations and
+// follow locale fallback chains
+func (t *NotificationTemplates) SetTranslations(translations *TranslationService) {
+	t.translations = translations
+}
+
+// Events lists the events with an English template, in name order
+func (t *NotificationTemplates) Events() []string {
+	var events []string
+	for key := range t.text {
+		if event, locale, _ := strings.Cut(key, "."); locale == DefaultLocale {
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	return events
+}
+
+// Has reports whether event has an English template
+func (t *NotificationTemplates) Has(event string) bool {
+	_, ok := t.text[event+"."+DefaultLocale]
+	return ok
+}
+
+// Source returns the English template source of event's "text" or "html" part
+func (t *NotificationTemplates) Source(event, field string) (string, bool) {
+	src, ok := t.source[event+"."+field]
+	return src, ok
+}
+
+// templateKey turns ".../status_changed.hi.html" into "status_changed.hi"
+func templateKey(path, ext string) string {
+	return strings.TrimSuffix(filepath.Base(path), ext)
+}
+
+// Render executes the templates for event in locale, falling back along the
+// locale's chain to the default locale. The HTML body is optional; the text
+// template is required.
+func (t *NotificationTemplates) Render(event, locale string, data interface{}) (subject, text, html string, err error) {
+	textTmpl, htmlTmpl, err := t.lookup(event, locale)
+	if err != nil {
+		return "", "", "", err
+	}
+	var buf bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := textTmpl.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = strings.TrimSpace(buf.String())
+	if htmlTmpl != nil {
+		buf.Reset()
+		if err := htmlTmpl.Execute(&buf, data); err != nil {
+			return "", "", "", err
+		}
+		html = buf.String()
+	}
+	return subject, text, html, nil
+}
+
+// lookup finds the first locale on the chain with a text template for event,
+// stored translations before files, and its HTML template if any
+func (t *NotificationTemplates) lookup(event, locale string) (*texttemplate.Template, *htmltemplate.Template, error) {
+	for _, l := range t.translations.Chain(locale) {
+		if src, ok := t.translations.Get(EntityNotificationTemplate, event, "text", l); ok {
+			textTmpl, err := texttemplate.New(event).Parse(src)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s template for %q: %w", event, l, err)
+			}
+			htmlTmpl := t.html[event+"."+l]
+			if src, ok := t.translations.Get(EntityNotificationTemplate, event, "html", l); ok {
+				if htmlTmpl, err = htmltemplate.New(event).Parse(src); err != nil {
+					return nil, nil, fmt.Errorf("%s HTML template for %q: %w", event, l, err)
+				}
+			}
+			return textTmpl, htmlTmpl, nil
+		}
+		if textTmpl, ok := t.text[event+"."+l]; ok {
+			return textTmpl, t.html[event+"."+l], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no template for event %q", event)
+}
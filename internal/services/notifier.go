@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+// ErrRecipientGone tells the queue a recipient no longer exists (e.g. an
+// expired push subscription): the notification is dropped without retrying.
+var ErrRecipientGone = errors.New("recipient gone")
+
+// Notifier delivers a queued notification over one channel
+type Notifier interface {
+	Send(ctx context.Context, n *models.Notification) error
+}
@@ -2,18 +2,111 @@ package services
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/projects-for-public/help-govern/internal/config"
 	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrReportNotFound = errors.New("report not found")
+	ErrInvalidMerge   = errors.New("invalid merge")
+	ErrReportClosed   = errors.New("report is no longer open")
+	ErrInvalidToken   = errors.New("invalid edit token")
+	ErrInvalidReport  = errors.New("invalid report")
+	// ErrSubmissionConflict means a submission ID was already used by
+	// someone else: anonymously for a draft, or by another volunteer key
+	ErrSubmissionConflict = errors.New("submission ID belongs to another report")
+)
+
+// openStatuses are the statuses of reports that still await resolution.
+var openStatuses = []string{"pending", "verified", "in_progress"}
+
+// validStatuses are the statuses a moderator may set
+var validStatuses = map[string]bool{
+	"pending": true, "verified": true, "in_progress": true, "resolved": true, "rejected": true,
+}
+
 type ReportService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	events *EventBus
+}
+
+func NewReportService(db *gorm.DB, cfg *config.Config, events *EventBus) *ReportService {
+	return &ReportService{db: db, cfg: cfg, events: events}
+}
+
+// ReportFilter narrows and orders ListReports results.
+// Zero values mean "no filter".
+type ReportFilter struct {
+	IDs              []int
+	Category         string
+	Status           string
+	Statuses         []string // any of these; takes precedence over Status
+	MinConfirmations int
+	State            string
+	City             string
+	BBox             *BBox
+	Since            *time.Time // created at or after
+	Until            *time.Time // created before
+	Sort             string     // "created_at" (default), "confirmations", "priority" or "activity"
+	Limit            int
+	// AllImages loads images awaiting or failing moderation too; admin only
+	AllImages bool
 }
 
-func NewReportService(db *gorm.DB) *ReportService {
-	return &ReportService{db: db}
+// BBox is a lat/lng bounding box
+type BBox struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// priorityExpr mirrors models.Report.Priority in SQL
+const priorityExpr = "(1 + confirmation_count + duplicate_count)"
+
+// DuplicateCandidate is an existing open report that may describe the same issue
+// as a new submission.
+type DuplicateCandidate struct {
+	ID             int       `json:"id"`
+	Category       string    `json:"category"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Description    string    `json:"description"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	DuplicateCount int       `json:"duplicate_count"`
+	DistanceMeters float64   `json:"distance_m"`
+}
+
+// ListCategories returns the active categories in display order
+func (s *ReportService) ListCategories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := s.db.WithContext(ctx).Where("is_active = TRUE").Order("sort_order, name").Find(&categories).Error
+	return categories, err
+}
+
+// ValidateNewReport checks a submission before CreateReport: coordinates
+// must be in range and the category must exist. Shared by every way of
+// submitting a report.
+func (s *ReportService) ValidateNewReport(ctx context.Context, report *models.Report) error {
+	if report.Latitude < -90 || report.Latitude > 90 || report.Longitude < -180 || report.Longitude > 180 {
+		return fmt.Errorf("%w: invalid latitude or longitude", ErrInvalidReport)
+	}
+	exists, err := s.CategoryExists(ctx, report.Category)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: invalid category", ErrInvalidReport)
+	}
+	return nil
 }
 
 // CategoryExists checks if a category exists in the database
@@ -27,34 +120,648 @@ func (s *ReportService) CategoryExists(ctx context.Context, name string) (bool,
 	return count > 0, nil
 }
 
-// CreateReport creates a new report
-func (s *ReportService) CreateReport(ctx context.Context, report *models.Report) error {
-	return s.db.WithContext(ctx).Create(report).Error
+// CreateReport creates a new report with a random share slug. It returns the
+// private edit token for the reporter; only its hash is stored, so it cannot
+// be recovered later.
+func (s *ReportService) CreateReport(ctx context.Context, report *models.Report) (string, error) {
+	return s.createReport(ctx, report, nil)
+}
+
+// createReport is CreateReport, also running with in the same transaction,
+// e.g. to store the report's photos; an error from with rolls the report
+// back and nothing is published
+func (s *ReportService) createReport(ctx context.Context, report *models.Report, with func(tx *gorm.DB) error) (string, error) {
+	slug, err := utils.RandomToken(9)
+	if err != nil {
+		return "", err
+	}
+	editToken, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	hash := utils.HashToken(editToken)
+	report.ShareSlug = slug
+	report.EditTokenHash = &hash
+	report.CreatedAt = time.Now()
+	report.LastActivityAt = report.CreatedAt
+	if lang := utils.DetectLanguage(report.Description); lang != "" {
+		report.DescriptionLanguage = &lang
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+		if with != nil {
+			return with(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	s.events.Publish(ctx, Event{Type: EventReportCreated, Report: report})
+	return editToken, nil
+}
+
+// CreateDraftReport creates a report drafted offline, at most once per
+// submission ID. When the submission was already received it loads that
+// report into report instead and returns no edit token, since only the
+// first response carries it; created tells the two apart. Two first
+// attempts racing each other meet on the unique index, and the loser is
+// answered as a replay.
+func (s *ReportService) CreateDraftReport(ctx context.Context, report *models.Report, submissionID string) (editToken string, created bool, err error) {
+	return s.createSubmittedReport(ctx, report, submissionID, nil)
+}
+
+// createSubmittedReport is CreateDraftReport, running with as createReport
+// does
+func (s *ReportService) createSubmittedReport(ctx context.Context, report *models.Report, submissionID string, with func(tx *gorm.DB) error) (editToken string, created bool, err error) {
+	hash := utils.HashToken(submissionID)
+	if found, err := s.loadSubmission(ctx, report, hash); found || err != nil {
+		return "", false, err
+	}
+	report.SubmissionHash = &hash
+	editToken, err = s.createReport(ctx, report, with)
+	if isUniqueViolation(err) {
+		if found, lookupErr := s.loadSubmission(ctx, report, hash); found || lookupErr != nil {
+			return "", false, lookupErr
+		}
+	}
+	return editToken, err == nil, err
+}
+
+// loadSubmission loads the report received with a submission hash into
+// report, if there is one. Submission IDs are scoped to who sent them: the
+// report must come from the same volunteer key as report, or like it from
+// none.
+func (s *ReportService) loadSubmission(ctx context.Context, report *models.Report, hash string) (bool, error) {
+	var existing models.Report
+	err := s.db.WithContext(ctx).Where("submission_hash = ?", hash).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !sameVolunteerKey(existing.VolunteerKeyID, report.VolunteerKeyID) {
+		return false, ErrSubmissionConflict
+	}
+	*report = existing
+	return true, nil
+}
+
+func sameVolunteerKey(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// isUniqueViolation reports whether err is PostgreSQL's unique_violation,
+// from either driver
+func isUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// approvedImages preloads the images the public may see; the others await
+// moderation
+func approvedImages(db *gorm.DB) *gorm.DB {
+	return db.Where("moderation_status = ?", "approved").Order("uploaded_at, id")
+}
+
+// allImages preloads every image, for admin views
+func allImages(db *gorm.DB) *gorm.DB {
+	return db.Order("uploaded_at, id")
 }
 
-// GetReportByID fetches a report by its ID
+// GetReportByID fetches a report by its ID with its approved images
 func (s *ReportService) GetReportByID(ctx context.Context, id int) (*models.Report, error) {
 	var report models.Report
-	err := s.db.WithContext(ctx).Preload("Images").Preload("StatusUpdates").First(&report, id).Error
+	err := s.db.WithContext(ctx).
+		Preload("Images", approvedImages).
+		Preload("StatusUpdates", func(db *gorm.DB) *gorm.DB { return db.Order("updated_at, id") }).
+		First(&report, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
-	return &report, err
+	if err != nil {
+		return nil, err
+	}
+	report.BeforeAfter = report.BeforeAfterPairs()
+	return &report, nil
 }
 
-// ListReports returns all reports (optionally with filters)
-func (s *ReportService) ListReports(ctx context.Context) ([]models.Report, error) {
+// GetReportBySlug fetches a report by its share slug
+func (s *ReportService) GetReportBySlug(ctx context.Context, slug string) (*models.Report, error) {
+	var report models.Report
+	err := s.db.WithContext(ctx).Select("id").Where("share_slug = ?", slug).First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReportByID(ctx, report.ID)
+}
+
+// ListReports returns reports matching the filter, with their approved
+// images unless filter.AllImages is set.
+// Reports merged into another report are left out, as are withdrawn ones
+// unless asked for by status.
+func (s *ReportService) ListReports(ctx context.Context, filter ReportFilter) ([]models.Report, error) {
+	images := approvedImages
+	if filter.AllImages {
+		images = allImages
+	}
+	q := applyReportFilter(s.db.WithContext(ctx).Preload("Images", images).Preload("StatusUpdates"), filter)
 	var reports []models.Report
-	err := s.db.WithContext(ctx).Preload("Images").Preload("StatusUpdates").Find(&reports).Error
+	err := orderReports(q, filter).Find(&reports).Error
 	return reports, err
 }
 
-// UpdateReport updates an existing report
-func (s *ReportService) UpdateReport(ctx context.Context, report *models.Report) error {
-	return s.db.WithContext(ctx).Save(report).Error
+// ReportVersion identifies the state of a report cheaply, for checking a
+// client's cached copy of a listing before loading it
+type ReportVersion struct {
+	ID              int
+	Status          string
+	LastActivityAt  time.Time
+	DescriptionHash string
+}
+
+// ListReportVersions returns the versions of the reports ListReports would
+// return for filter, in the same order, without loading them
+func (s *ReportService) ListReportVersions(ctx context.Context, filter ReportFilter) ([]ReportVersion, error) {
+	q := applyReportFilter(s.db.WithContext(ctx).Model(&models.Report{}), filter).
+		Select("id, status, last_activity_at, md5(COALESCE(description, '')) AS description_hash")
+	var versions []ReportVersion
+	err := orderReports(q, filter).Scan(&versions).Error
+	return versions, err
+}
+
+// orderReports adds the filter's sort order and limit
+func orderReports(q *gorm.DB, filter ReportFilter) *gorm.DB {
+	switch filter.Sort {
+	case "confirmations":
+		q = q.Order("confirmation_count DESC").Order("created_at DESC")
+	case "priority":
+		q = q.Order(priorityExpr + " DESC").Order("created_at DESC")
+	case "activity":
+		q = q.Order("last_activity_at DESC").Order("id DESC")
+	default:
+		q = q.Order("created_at DESC")
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	return q
+}
+
+// applyReportFilter adds the WHERE clauses of a filter; ordering and limits
+// are left to the caller
+func applyReportFilter(q *gorm.DB, filter ReportFilter) *gorm.DB {
+	q = q.Where("merged_into_id IS NULL")
+	if len(filter.IDs) > 0 {
+		q = q.Where("id IN ?", filter.IDs)
+	}
+	if filter.Category != "" {
+		q = q.Where("category = ?", filter.Category)
+	}
+	switch {
+	case len(filter.Statuses) > 0:
+		q = q.Where("status IN ?", filter.Statuses)
+	case filter.Status != "":
+		q = q.Where("status = ?", filter.Status)
+	default:
+		q = q.Where("status <> ?", "withdrawn")
+	}
+	if filter.MinConfirmations > 0 {
+		q = q.Where("confirmation_count >= ?", filter.MinConfirmations)
+	}
+	if filter.State != "" {
+		q = q.Where("state = ?", filter.State)
+	}
+	if filter.City != "" {
+		q = q.Where("city = ?", filter.City)
+	}
+	if b := filter.BBox; b != nil {
+		q = q.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", b.MinLat, b.MaxLat, b.MinLng, b.MaxLng)
+	}
+	if filter.Since != nil {
+		q = q.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		q = q.Where("created_at < ?", *filter.Until)
+	}
+	return q
+}
+
+// ReportChanges are the fields of a report that can be edited after
+// submission. Nil fields are left as they are.
+type ReportChanges struct {
+	Description *string
+	Category    *string
+}
+
+// UpdateReport applies changes to an open report. Only the editable columns
+// are written, so counts, links and tokens cannot be overwritten. The caller
+// must have authorized the reporter or an admin.
+func (s *ReportService) UpdateReport(ctx context.Context, report *models.Report, changes ReportChanges) (*models.Report, error) {
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+	updates := map[string]interface{}{}
+	if changes.Category != nil && *changes.Category != report.Category {
+		exists, err := s.CategoryExists(ctx, *changes.Category)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: invalid category", ErrInvalidReport)
+		}
+		updates["category"] = *changes.Category
+	}
+	var lang *string
+	if changes.Description != nil && *changes.Description != report.Description {
+		if l := utils.DetectLanguage(*changes.Description); l != "" {
+			lang = &l
+		}
+		updates["description"] = *changes.Description
+		updates["description_language"] = lang
+	}
+	if len(updates) == 0 {
+		return report, nil
+	}
+	_, descriptionChanged := updates["description"]
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(report).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Translations of the old description are stale; new ones are made
+		// when readers ask for them
+		if descriptionChanged {
+			return tx.Where("report_id = ?", report.ID).Delete(&models.ReportTranslation{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := updates["category"]; ok {
+		report.Category = *changes.Category
+	}
+	if descriptionChanged {
+		report.Description = *changes.Description
+		report.DescriptionLanguage = lang
+	}
+	return report, nil
 }
 
-// DeleteReport deletes a report by ID
+// DeleteReport deletes a report for good and publishes report.deleted.
+// Duplicates merged into it become standalone reports again, since their
+// redirect target is gone.
 func (s *ReportService) DeleteReport(ctx context.Context, id int) error {
-	return s.db.WithContext(ctx).Delete(&models.Report{}, id).Error
+	var report models.Report
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if err := tx.Model(&models.Report{}).Where("merged_into_id = ?", report.ID).
+			Update("merged_into_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Report{}, report.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.events.Publish(ctx, Event{Type: EventReportDeleted, Report: &report})
+	return nil
+}
+
+// FindDuplicateCandidates returns open reports of the same category within the
+// configured radius and time window, nearest first.
+func (s *ReportService) FindDuplicateCandidates(ctx context.Context, category string, lat, lng float64) ([]DuplicateCandidate, error) {
+	radius := s.cfg.DuplicateRadiusMeters
+	if radius <= 0 {
+		return nil, nil
+	}
+	minLat, minLng, maxLat, maxLng := utils.BoundingBox(lat, lng, radius)
+	var reports []models.Report
+	err := s.db.WithContext(ctx).
+		Where("category = ? AND status IN ? AND merged_into_id IS NULL", category, openStatuses).
+		Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng).
+		Where("created_at >= ?", time.Now().Add(-s.cfg.DuplicateWindow)).
+		Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]DuplicateCandidate, 0, len(reports))
+	for _, r := range reports {
+		d := utils.HaversineMeters(lat, lng, r.Latitude, r.Longitude)
+		if d > radius {
+			continue
+		}
+		candidates = append(candidates, DuplicateCandidate{
+			ID:             r.ID,
+			Category:       r.Category,
+			Latitude:       r.Latitude,
+			Longitude:      r.Longitude,
+			Description:    r.Description,
+			Status:         r.Status,
+			CreatedAt:      r.CreatedAt,
+			DuplicateCount: r.DuplicateCount,
+			DistanceMeters: d,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].DistanceMeters < candidates[j].DistanceMeters
+	})
+	return candidates, nil
+}
+
+// MergeReports folds the duplicate report into the canonical one. Images and
+// timeline entries move to the canonical report, its duplicate count grows by
+// the duplicate plus anything already merged into the duplicate, and the
+// duplicate is left pointing at the canonical report.
+func (s *ReportService) MergeReports(ctx context.Context, duplicateID, canonicalID int, notes string) (*models.Report, error) {
+	if duplicateID == canonicalID {
+		return nil, fmt.Errorf("%w: a report cannot be merged into itself", ErrInvalidMerge)
+	}
+	var canonical, dup models.Report
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+		if err := locked.First(&dup, duplicateID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if err := locked.First(&canonical, canonicalID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if dup.MergedIntoID != nil {
+			return fmt.Errorf("%w: report %d is already merged into %d", ErrInvalidMerge, dup.ID, *dup.MergedIntoID)
+		}
+		if canonical.MergedIntoID != nil {
+			return fmt.Errorf("%w: report %d is itself merged into %d", ErrInvalidMerge, canonical.ID, *canonical.MergedIntoID)
+		}
+
+		if err := tx.Model(&models.Image{}).Where("report_id = ?", dup.ID).
+			Update("report_id", canonical.ID).Error; err != nil {
+			return err
+		}
+		// UpdateColumn keeps updated_at, the time of each status change
+		if err := tx.Model(&models.StatusUpdate{}).Where("report_id = ?", dup.ID).
+			UpdateColumn("report_id", canonical.ID).Error; err != nil {
+			return err
+		}
+		// Resolution claims follow their photos, which moved above, so a
+		// pending claim is reviewed against the canonical report
+		if err := tx.Model(&models.ResolutionClaim{}).Where("report_id = ?", dup.ID).
+			Update("report_id", canonical.ID).Error; err != nil {
+			return err
+		}
+		// Carry over confirmations from people who haven't already
+		// confirmed the canonical report, by IP or device, then recount.
+		if err := tx.Exec(`UPDATE report_confirmations SET report_id = ?
+			WHERE report_id = ? AND fingerprint NOT IN
+				(SELECT fingerprint FROM report_confirmations WHERE report_id = ?)
+			AND (device_fingerprint IS NULL OR device_fingerprint NOT IN
+				(SELECT device_fingerprint FROM report_confirmations WHERE report_id = ? AND device_fingerprint IS NOT NULL))`,
+			canonical.ID, dup.ID, canonical.ID, canonical.ID).Error; err != nil {
+			return err
+		}
+		// Followers of the duplicate follow the canonical report from now
+		// on, except where the same address or push endpoint already does
+		if err := tx.Exec(`DELETE FROM subscriptions d
+			WHERE d.report_id = ? AND EXISTS (SELECT 1 FROM subscriptions c
+				WHERE c.report_id = ? AND c.channel = d.channel
+				AND (lower(c.email) = lower(d.email) OR c.push_endpoint = d.push_endpoint))`,
+			dup.ID, canonical.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Subscription{}).Where("report_id = ?", dup.ID).
+			Update("report_id", canonical.ID).Error; err != nil {
+			return err
+		}
+		var confirmations int64
+		if err := tx.Model(&models.ReportConfirmation{}).Where("report_id = ?", canonical.ID).
+			Count(&confirmations).Error; err != nil {
+			return err
+		}
+		canonical.ConfirmationCount = int(confirmations)
+		// Keep redirects one hop deep: anything merged into the duplicate
+		// now points straight at the canonical report.
+		if err := tx.Model(&models.Report{}).Where("merged_into_id = ?", dup.ID).
+			Update("merged_into_id", canonical.ID).Error; err != nil {
+			return err
+		}
+		// Counted before the update below zeroes dup.DuplicateCount
+		canonical.DuplicateCount += 1 + dup.DuplicateCount
+		if err := tx.Model(&dup).Updates(map[string]interface{}{
+			"merged_into_id":     canonical.ID,
+			"duplicate_count":    0,
+			"confirmation_count": 0,
+		}).Error; err != nil {
+			return err
+		}
+		canonical.LastActivityAt = time.Now()
+		if err := tx.Model(&canonical).Updates(map[string]interface{}{
+			"duplicate_count":    canonical.DuplicateCount,
+			"confirmation_count": canonical.ConfirmationCount,
+			"last_activity_at":   canonical.LastActivityAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		note := fmt.Sprintf("Merged duplicate report #%d", dup.ID)
+		if notes != "" {
+			note += ": " + notes
+		}
+		oldStatus := canonical.Status
+		return tx.Create(&models.StatusUpdate{
+			ReportID:  canonical.ID,
+			OldStatus: &oldStatus,
+			NewStatus: canonical.Status,
+			Notes:     &note,
+			UpdatedAt: canonical.LastActivityAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(ctx, Event{Type: EventReportMerged, Report: &canonical, MergedID: dup.ID})
+	return &canonical, nil
+}
+
+// ConfirmReport records a "me too" from the given IP fingerprint and,
+// optionally, device fingerprint. It is not counted when either has already
+// confirmed the report. Confirmations on a merged report count towards the
+// canonical one. It returns the report's confirmation count and whether this
+// confirmation was newly counted.
+func (s *ReportService) ConfirmReport(ctx context.Context, reportID int, fingerprint, deviceFingerprint string) (int, bool, error) {
+	var count int
+	var created bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var report models.Report
+		if err := tx.First(&report, reportID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if report.MergedIntoID != nil {
+			if err := tx.First(&report, *report.MergedIntoID).Error; err != nil {
+				return err
+			}
+		}
+		if !report.IsOpen() {
+			return ErrReportClosed
+		}
+		seen := tx.Model(&models.ReportConfirmation{}).Where("report_id = ? AND fingerprint = ?", report.ID, fingerprint)
+		confirmation := models.ReportConfirmation{ReportID: report.ID, Fingerprint: fingerprint}
+		if deviceFingerprint != "" {
+			seen = tx.Model(&models.ReportConfirmation{}).
+				Where("report_id = ? AND (fingerprint = ? OR device_fingerprint = ?)", report.ID, fingerprint, deviceFingerprint)
+			confirmation.DeviceFingerprint = &deviceFingerprint
+		}
+		var existing int64
+		if err := seen.Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing == 0 {
+			// A concurrent confirmation from the same IP or device loses on
+			// the unique indexes
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&confirmation)
+			if res.Error != nil {
+				return res.Error
+			}
+			created = res.RowsAffected > 0
+		}
+		if created {
+			if err := tx.Model(&report).
+				Update("confirmation_count", gorm.Expr("confirmation_count + 1")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Report{}).Select("confirmation_count").
+			Where("id = ?", report.ID).Scan(&count).Error
+	})
+	return count, created, err
+}
+
+// changeStatus moves the report to newStatus inside tx, stamps the matching
+// lifecycle timestamp and appends a timeline entry.
+func (s *ReportService) changeStatus(tx *gorm.DB, report *models.Report, newStatus, notes string, updatedBy *int) (*models.StatusUpdate, error) {
+	now := time.Now()
+	report.LastActivityAt = now
+	updates := map[string]interface{}{"status": newStatus, "last_activity_at": now}
+	switch newStatus {
+	case "verified":
+		if report.VerifiedAt == nil {
+			report.VerifiedAt = &now
+			updates["verified_at"] = now
+		}
+	case "in_progress":
+		if report.StartedAt == nil {
+			report.StartedAt = &now
+			updates["started_at"] = now
+		}
+	case "resolved":
+		report.ResolvedAt = &now
+		updates["resolved_at"] = now
+	}
+	if err := tx.Model(report).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	oldStatus := report.Status
+	report.Status = newStatus
+	update := &models.StatusUpdate{
+		ReportID:  report.ID,
+		OldStatus: &oldStatus,
+		NewStatus: newStatus,
+		UpdatedBy: updatedBy,
+		UpdatedAt: now,
+	}
+	if notes != "" {
+		update.Notes = &notes
+	}
+	if err := tx.Create(update).Error; err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// AuthorizeReporter checks the reporter's edit token and returns the report
+func (s *ReportService) AuthorizeReporter(ctx context.Context, id int, editToken string) (*models.Report, error) {
+	var report models.Report
+	if err := s.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+	if editToken == "" || report.EditTokenHash == nil ||
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(editToken)), []byte(*report.EditTokenHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	return &report, nil
+}
+
+// WithdrawReport lets the anonymous reporter take back their own open report.
+// The caller must have authorized the reporter with AuthorizeReporter.
+func (s *ReportService) WithdrawReport(ctx context.Context, report *models.Report, reason string) (*models.Report, error) {
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+	notes := "Withdrawn by reporter"
+	if reason != "" {
+		notes += ": " + reason
+	}
+	var update *models.StatusUpdate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		update, err = s.changeStatus(tx, report, "withdrawn", notes, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(ctx, Event{Type: EventReportStatusChanged, Report: report, StatusUpdate: update})
+	return report, nil
+}
+
+// UpdateStatus is the moderator path for moving a report through its lifecycle
+func (s *ReportService) UpdateStatus(ctx context.Context, id int, status, notes string, updatedBy *int) (*models.Report, *models.StatusUpdate, error) {
+	if !validStatuses[status] {
+		return nil, nil, fmt.Errorf("invalid status %q", status)
+	}
+	var report models.Report
+	var update *models.StatusUpdate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if report.MergedIntoID != nil || report.Status == "withdrawn" {
+			return ErrReportClosed
+		}
+		var err error
+		update, err = s.changeStatus(tx, &report, status, notes, updatedBy)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.events.Publish(ctx, Event{Type: EventReportStatusChanged, Report: &report, StatusUpdate: update})
+	return &report, update, nil
 }
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+const draftSubmissionID = "1b4e28ba-2fa1-4d2e-883f-0016d3cca427"
+
+var submittedReportColumns = []string{"id", "category", "status", "share_slug", "edit_token_hash", "submission_hash", "created_at"}
+
+func submittedReportRow() []driver.Value {
+	return []driver.Value{int64(12), "potholes", "pending", "k3Jd9x", "original-token-hash", utils.HashToken(draftSubmissionID), time.Now()}
+}
+
+// sqlStateError stands in for the drivers' errors, which carry an SQLSTATE
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestCreateDraftReportReplayKeepsEditToken(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", submittedReportColumns, submittedReportRow())
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	editToken, created, err := s.CreateDraftReport(context.Background(), &report, draftSubmissionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || editToken != "" {
+		t.Errorf("replay returned created=%v, edit token %q; want the existing report and no token", created, editToken)
+	}
+	if report.ID != 12 || report.ShareSlug != "k3Jd9x" {
+		t.Errorf("report = %d %q, want the existing report 12", report.ID, report.ShareSlug)
+	}
+	if n := len(fake.Executed(`UPDATE "reports"`)); n != 0 {
+		t.Errorf("replay updated the report %d times", n)
+	}
+	if n := len(fake.Executed(`INSERT INTO "reports"`)); n != 0 {
+		t.Errorf("replay inserted %d reports", n)
+	}
+}
+
+func TestCreateDraftReportRaceIsReplay(t *testing.T) {
+	db, fake := fakedb.New(t)
+	// Not there when first looked up, there once the insert has lost
+	fake.SetRows("reports", submittedReportColumns)
+	fake.SetRows("reports", submittedReportColumns, submittedReportRow())
+	fake.Fail(`INSERT INTO "reports"`, fmt.Errorf("insert report: %w", sqlStateError("23505")))
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	editToken, created, err := s.CreateDraftReport(context.Background(), &report, draftSubmissionID)
+	if err != nil {
+		t.Fatalf("CreateDraftReport: %v, want the race answered as a replay", err)
+	}
+	if created || editToken != "" || report.ID != 12 {
+		t.Errorf("got created=%v, token %q, report %d; want report 12 as a replay", created, editToken, report.ID)
+	}
+}
+
+func TestCreateDraftReportOtherErrors(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", submittedReportColumns)
+	fake.Fail(`INSERT INTO "reports"`, sqlStateError("23503"))
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	if _, created, err := s.CreateDraftReport(context.Background(), &report, draftSubmissionID); err == nil || created {
+		t.Errorf("got created=%v, err %v; want the foreign key violation", created, err)
+	}
+}
+
+func TestPublicReportsOnlyLoadApprovedImages(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"id", "status", "created_at"}, []driver.Value{int64(12), "pending", time.Now()})
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+	ctx := context.Background()
+
+	if _, err := s.GetReportByID(ctx, 12); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ListReports(ctx, ReportFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	queries := fake.Queried(`SELECT * FROM "images"`)
+	if len(queries) != 2 {
+		t.Fatalf("%d image queries, want 2", len(queries))
+	}
+	for _, q := range queries {
+		if !strings.Contains(q.Query, "moderation_status = $2") || q.Args[1] != "approved" {
+			t.Errorf("public image query is not limited to approved images: %s %v", q.Query, q.Args)
+		}
+	}
+
+	if _, err := s.ListReports(ctx, ReportFilter{AllImages: true}); err != nil {
+		t.Fatal(err)
+	}
+	queries = fake.Queried(`SELECT * FROM "images"`)
+	if last := queries[len(queries)-1]; strings.Contains(last.Query, "moderation_status") {
+		t.Errorf("admin image query filters on moderation: %s", last.Query)
+	}
+}
+
+func TestDeleteReportUnlinksDuplicatesFirst(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"id", "status", "latitude", "longitude", "created_at"},
+		[]driver.Value{int64(12), "verified", 26.9, 75.8, time.Now()})
+	events := NewEventBus()
+	var published []Event
+	events.Subscribe(func(_ context.Context, e Event) { published = append(published, e) })
+	s := NewReportService(db, &config.Config{}, events)
+
+	if err := s.DeleteReport(context.Background(), 12); err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.Executed("")
+	unlinked, deleted := -1, -1
+	for i, e := range execs {
+		switch {
+		case strings.HasPrefix(e.Query, `UPDATE "reports" SET "merged_into_id"=$1`):
+			unlinked = i
+		case strings.HasPrefix(e.Query, `DELETE FROM "reports"`):
+			deleted = i
+		}
+	}
+	if unlinked < 0 || deleted < 0 || unlinked > deleted {
+		t.Fatalf("merged duplicates must be unlinked before the delete; ran %v", execs)
+	}
+	if len(published) != 1 || published[0].Type != EventReportDeleted || published[0].Report.ID != 12 {
+		t.Errorf("published %+v, want one report.deleted for report 12", published)
+	}
+}
+
+func TestDeleteReportNotFound(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"id"})
+	events := NewEventBus()
+	events.Subscribe(func(_ context.Context, e Event) { t.Errorf("published %s for a missing report", e.Type) })
+	s := NewReportService(db, &config.Config{}, events)
+
+	if err := s.DeleteReport(context.Background(), 12); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("err = %v, want ErrReportNotFound", err)
+	}
+	if n := len(fake.Executed(`DELETE FROM "reports"`)); n != 0 {
+		t.Errorf("deleted %d reports", n)
+	}
+}
+
+func TestFindDuplicateCandidatesNearestFirstWithinRadius(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"id", "category", "latitude", "longitude", "status", "created_at"},
+		[]driver.Value{int64(1), "potholes", 26.90027, 75.8, "pending", time.Now()},     // 30 m north
+		[]driver.Value{int64(2), "potholes", 26.9, 75.80009, "verified", time.Now()},    // 9 m east
+		[]driver.Value{int64(3), "potholes", 26.90072, 75.80081, "pending", time.Now()}, // in the box, 113 m away
+	)
+	s := NewReportService(db, &config.Config{DuplicateRadiusMeters: 100, DuplicateWindow: 72 * time.Hour}, NewEventBus())
+
+	candidates, err := s.FindDuplicateCandidates(context.Background(), "potholes", 26.9, 75.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 || candidates[0].ID != 2 || candidates[1].ID != 1 {
+		t.Fatalf("candidates = %+v, want reports 2 and 1, nearest first", candidates)
+	}
+	if d := candidates[1].DistanceMeters; d < 29 || d > 31 {
+		t.Errorf("report 1 is %.1f m away, want about 30", d)
+	}
+	q := fake.Queried(`SELECT * FROM "reports"`)[0]
+	for _, want := range []string{"category = $1", "status IN ($2,$3,$4)", "merged_into_id IS NULL", "created_at >="} {
+		if !strings.Contains(q.Query, want) {
+			t.Errorf("candidate query lacks %q: %s", want, q.Query)
+		}
+	}
+}
+
+func TestFindDuplicateCandidatesDisabled(t *testing.T) {
+	db, fake := fakedb.New(t)
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	candidates, err := s.FindDuplicateCandidates(context.Background(), "potholes", 26.9, 75.8)
+	if err != nil || candidates != nil {
+		t.Errorf("got %v, %v; want no candidates", candidates, err)
+	}
+	if n := len(fake.Queried("")); n != 0 {
+		t.Errorf("ran %d queries with detection off", n)
+	}
+}
+
+func TestMergeReports(t *testing.T) {
+	db, fake := fakedb.New(t)
+	columns := []string{"id", "status", "duplicate_count", "created_at"}
+	fake.SetRows("reports", columns, []driver.Value{int64(13), "pending", int64(2), time.Now()})
+	fake.SetRows("reports", columns, []driver.Value{int64(12), "verified", int64(1), time.Now()})
+	fake.SetRows("report_confirmations", []string{"count"}, []driver.Value{int64(4)})
+	events := NewEventBus()
+	var published []Event
+	events.Subscribe(func(_ context.Context, e Event) { published = append(published, e) })
+	s := NewReportService(db, &config.Config{}, events)
+
+	canonical, err := s.MergeReports(context.Background(), 13, 12, "same pothole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The duplicate and the two reports already merged into it
+	if canonical.DuplicateCount != 4 || canonical.ConfirmationCount != 4 {
+		t.Errorf("duplicate_count %d, confirmation_count %d; want 4 and 4", canonical.DuplicateCount, canonical.ConfirmationCount)
+	}
+	for _, table := range []string{"images", "status_updates", "resolution_claims", "subscriptions"} {
+		moved := fake.Executed(`UPDATE "` + table + `" SET "report_id"=$1 WHERE report_id = $2`)
+		if len(moved) != 1 || moved[0].Args[0] != int64(12) || moved[0].Args[1] != int64(13) {
+			t.Errorf("%s not moved from 13 to 12: %v", table, moved)
+		}
+	}
+	repointed := fake.Executed(`UPDATE "reports" SET "merged_into_id"=$1 WHERE merged_into_id = $2`)
+	if len(repointed) != 1 || repointed[0].Args[0] != int64(12) || repointed[0].Args[1] != int64(13) {
+		t.Errorf("reports merged into 13 not re-pointed at 12: %v", repointed)
+	}
+	if len(published) != 1 || published[0].Type != EventReportMerged || published[0].MergedID != 13 {
+		t.Errorf("published %+v, want report.merged of 13", published)
+	}
+}
+
+func TestMergeReportsInvalid(t *testing.T) {
+	for name, mergedInto := range map[string][2]driver.Value{
+		"duplicate already merged": {int64(20), nil},
+		"canonical is merged":      {nil, int64(20)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, fake := fakedb.New(t)
+			columns := []string{"id", "status", "merged_into_id", "created_at"}
+			fake.SetRows("reports", columns, []driver.Value{int64(13), "pending", mergedInto[0], time.Now()})
+			fake.SetRows("reports", columns, []driver.Value{int64(12), "verified", mergedInto[1], time.Now()})
+			s := NewReportService(db, &config.Config{}, NewEventBus())
+
+			if _, err := s.MergeReports(context.Background(), 13, 12, ""); !errors.Is(err, ErrInvalidMerge) {
+				t.Errorf("err = %v, want ErrInvalidMerge", err)
+			}
+			if n := len(fake.Executed("UPDATE")); n != 0 {
+				t.Errorf("ran %d updates", n)
+			}
+		})
+	}
+
+	db, _ := fakedb.New(t)
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+	if _, err := s.MergeReports(context.Background(), 12, 12, ""); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("merging a report into itself: err = %v, want ErrInvalidMerge", err)
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// reportTranslationTimeout bounds the background translation of a
+	// report into one or all target languages
+	reportTranslationTimeout = time.Minute
+	// reportTranslationWorkers caps the translations requested by readers
+	// that run at once; requests beyond it are dropped and made again by a
+	// later reader
+	reportTranslationWorkers = 4
+	// reportTranslationRetry is how long a failed translation is not
+	// attempted again
+	reportTranslationRetry = time.Hour
+)
+
+// ReportTranslationService keeps machine translations of report
+// descriptions. New reports are translated into the target languages in the
+// background; any other language is translated in the background on first
+// request. Readers are only ever served stored translations, and without a
+// translator nothing new is stored.
+type ReportTranslationService struct {
+	db         *gorm.DB
+	translator Translator
+	targets    []string
+
+	slots chan struct{}
+	// running tracks background translations, for tests
+	running sync.WaitGroup
+
+	mu sync.Mutex
+	// inFlight holds the translations being made, failed the ones not to
+	// retry before the time stored
+	inFlight map[reportTranslationKey]bool
+	failed   map[reportTranslationKey]time.Time
+}
+
+// reportTranslationKey identifies one translation of one version of a
+// description, so an edited description is translated afresh
+type reportTranslationKey struct {
+	reportID int
+	locale   string
+	text     uint64
+}
+
+func newReportTranslationKey(reportID int, text, locale string) reportTranslationKey {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return reportTranslationKey{reportID: reportID, locale: locale, text: h.Sum64()}
+}
+
+func NewReportTranslationService(db *gorm.DB, translator Translator, targets []string) *ReportTranslationService {
+	return &ReportTranslationService{
+		db:         db,
+		translator: translator,
+		targets:    targets,
+		slots:      make(chan struct{}, reportTranslationWorkers),
+		inFlight:   map[reportTranslationKey]bool{},
+		failed:     map[reportTranslationKey]time.Time{},
+	}
+}
+
+// HandleEvent translates new reports into the target languages. The work
+// runs in the background so submitting a report does not wait on the
+// translator.
+func (s *ReportTranslationService) HandleEvent(ctx context.Context, e Event) {
+	if e.Type != EventReportCreated || s.translator == nil || e.Report.Description == "" || e.Report.DescriptionLanguage == nil {
+		return
+	}
+	id, text, from := e.Report.ID, e.Report.Description, *e.Report.DescriptionLanguage
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), reportTranslationTimeout)
+		defer cancel()
+		for _, to := range s.targets {
+			if to == from {
+				continue
+			}
+			s.attempt(ctx, newReportTranslationKey(id, text, to), text, from)
+		}
+	}()
+}
+
+// Localize returns the stored translation of the report's description into
+// locale. When there is none yet it starts one in the background and
+// returns nil, so readers never wait on the translator; nil is also
+// returned when the description is empty, already in locale or of unknown
+// language.
+func (s *ReportTranslationService) Localize(ctx context.Context, report *models.Report, locale string) (*models.ReportTranslation, error) {
+	if report.Description == "" || report.DescriptionLanguage == nil || *report.DescriptionLanguage == locale {
+		return nil, nil
+	}
+	var stored models.ReportTranslation
+	err := s.db.WithContext(ctx).Where("report_id = ? AND locale = ?", report.ID, locale).First(&stored).Error
+	if err == nil {
+		return &stored, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if s.translator != nil {
+		s.request(newReportTranslationKey(report.ID, report.Description, locale), report.Description, *report.DescriptionLanguage)
+	}
+	return nil, nil
+}
+
+// request starts a background translation unless the same one is running,
+// failed recently or all workers are busy
+func (s *ReportTranslationService) request(key reportTranslationKey, text, from string) {
+	s.mu.Lock()
+	if retryAt, ok := s.failed[key]; s.inFlight[key] || ok && time.Now().Before(retryAt) {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer func() { <-s.slots }()
+		ctx, cancel := context.WithTimeout(context.Background(), reportTranslationTimeout)
+		defer cancel()
+		s.attempt(ctx, key, text, from)
+	}()
+}
+
+// attempt translates and stores one translation, remembering a failure so
+// it is not retried for reportTranslationRetry
+func (s *ReportTranslationService) attempt(ctx context.Context, key reportTranslationKey, text, from string) {
+	s.mu.Lock()
+	if s.inFlight[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.inFlight[key] = true
+	s.mu.Unlock()
+
+	err := s.translate(ctx, key.reportID, text, from, key.locale)
+	if err != nil && !errors.Is(err, ErrUnsupportedLanguagePair) {
+		utils.Error("failed to translate report %d into %s: %v", key.reportID, key.locale, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, key)
+	if err == nil {
+		delete(s.failed, key)
+		return
+	}
+	now := time.Now()
+	for k, retryAt := range s.failed {
+		if now.After(retryAt) {
+			delete(s.failed, k)
+		}
+	}
+	s.failed[key] = now.Add(reportTranslationRetry)
+}
+
+// translate stores the translation of text, as long as it is still the
+// report's description. The report row is share-locked, so an edit either
+// commits first and the stale translation is not stored, or waits and then
+// deletes it.
+func (s *ReportTranslationService) translate(ctx context.Context, reportID int, text, from, to string) error {
+	translated, err := s.translator.Translate(ctx, text, from, to)
+	if err != nil {
+		return err
+	}
+	// A concurrent request may have stored one first; either will do
+	return s.db.WithContext(ctx).Exec(`INSERT INTO report_translations (report_id, locale, description, engine, created_at)
+		SELECT ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM reports WHERE id = ? AND description = ? FOR SHARE)
+		ON CONFLICT (report_id, locale) DO NOTHING`,
+		reportID, to, translated, s.translator.Name(), time.Now(), reportID, text).Error
+}
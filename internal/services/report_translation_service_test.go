@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+const insertReportTranslation = "INSERT INTO report_translations"
+
+func hindiReport() *models.Report {
+	lang := "hi"
+	return &models.Report{ID: 7, Status: "pending", Description: "बहुत बड़ा गड्ढा", DescriptionLanguage: &lang}
+}
+
+func glossaryTranslator() *DictionaryTranslator {
+	return NewDictionaryTranslator(map[string]map[string]string{
+		"hi-en": {"बहुत": "very", "बड़ा": "big", "गड्ढा": "pothole"},
+	})
+}
+
+// countingTranslator counts calls and fails them with err when it is set
+type countingTranslator struct {
+	Translator
+	calls atomic.Int32
+	err   error
+}
+
+func (t *countingTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	t.calls.Add(1)
+	if t.err != nil {
+		return "", t.err
+	}
+	return t.Translator.Translate(ctx, text, from, to)
+}
+
+func TestLocalizeServesStoredTranslation(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("report_translations", []string{"id", "report_id", "locale", "description", "engine", "created_at"},
+		[]driver.Value{int64(1), int64(7), "en", "very big pothole", "dictionary", time.Now()})
+	translator := &countingTranslator{Translator: glossaryTranslator()}
+	s := NewReportTranslationService(db, translator, nil)
+
+	got, err := s.Localize(context.Background(), hindiReport(), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Description != "very big pothole" {
+		t.Fatalf("Localize = %+v, want the stored translation", got)
+	}
+	s.running.Wait()
+	if n := translator.calls.Load(); n != 0 {
+		t.Errorf("translator called %d times for a stored translation", n)
+	}
+}
+
+func TestLocalizeTranslatesInBackground(t *testing.T) {
+	db, fake := fakedb.New(t)
+	s := NewReportTranslationService(db, glossaryTranslator(), nil)
+
+	got, err := s.Localize(context.Background(), hindiReport(), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Fatalf("Localize = %+v, want nil until the translation is stored", got)
+	}
+	s.running.Wait()
+	inserts := fake.Executed(insertReportTranslation)
+	if len(inserts) != 1 {
+		t.Fatalf("%d translations stored, want 1", len(inserts))
+	}
+	args := inserts[0].Args
+	if args[0] != int64(7) || args[1] != "en" || args[2] != "very big pothole" || args[3] != "dictionary" {
+		t.Errorf("stored %v", args[:4])
+	}
+	// Only stored if the description has not been edited meanwhile
+	if args[6] != "बहुत बड़ा गड्ढा" {
+		t.Errorf("stored translation is not tied to the description it translates: %v", args[6])
+	}
+}
+
+func TestLocalizeDoesNotRetryFailedTranslation(t *testing.T) {
+	db, fake := fakedb.New(t)
+	translator := &countingTranslator{Translator: glossaryTranslator(), err: errors.New("translator unavailable")}
+	s := NewReportTranslationService(db, translator, nil)
+	report := hindiReport()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Localize(context.Background(), report, "en"); err != nil {
+			t.Fatal(err)
+		}
+		s.running.Wait()
+	}
+	if n := translator.calls.Load(); n != 1 {
+		t.Errorf("translator called %d times, want 1", n)
+	}
+	if n := len(fake.Executed(insertReportTranslation)); n != 0 {
+		t.Errorf("%d failed translations stored", n)
+	}
+
+	// An edited description is a different translation
+	report.Description = "गड्ढा"
+	if _, err := s.Localize(context.Background(), report, "en"); err != nil {
+		t.Fatal(err)
+	}
+	s.running.Wait()
+	if n := translator.calls.Load(); n != 2 {
+		t.Errorf("translator called %d times after the edit, want 2", n)
+	}
+}
+
+func TestLocalizeUnsupportedPairIsNotRetried(t *testing.T) {
+	db, _ := fakedb.New(t)
+	translator := &countingTranslator{Translator: glossaryTranslator()}
+	s := NewReportTranslationService(db, translator, nil)
+
+	for i := 0; i < 2; i++ {
+		got, err := s.Localize(context.Background(), hindiReport(), "ta")
+		if err != nil || got != nil {
+			t.Fatalf("Localize = %+v, %v; want nil, nil", got, err)
+		}
+		s.running.Wait()
+	}
+	if n := translator.calls.Load(); n != 1 {
+		t.Errorf("translator called %d times, want 1", n)
+	}
+}
+
+func TestLocalizeWithoutTranslator(t *testing.T) {
+	db, fake := fakedb.New(t)
+	s := NewReportTranslationService(db, nil, nil)
+	got, err := s.Localize(context.Background(), hindiReport(), "en")
+	if err != nil || got != nil {
+		t.Fatalf("Localize = %+v, %v; want nil, nil", got, err)
+	}
+	s.running.Wait()
+	if n := len(fake.Executed(insertReportTranslation)); n != 0 {
+		t.Errorf("%d translations stored without a translator", n)
+	}
+}
+
+func TestUpdateReportDropsTranslations(t *testing.T) {
+	db, fake := fakedb.New(t)
+	s := NewReportService(db, nil, nil)
+	description := "पुल टूटा है"
+	report, err := s.UpdateReport(context.Background(), hindiReport(), ReportChanges{Description: &description})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Description != description || report.DescriptionLanguage == nil || *report.DescriptionLanguage != "hi" {
+		t.Errorf("report = %q (%v)", report.Description, report.DescriptionLanguage)
+	}
+	deletes := fake.Executed(`DELETE FROM "report_translations"`)
+	if len(deletes) != 1 || deletes[0].Args[0] != int64(7) {
+		t.Errorf("translations dropped: %+v, want those of report 7", deletes)
+	}
+}
+
+func TestUpdateReportCategoryKeepsTranslations(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("categories", []string{"count"}, []driver.Value{int64(1)})
+	s := NewReportService(db, nil, nil)
+	category := "roads"
+	if _, err := s.UpdateReport(context.Background(), hindiReport(), ReportChanges{Category: &category}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Executed(`UPDATE "reports"`)); n != 1 {
+		t.Errorf("%d report updates, want 1", n)
+	}
+	if n := len(fake.Executed(`DELETE FROM "report_translations"`)); n != 0 {
+		t.Errorf("translations dropped when only the category changed")
+	}
+}
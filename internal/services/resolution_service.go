@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrClaimNotFound = errors.New("resolution claim not found")
+	ErrClaimReviewed = errors.New("resolution claim already reviewed")
+)
+
+// ResolutionService handles citizen-submitted resolution claims
+// (feature 9: public resolution reporting).
+type ResolutionService struct {
+	db      *gorm.DB
+	reports *ReportService
+	images  *ImageService
+}
+
+func NewResolutionService(db *gorm.DB, reports *ReportService, images *ImageService) *ResolutionService {
+	return &ResolutionService{db: db, reports: reports, images: images}
+}
+
+// CreateClaim records a claim that the report has been fixed, with its
+// resolution photos. The photos stay pending until a moderator reviews the claim.
+func (s *ResolutionService) CreateClaim(ctx context.Context, reportID int, note, claimantIP string, images []UploadedImage) (*models.ResolutionClaim, error) {
+	claim := &models.ResolutionClaim{
+		ReportID:   reportID,
+		Note:       note,
+		Status:     "pending",
+		ClaimantIP: claimantIP,
+	}
+	var saved []models.Image
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var report models.Report
+		if err := tx.First(&report, reportID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReportNotFound
+			}
+			return err
+		}
+		if !report.IsOpen() {
+			return ErrReportClosed
+		}
+		if err := tx.Create(claim).Error; err != nil {
+			return err
+		}
+		var err error
+		saved, err = s.images.StoreImages(ctx, tx, report.ID, &claim.ID, models.ImageTypeResolution, images)
+		if err != nil {
+			return err
+		}
+		claim.Images = saved
+		return nil
+	})
+	if err != nil {
+		s.images.RemoveImages(ctx, saved)
+		return nil, err
+	}
+	return claim, nil
+}
+
+// ListClaims returns claims with the given status (all when empty), oldest first
+func (s *ResolutionService) ListClaims(ctx context.Context, status string) ([]models.ResolutionClaim, error) {
+	q := s.db.WithContext(ctx).Preload("Images").Order("created_at, id")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var claims []models.ResolutionClaim
+	err := q.Find(&claims).Error
+	return claims, err
+}
+
+// ReviewClaim verifies or rejects a pending claim. Verifying approves the
+// claim's photos and moves the report to resolved; rejecting rejects the photos.
+func (s *ResolutionService) ReviewClaim(ctx context.Context, claimID int, status, notes string, reviewedBy *int) (*models.ResolutionClaim, error) {
+	if status != "verified" && status != "rejected" {
+		return nil, fmt.Errorf("invalid review status %q", status)
+	}
+	var claim models.ResolutionClaim
+	var report models.Report
+	var update *models.StatusUpdate
+	var claimImages []models.Image
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&claim, claimID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClaimNotFound
+			}
+			return err
+		}
+		if claim.Status != "pending" {
+			return ErrClaimReviewed
+		}
+		if status == "verified" {
+			// A report that was resolved, rejected or withdrawn in the
+			// meantime cannot be resolved by this claim; reject it instead
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, claim.ReportID).Error; err != nil {
+				return err
+			}
+			if !report.IsOpen() {
+				return ErrReportClosed
+			}
+		}
+		now := time.Now()
+		claim.Status = status
+		claim.ReviewedAt = &now
+		claim.ReviewedBy = reviewedBy
+		if notes != "" {
+			claim.ReviewNotes = &notes
+		}
+		if err := tx.Model(&claim).Updates(map[string]interface{}{
+			"status":       claim.Status,
+			"reviewed_at":  claim.ReviewedAt,
+			"reviewed_by":  claim.ReviewedBy,
+			"review_notes": claim.ReviewNotes,
+		}).Error; err != nil {
+			return err
+		}
+
+		imageStatus := "rejected"
+		if status == "verified" {
+			imageStatus = "approved"
+		}
+		if err := tx.Model(&models.Image{}).Where("resolution_claim_id = ?", claim.ID).
+			Updates(map[string]interface{}{
+				"moderation_status": imageStatus,
+				"moderated_at":      now,
+				"moderated_by":      reviewedBy,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("resolution_claim_id = ?", claim.ID).Find(&claimImages).Error; err != nil {
+			return err
+		}
+		if status != "verified" {
+			return nil
+		}
+
+		resolverNotes := fmt.Sprintf("Resolution reported by the public (claim #%d)", claim.ID)
+		if claim.Note != "" {
+			resolverNotes += ": " + claim.Note
+		}
+		if err := tx.Model(&report).Update("resolver_notes", resolverNotes).Error; err != nil {
+			return err
+		}
+		var err error
+		update, err = s.reports.changeStatus(tx, &report, "resolved", resolverNotes, reviewedBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if update != nil {
+		s.reports.events.Publish(ctx, Event{Type: EventReportStatusChanged, Report: &report, StatusUpdate: update})
+	}
+	for i := range claimImages {
+		s.images.publishModeration(ctx, &claimImages[i])
+	}
+	return &claim, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+)
+
+func newTestResolutionService(t *testing.T) (*ResolutionService, *fakedb.DB) {
+	t.Helper()
+	db, fake := fakedb.New(t)
+	events := NewEventBus()
+	reports := NewReportService(db, &config.Config{}, events)
+	images := NewImageService(db, &memoryImageStore{files: map[string][]byte{}}, events)
+	return NewResolutionService(db, reports, images), fake
+}
+
+func TestVerifyClaimOnClosedReport(t *testing.T) {
+	for _, status := range []string{"resolved", "rejected", "withdrawn"} {
+		t.Run(status, func(t *testing.T) {
+			s, fake := newTestResolutionService(t)
+			fake.SetRows("resolution_claims", []string{"id", "report_id", "status"}, []driver.Value{int64(5), int64(12), "pending"})
+			fake.SetRows("reports", []string{"id", "status", "created_at"}, []driver.Value{int64(12), status, time.Now()})
+
+			if _, err := s.ReviewClaim(context.Background(), 5, "verified", "", nil); !errors.Is(err, ErrReportClosed) {
+				t.Fatalf("err = %v, want ErrReportClosed", err)
+			}
+			for _, prefix := range []string{`UPDATE "resolution_claims"`, `UPDATE "images"`, `UPDATE "reports"`, `INSERT INTO "status_updates"`} {
+				if n := len(fake.Executed(prefix)); n != 0 {
+					t.Errorf("ran %d %s statements", n, prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestRejectClaimOnClosedReport(t *testing.T) {
+	s, fake := newTestResolutionService(t)
+	fake.SetRows("resolution_claims", []string{"id", "report_id", "status"}, []driver.Value{int64(5), int64(12), "pending"})
+	fake.SetRows("reports", []string{"id", "status", "created_at"}, []driver.Value{int64(12), "resolved", time.Now()})
+
+	claim, err := s.ReviewClaim(context.Background(), 5, "rejected", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.Status != "rejected" || len(fake.Executed(`UPDATE "resolution_claims"`)) != 1 {
+		t.Errorf("claim status %q; want it rejected", claim.Status)
+	}
+}
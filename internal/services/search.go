@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// MaxSearchQueryLength bounds the q parameter of report searches
+const MaxSearchQueryLength = 200
+
+// searchQuery matches the 'english' and 'simple' vectors of migration
+// 021_report_search.sql; see there for why both are used
+const searchQuery = "(websearch_to_tsquery('english', @q) || websearch_to_tsquery('simple', @q))"
+
+// ts_headline copies the document verbatim, so matches are marked with
+// private-use characters and the snippet is HTML-escaped before they are
+// turned into <mark> tags
+const (
+	snippetStart   = "\ue000"
+	snippetStop    = "\ue001"
+	headlineOption = `StartSel="` + snippetStart + `", StopSel="` + snippetStop + `", ` +
+		`MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// SearchResult is a report matching a search, best matches first. Snippets
+// are HTML with matched words wrapped in <mark>.
+type SearchResult struct {
+	models.Report
+	Rank              float64 `json:"rank"`
+	Snippet           string  `json:"snippet"`
+	AdminNotesSnippet string  `json:"admin_notes_snippet,omitempty"`
+}
+
+// SearchReports runs a full-text search over report descriptions, cities
+// and categories, narrowed by filter. The query uses web search syntax
+// ("quoted phrases", -excluded, or). Admin searches also match admin notes
+// and return them; public results never include admin notes.
+func (s *ReportService) SearchReports(ctx context.Context, query string, filter ReportFilter, admin bool) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if len(query) > MaxSearchQueryLength {
+		return nil, fmt.Errorf("%w: q is too long", ErrInvalidSearch)
+	}
+
+	vector := "search_vector"
+	if admin {
+		vector = "admin_search_vector"
+	}
+	args := map[string]interface{}{"q": query, "opts": headlineOption}
+	sel := "id, ts_rank_cd(" + vector + ", " + searchQuery + ") AS rank, " +
+		"ts_headline('english', COALESCE(description, ''), " + searchQuery + ", @opts) AS snippet"
+	if admin {
+		sel += ", CASE WHEN admin_notes IS NOT NULL THEN ts_headline('english', admin_notes, " + searchQuery + ", @opts) END AS notes_snippet"
+	}
+
+	var hits []struct {
+		ID           int
+		Rank         float64
+		Snippet      string
+		NotesSnippet *string
+	}
+	q := applyReportFilter(s.db.WithContext(ctx).Model(&models.Report{}), filter).
+		Select(sel, args).
+		Where(vector+" @@ "+searchQuery, map[string]interface{}{"q": query}).
+		Order("rank DESC").Order("created_at DESC")
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	if err := q.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	reports, err := s.ListReports(ctx, ReportFilter{IDs: ids, Status: filter.Status, Statuses: filter.Statuses, AllImages: admin})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Report, len(reports))
+	for _, r := range reports {
+		byID[r.ID] = r
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, h := range hits {
+		report, ok := byID[h.ID]
+		if !ok {
+			continue
+		}
+		result := SearchResult{Report: report, Rank: h.Rank, Snippet: markSnippet(h.Snippet)}
+		if admin {
+			if h.NotesSnippet != nil && strings.Contains(*h.NotesSnippet, snippetStart) {
+				result.AdminNotesSnippet = markSnippet(*h.NotesSnippet)
+			}
+		} else {
+			result.AdminNotes = nil
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// markSnippet escapes a ts_headline result and turns the match markers
+// into <mark> tags
+func markSnippet(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>").Replace(s)
+}
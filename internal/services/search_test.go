@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+)
+
+func TestMarkSnippet(t *testing.T) {
+	for in, want := range map[string]string{
+		"Deep " + snippetStart + "pothole" + snippetStop + " near the school": "Deep <mark>pothole</mark> near the school",
+		"<script>alert(1)</script> " + snippetStart + "pothole" + snippetStop: "&lt;script&gt;alert(1)&lt;/script&gt; <mark>pothole</mark>",
+		`<img src=x onerror="alert(1)">`:                                      `&lt;img src=x onerror=&#34;alert(1)&#34;&gt;`,
+		"Tom & Jerry's <mark>lane</mark>":                                     "Tom &amp; Jerry&#39;s &lt;mark&gt;lane&lt;/mark&gt;",
+		"no match at all":                                                     "no match at all",
+	} {
+		if got := markSnippet(in); got != want {
+			t.Errorf("markSnippet(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func searchRows(fake *fakedb.DB) {
+	fake.SetRows("reports", []string{"id", "rank", "snippet", "notes_snippet"},
+		[]driver.Value{int64(12), 0.5, `<b>Deep</b> ` + snippetStart + "pothole" + snippetStop, "Call " + snippetStart + "<ward office>" + snippetStop},
+		[]driver.Value{int64(13), 0.2, snippetStart + "pothole" + snippetStop + " again", "No match in the notes"},
+	)
+	fake.SetRows("reports", []string{"id", "category", "description", "status", "admin_notes", "created_at"},
+		[]driver.Value{int64(12), "potholes", "<b>Deep</b> pothole", "verified", "Call <ward office>", time.Now()},
+		[]driver.Value{int64(13), "potholes", "pothole again", "verified", "No match in the notes", time.Now()},
+	)
+}
+
+func TestSearchSnippetsAreEscaped(t *testing.T) {
+	db, fake := fakedb.New(t)
+	searchRows(fake)
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	results, err := s.SearchReports(context.Background(), "pothole", ReportFilter{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	if got := results[0].Snippet; got != "&lt;b&gt;Deep&lt;/b&gt; <mark>pothole</mark>" {
+		t.Errorf("snippet %q", got)
+	}
+	for _, r := range results {
+		if r.AdminNotes != nil || r.AdminNotesSnippet != "" {
+			t.Errorf("public result %d carries admin notes", r.ID)
+		}
+	}
+}
+
+func TestAdminSearchNotesSnippets(t *testing.T) {
+	db, fake := fakedb.New(t)
+	searchRows(fake)
+	s := NewReportService(db, &config.Config{}, NewEventBus())
+
+	results, err := s.SearchReports(context.Background(), "pothole", ReportFilter{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	if got := results[0].AdminNotesSnippet; got != "Call <mark>&lt;ward office&gt;</mark>" {
+		t.Errorf("notes snippet %q", got)
+	}
+	// Notes without a match get no snippet
+	if got := results[1].AdminNotesSnippet; got != "" {
+		t.Errorf("unmatched notes snippet %q", got)
+	}
+}
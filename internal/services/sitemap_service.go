@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// SitemapChunkSize is the number of report IDs per sitemap file. Chunks
+	// are ID ranges, so a report never moves between files; the protocol
+	// allows up to 50,000 URLs per file.
+	SitemapChunkSize = 10000
+	// sitemapFullRebuild bounds staleness from changes that publish no event
+	sitemapFullRebuild = 24 * time.Hour
+)
+
+// SitemapStatuses are the statuses of reports listed for search engines, and
+// in the feeds unless a status is asked for. Pending reports are held for
+// moderation; rejected and withdrawn ones are left out too, as are merged
+// duplicates.
+var SitemapStatuses = []string{"verified", "in_progress", "resolved"}
+
+// SitemapListed reports whether reports with this status are in the sitemap.
+// Pages of other reports ask search engines not to index them.
+func SitemapListed(status string) bool {
+	for _, s := range SitemapStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// SitemapDocument is a rendered sitemap or sitemap index
+type SitemapDocument struct {
+	XML     []byte
+	LastMod time.Time
+	ETag    string
+}
+
+// SitemapService keeps the sitemap of report pages: an index at
+// /sitemap.xml pointing at one file per chunk of report IDs. Everything is
+// rendered up front and held in memory. Report events mark their chunk
+// stale, and Run re-renders only the stale chunks.
+type SitemapService struct {
+	db      *gorm.DB
+	baseURL string
+
+	// build serializes rebuilds
+	build sync.Mutex
+
+	mu        sync.RWMutex
+	chunks    map[int]*SitemapDocument
+	index     *SitemapDocument
+	stale     map[int]bool
+	lastBuilt time.Time // of the last full build
+}
+
+func NewSitemapService(db *gorm.DB, publicBaseURL string) *SitemapService {
+	return &SitemapService{
+		db:      db,
+		baseURL: strings.TrimRight(publicBaseURL, "/"),
+		chunks:  map[int]*SitemapDocument{},
+		stale:   map[int]bool{},
+	}
+}
+
+// sitemapChunk is the chunk holding a report ID, counting from 1
+func sitemapChunk(reportID int) int {
+	return (reportID-1)/SitemapChunkSize + 1
+}
+
+// ChunkURL is where chunk n is served
+func (s *SitemapService) ChunkURL(n int) string {
+	return fmt.Sprintf("%s/sitemaps/reports-%d.xml", s.baseURL, n)
+}
+
+// HandleEvent marks the chunks of changed reports stale
+func (s *SitemapService) HandleEvent(ctx context.Context, e Event) {
+	switch e.Type {
+	case EventReportCreated, EventReportStatusChanged, EventReportMerged, EventReportDeleted:
+	default:
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.Report != nil {
+		s.stale[sitemapChunk(e.Report.ID)] = true
+	}
+	if e.MergedID != 0 {
+		s.stale[sitemapChunk(e.MergedID)] = true
+	}
+}
+
+// Refresh re-renders the stale chunks and the index, or every chunk on the
+// first call and once every sitemapFullRebuild
+func (s *SitemapService) Refresh(ctx context.Context) error {
+	s.build.Lock()
+	defer s.build.Unlock()
+
+	s.mu.Lock()
+	full := s.index == nil || time.Since(s.lastBuilt) > sitemapFullRebuild
+	stale := s.stale
+	s.stale = map[int]bool{}
+	s.mu.Unlock()
+
+	var todo []int
+	if full {
+		var maxID int
+		if err := s.db.WithContext(ctx).Model(&models.Report{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+			s.restale(stale)
+			return err
+		}
+		for n := 1; n <= sitemapChunk(maxID) && maxID > 0; n++ {
+			todo = append(todo, n)
+		}
+	} else {
+		for n := range stale {
+			todo = append(todo, n)
+		}
+		if len(todo) == 0 {
+			return nil
+		}
+	}
+
+	rendered := make(map[int]*SitemapDocument, len(todo))
+	for _, n := range todo {
+		doc, err := s.renderChunk(ctx, n)
+		if err != nil {
+			s.restale(stale)
+			return err
+		}
+		rendered[n] = doc
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if full {
+		s.chunks = map[int]*SitemapDocument{}
+		s.lastBuilt = time.Now()
+	}
+	for n, doc := range rendered {
+		if doc == nil {
+			delete(s.chunks, n)
+		} else {
+			s.chunks[n] = doc
+		}
+	}
+	index, err := s.renderIndex()
+	if err != nil {
+		return err
+	}
+	s.index = index
+	return nil
+}
+
+// restale puts chunks back after a failed refresh so the next one retries
+func (s *SitemapService) restale(chunks map[int]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n := range chunks {
+		s.stale[n] = true
+	}
+}
+
+// Run builds the sitemap immediately and then refreshes stale chunks every
+// interval until ctx is cancelled
+func (s *SitemapService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			utils.Error("sitemap refresh: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Index returns the sitemap index, building the sitemap first if Run has not
+// yet
+func (s *SitemapService) Index(ctx context.Context) (*SitemapDocument, error) {
+	s.mu.RLock()
+	index := s.index
+	s.mu.RUnlock()
+	if index != nil {
+		return index, nil
+	}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index, nil
+}
+
+// Chunk returns sitemap file n, or nil if it lists no reports
+func (s *SitemapService) Chunk(ctx context.Context, n int) (*SitemapDocument, error) {
+	if _, err := s.Index(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chunks[n], nil
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemapRow struct {
+	ID        int
+	ShareSlug string
+	LastMod   time.Time
+}
+
+// renderChunk lists the reports of chunk n, or returns nil if it has none
+func (s *SitemapService) renderChunk(ctx context.Context, n int) (*SitemapDocument, error) {
+	var rows []sitemapRow
+	err := s.db.WithContext(ctx).Model(&models.Report{}).
+		Select("id, share_slug, last_activity_at AS last_mod").
+		Where("merged_into_id IS NULL AND status IN ?", SitemapStatuses).
+		Where("id BETWEEN ? AND ?", (n-1)*SitemapChunkSize+1, n*SitemapChunkSize).
+		Order("id").
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	set := sitemapURLSet{URLs: make([]sitemapURL, len(rows))}
+	var lastMod time.Time
+	for i, r := range rows {
+		report := models.Report{ShareSlug: r.ShareSlug}
+		set.URLs[i] = sitemapURL{Loc: report.GenerateShareURL(s.baseURL), LastMod: r.LastMod.UTC().Format(time.RFC3339)}
+		if r.LastMod.After(lastMod) {
+			lastMod = r.LastMod
+		}
+	}
+	return sitemapDocument(set, lastMod)
+}
+
+// renderIndex lists the chunks; the caller holds mu
+func (s *SitemapService) renderIndex() (*SitemapDocument, error) {
+	numbers := make([]int, 0, len(s.chunks))
+	for n := range s.chunks {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	index := sitemapIndex{Sitemaps: make([]sitemapURL, len(numbers))}
+	var lastMod time.Time
+	for i, n := range numbers {
+		chunk := s.chunks[n]
+		index.Sitemaps[i] = sitemapURL{Loc: s.ChunkURL(n), LastMod: chunk.LastMod.UTC().Format(time.RFC3339)}
+		if chunk.LastMod.After(lastMod) {
+			lastMod = chunk.LastMod
+		}
+	}
+	return sitemapDocument(index, lastMod)
+}
+
+func sitemapDocument(v interface{}, lastMod time.Time) (*SitemapDocument, error) {
+	out, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte(xml.Header), out...)
+	sum := sha256.Sum256(data)
+	return &SitemapDocument{XML: data, LastMod: lastMod, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`}, nil
+}
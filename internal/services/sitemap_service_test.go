@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+func TestSitemapChunkBoundaries(t *testing.T) {
+	for id, want := range map[int]int{
+		1:                      1,
+		SitemapChunkSize:       1,
+		SitemapChunkSize + 1:   2,
+		2 * SitemapChunkSize:   2,
+		2*SitemapChunkSize + 1: 3,
+	} {
+		if got := sitemapChunk(id); got != want {
+			t.Errorf("report %d is in chunk %d, want %d", id, got, want)
+		}
+	}
+}
+
+var sitemapColumns = []string{"id", "share_slug", "last_mod"}
+
+// chunkRanges returns the ID ranges of the chunks rendered so far
+func chunkRanges(fake *fakedb.DB) [][2]driver.Value {
+	var ranges [][2]driver.Value
+	for _, q := range fake.Queried(`SELECT id, share_slug`) {
+		// The statuses come first, then the BETWEEN bounds
+		ranges = append(ranges, [2]driver.Value{q.Args[len(q.Args)-2], q.Args[len(q.Args)-1]})
+	}
+	return ranges
+}
+
+func TestSitemapFullBuild(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"coalesce"}, []driver.Value{int64(2*SitemapChunkSize + 1)})
+	fake.SetRows("reports", sitemapColumns, []driver.Value{int64(SitemapChunkSize), "a1", time.Now()})
+	fake.SetRows("reports", sitemapColumns) // every report of chunk 2 is pending or merged
+	fake.SetRows("reports", sitemapColumns, []driver.Value{int64(2*SitemapChunkSize + 1), "c3", time.Now()})
+	s := NewSitemapService(db, "https://helpgovern.example")
+
+	index, err := s.Index(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]driver.Value{
+		{int64(1), int64(SitemapChunkSize)},
+		{int64(SitemapChunkSize + 1), int64(2 * SitemapChunkSize)},
+		{int64(2*SitemapChunkSize + 1), int64(3 * SitemapChunkSize)},
+	}
+	got := chunkRanges(fake)
+	if len(got) != len(want) {
+		t.Fatalf("rendered chunks %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d covers %v, want %v", i+1, got[i], want[i])
+		}
+	}
+	xml := string(index.XML)
+	if !strings.Contains(xml, "/sitemaps/reports-1.xml") || strings.Contains(xml, "/sitemaps/reports-2.xml") || !strings.Contains(xml, "/sitemaps/reports-3.xml") {
+		t.Errorf("index should list chunks 1 and 3 only:\n%s", xml)
+	}
+	if chunk, _ := s.Chunk(context.Background(), 3); chunk == nil || !strings.Contains(string(chunk.XML), "https://helpgovern.example/r/c3") {
+		t.Errorf("chunk 3 does not list report c3")
+	}
+}
+
+func TestSitemapEventsRefreshTheirChunks(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("reports", []string{"coalesce"}, []driver.Value{int64(0)})
+	fake.SetRows("reports", sitemapColumns, []driver.Value{int64(SitemapChunkSize), "a1", time.Now()})
+	s := NewSitemapService(db, "https://helpgovern.example")
+	ctx := context.Background()
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.HandleEvent(ctx, Event{Type: EventReportStatusChanged, Report: &models.Report{ID: SitemapChunkSize}})
+	s.HandleEvent(ctx, Event{Type: EventReportMerged, Report: &models.Report{ID: 3 * SitemapChunkSize}, MergedID: SitemapChunkSize + 1})
+	s.HandleEvent(ctx, Event{Type: EventReportDeleted, Report: &models.Report{ID: 5*SitemapChunkSize + 1}})
+	s.HandleEvent(ctx, Event{Type: EventImageApproved, Report: &models.Report{ID: 8 * SitemapChunkSize}})
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed := map[driver.Value]bool{}
+	for _, r := range chunkRanges(fake) {
+		refreshed[r[1]] = true
+	}
+	for _, n := range []int64{1, 2, 3, 6} {
+		if !refreshed[n*SitemapChunkSize] {
+			t.Errorf("chunk %d was not refreshed", n)
+		}
+	}
+	if len(refreshed) != 4 {
+		t.Errorf("refreshed chunks ending at %v, want 1, 2, 3 and 6 only", refreshed)
+	}
+}
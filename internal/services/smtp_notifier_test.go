@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one message per
+// connection and records it
+type smtpStandIn struct {
+	ln       net.Listener
+	received chan smtpEnvelope
+}
+
+type smtpEnvelope struct {
+	from, to, data string
+}
+
+func newSMTPStandIn(t *testing.T, serve func(*smtpStandIn, net.Conn)) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &smtpStandIn{ln: ln, received: make(chan smtpEnvelope, 1)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(srv, conn)
+		}
+	}()
+	return srv
+}
+
+func (s *smtpStandIn) notifier(t *testing.T, from string) *SMTPNotifier {
+	t.Helper()
+	addr := s.ln.Addr().(*net.TCPAddr)
+	n, err := NewSMTPNotifier("127.0.0.1", addr.Port, "", "", from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// acceptMail speaks just enough SMTP for net/smtp to send a message
+func acceptMail(s *smtpStandIn, conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	var env smtpEnvelope
+	tp.PrintfLine("220 stand-in ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 stand-in")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			env.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			env.to = strings.Trim(line[len("RCPT TO:"):], "<>")
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			env.data = string(data)
+			tp.PrintfLine("250 queued")
+			s.received <- env
+		case cmd == "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	srv := newSMTPStandIn(t, acceptMail)
+	n := &models.Notification{
+		Channel:   models.ChannelEmail,
+		Recipient: "rwa@example.org",
+		Subject:   "Report #12 is now Resolved",
+		BodyText:  "The pothole issue you are following has a new status.",
+		BodyHTML:  "<p>The pothole issue you are following has a new status.</p>",
+	}
+	if err := srv.notifier(t, "alerts@helpgovern.example").Send(context.Background(), n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case env := <-srv.received:
+		if env.from != "alerts@helpgovern.example" || env.to != "rwa@example.org" {
+			t.Errorf("envelope = %q -> %q", env.from, env.to)
+		}
+		for _, want := range []string{
+			"To: rwa@example.org",
+			"Subject: Report #12 is now Resolved",
+			"multipart/alternative",
+			"The pothole issue you are following has a new status.",
+		} {
+			if !strings.Contains(env.data, want) {
+				t.Errorf("message is missing %q:\n%s", want, env.data)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stand-in received no message")
+	}
+}
+
+func TestSMTPNotifierSendStopsWithContext(t *testing.T) {
+	// A server that accepts connections but never greets
+	srv := newSMTPStandIn(t, func(_ *smtpStandIn, conn net.Conn) {
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := srv.notifier(t, "alerts@helpgovern.example").Send(ctx, &models.Notification{Recipient: "rwa@example.org", BodyText: "hi"})
+	if err == nil {
+		t.Fatal("Send succeeded against a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send returned after %v, want about 100ms", elapsed)
+	}
+	var netErr net.Error
+	if !errors.Is(err, context.DeadlineExceeded) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+}
+
+func TestSMTPNotifierSendDialFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	n, err := NewSMTPNotifier("127.0.0.1", port, "", "", "alerts@helpgovern.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), &models.Notification{Recipient: "rwa@example.org"}); err == nil {
+		t.Fatalf("Send to closed port %s succeeded", strconv.Itoa(port))
+	}
+}
+
+func TestSMTPNotifierSendWithDisplayName(t *testing.T) {
+	srv := newSMTPStandIn(t, acceptMail)
+	n := &models.Notification{Recipient: "rwa@example.org", Subject: "Hello", BodyText: "hi"}
+	if err := srv.notifier(t, "Help Govern <no-reply@helpgovern.example>").Send(context.Background(), n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case env := <-srv.received:
+		if env.from != "no-reply@helpgovern.example" {
+			t.Errorf("envelope sender = %q, want the bare address", env.from)
+		}
+		if !strings.Contains(env.data, `From: "Help Govern" <no-reply@helpgovern.example>`) {
+			t.Errorf("message is missing the display name in From:\n%s", env.data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stand-in received no message")
+	}
+}
+
+func TestNewSMTPNotifierRejectsBadSender(t *testing.T) {
+	for _, from := range []string{"", "Help Govern", "no-reply@", "<no-reply@example.org"} {
+		if _, err := NewSMTPNotifier("localhost", 25, "", "", from); err == nil {
+			t.Errorf("NewSMTPNotifier accepted sender %q", from)
+		}
+	}
+}
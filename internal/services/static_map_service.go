@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // tile sources may serve JPEG
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Static map size and zoom. 600×315 is the 1.91:1 ratio Open Graph and X
+// cards crop previews to.
+const (
+	StaticMapWidth  = 600
+	StaticMapHeight = 315
+	staticMapZoom   = 16
+	// staticMapTTL bounds how long base map tiles and rendered maps are
+	// kept; it is the minimum the OSM tile usage policy asks for
+	staticMapTTL = 7 * 24 * time.Hour
+	// Marker radius and the width of its white ring, in pixels
+	markerRadius = 9
+	markerRing   = 3
+)
+
+// mapTileUserAgent identifies the app to tile servers, as the OSM tile usage
+// policy requires
+const mapTileUserAgent = "help-govern/1.0 (+https://github.com/projects-for-public/help-govern)"
+
+// categoryColors follows the category colours of the web front end
+var categoryColors = map[string]color.NRGBA{
+	"potholes":           {0x8b, 0x5c, 0xf6, 0xff},
+	"broken_streetlight": {0xf5, 0x9e, 0x0b, 0xff},
+	"no_streetlight":     {0xf5, 0x9e, 0x0b, 0xff},
+	"water_leaks":        {0x3b, 0x82, 0xf6, 0xff},
+	"poor_drainage":      {0x06, 0xb6, 0xd4, 0xff},
+	"damaged_sidewalk":   {0x6b, 0x72, 0x80, 0xff},
+	"accident_prone":     {0xef, 0x44, 0x44, 0xff},
+	"garbage_heap":       {0x84, 0xcc, 0x16, 0xff},
+	"wrong_side_driving": {0xf9, 0x73, 0x16, 0xff},
+}
+
+var (
+	defaultMarkerColor = color.NRGBA{0x25, 0x63, 0xeb, 0xff}
+	markerShadowColor  = color.NRGBA{0, 0, 0, 0x60}
+	staticMapBackdrop  = color.NRGBA{0xe5, 0xe3, 0xdf, 0xff}
+)
+
+// CategoryColor is the marker colour for a report category
+func CategoryColor(category string) color.NRGBA {
+	if c, ok := categoryColors[category]; ok {
+		return c
+	}
+	return defaultMarkerColor
+}
+
+// MapTileSource fetches raster base map tiles. The template holds {z}, {x}
+// and {y} placeholders and is either an http(s) URL, such as a local tile
+// server or https://tile.openstreetmap.org/{z}/{x}/{y}.png, or a path to a
+// directory of pre-rendered tiles (optionally prefixed with file://).
+type MapTileSource struct {
+	template string
+	client   *http.Client
+}
+
+func NewMapTileSource(template string) *MapTileSource {
+	return &MapTileSource{template: template, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Tile returns the encoded tile z/x/y
+func (s *MapTileSource) Tile(ctx context.Context, z, x, y int) ([]byte, error) {
+	location := strings.NewReplacer(
+		"{z}", strconv.Itoa(z),
+		"{x}", strconv.Itoa(x),
+		"{y}", strconv.Itoa(y),
+	).Replace(s.template)
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", mapTileUserAgent)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tile server returned %s for %d/%d/%d", resp.Status, z, x, y)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// StaticMapService renders map snapshots for report previews: share pages,
+// social posts and emails. Base map tiles come from a MapTileSource and are
+// kept on disk under <dir>/base, rendered report maps under
+// <dir>/reports/<id>.png.
+type StaticMapService struct {
+	db     *gorm.DB
+	source *MapTileSource
+	tiles  *TileCache
+	dir    string
+}
+
+func NewStaticMapService(db *gorm.DB, source *MapTileSource, dir string) *StaticMapService {
+	return &StaticMapService{
+		db:     db,
+		source: source,
+		tiles:  NewTileCache(filepath.Join(dir, "base"), staticMapTTL, 0),
+		dir:    dir,
+	}
+}
+
+// ReportMap returns the PNG map of a report, centred on it with a marker in
+// its category's colour. Withdrawn reports are not found.
+func (s *StaticMapService) ReportMap(ctx context.Context, id int) ([]byte, error) {
+	path := filepath.Join(s.dir, "reports", strconv.Itoa(id)+".png")
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) <= staticMapTTL {
+		if data, err := os.ReadFile(path); err == nil {
+			return data, nil
+		}
+	}
+
+	var report models.Report
+	err := s.db.WithContext(ctx).Select("id, category, latitude, longitude, status").First(&report, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || report.Status == "withdrawn" {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, complete, err := s.Render(ctx, report.Latitude, report.Longitude, CategoryColor(report.Category))
+	if err != nil {
+		return nil, err
+	}
+	// A map with missing tiles is served but rendered again next time
+	if complete {
+		if err := writeFileAtomic(filepath.Dir(path), filepath.Base(path), data); err != nil {
+			utils.Error("static map: failed to cache map of report %d: %v", id, err)
+		}
+	}
+	return data, nil
+}
+
+// Render draws a StaticMapWidth × StaticMapHeight PNG centred on lat, lng
+// with a marker there. Tiles that cannot be fetched are left blank and
+// reported through complete=false.
+func (s *StaticMapService) Render(ctx context.Context, lat, lng float64, marker color.NRGBA) (data []byte, complete bool, err error) {
+	px, py := utils.WorldPixel(lat, lng, staticMapZoom)
+	left := int(math.Round(px)) - StaticMapWidth/2
+	top := int(math.Round(py)) - StaticMapHeight/2
+
+	img := image.NewNRGBA(image.Rect(0, 0, StaticMapWidth, StaticMapHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(staticMapBackdrop), image.Point{}, draw.Src)
+
+	n := 1 << staticMapZoom
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	for ty := floorDiv(top, utils.TileSize); ty <= floorDiv(top+StaticMapHeight-1, utils.TileSize); ty++ {
+		if ty < 0 || ty >= n {
+			continue
+		}
+		for tx := floorDiv(left, utils.TileSize); tx <= floorDiv(left+StaticMapWidth-1, utils.TileSize); tx++ {
+			wg.Add(1)
+			go func(tx, ty int) {
+				defer wg.Done()
+				tile, err := s.baseTile(ctx, (tx%n+n)%n, ty)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					utils.Error("static map: failed to load base tile %d/%d/%d: %v", staticMapZoom, tx, ty, err)
+					failed = true
+					return
+				}
+				at := image.Pt(tx*utils.TileSize-left, ty*utils.TileSize-top)
+				draw.Draw(img, tile.Bounds().Sub(tile.Bounds().Min).Add(at), tile, tile.Bounds().Min, draw.Src)
+			}(tx, ty)
+		}
+	}
+	wg.Wait()
+
+	cx, cy := px-float64(left), py-float64(top)
+	drawDisc(img, cx, cy+2, markerRadius+markerRing+2, markerShadowColor)
+	drawDisc(img, cx, cy, markerRadius+markerRing, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	drawDisc(img, cx, cy, markerRadius, marker)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), !failed, nil
+}
+
+// baseTile returns a decoded base map tile, from the disk cache when possible
+func (s *StaticMapService) baseTile(ctx context.Context, x, y int) (image.Image, error) {
+	data, ok := s.tiles.Get("base", staticMapZoom, x, y, "tile")
+	if !ok {
+		var err error
+		if data, err = s.source.Tile(ctx, staticMapZoom, x, y); err != nil {
+			return nil, err
+		}
+		if err := s.tiles.Put("base", staticMapZoom, x, y, "tile", data); err != nil {
+			utils.Error("static map: failed to cache base tile %d/%d/%d: %v", staticMapZoom, x, y, err)
+		}
+	}
+	tile, _, err := image.Decode(bytes.NewReader(data))
+	return tile, err
+}
+
+// drawDisc blends an anti-aliased filled circle onto img
+func drawDisc(img *image.NRGBA, cx, cy, r float64, c color.NRGBA) {
+	bounds := image.Rect(int(cx-r)-1, int(cy-r)-1, int(cx+r)+2, int(cy+r)+2).Intersect(img.Bounds())
+	mask := image.NewAlpha(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			coverage := math.Max(0, math.Min(1, r+0.5-d))
+			mask.SetAlpha(x, y, color.Alpha{uint8(coverage * 255)})
+		}
+	}
+	draw.DrawMask(img, bounds, image.NewUniform(c), image.Point{}, mask, bounds.Min, draw.Over)
+}
+
+// floorDiv divides rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// statsViews are the materialized views behind the /stats endpoints, see
+// migration 020_stats.sql
+var statsViews = []string{"stats_counts", "stats_resolution_times", "stats_monthly", "stats_authorities"}
+
+// StatsService serves public analytics from materialized views that Run
+// refreshes periodically. Figures can therefore lag by one refresh interval.
+type StatsService struct {
+	db *gorm.DB
+
+	mu          sync.RWMutex
+	refreshedAt *time.Time
+}
+
+func NewStatsService(db *gorm.DB) *StatsService {
+	return &StatsService{db: db}
+}
+
+// RefreshedAt is when this process last refreshed the views, nil before the
+// first refresh
+func (s *StatsService) RefreshedAt() *time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshedAt
+}
+
+// Refresh recomputes every stats view. CONCURRENTLY keeps the old contents
+// readable while the new ones are built.
+func (s *StatsService) Refresh(ctx context.Context) error {
+	for _, view := range statsViews {
+		if err := s.db.WithContext(ctx).Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + view).Error; err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	s.mu.Lock()
+	s.refreshedAt = &now
+	s.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the views immediately and then every interval until ctx is
+// cancelled
+func (s *StatsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			utils.Error("stats refresh: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CountEntry is the number of reports with one value of a dimension
+type CountEntry struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// StatsSummary counts live reports (not merged or withdrawn)
+type StatsSummary struct {
+	Total      int          `json:"total"`
+	ByCategory []CountEntry `json:"by_category"`
+	ByStatus   []CountEntry `json:"by_status"`
+	ByState    []CountEntry `json:"by_state"`
+}
+
+// Summary returns report counts by category, status and state, optionally
+// limited to one category
+func (s *StatsService) Summary(ctx context.Context, category string) (*StatsSummary, error) {
+	summary := &StatsSummary{}
+	for _, dim := range []struct {
+		column string
+		into   *[]CountEntry
+	}{
+		{"category", &summary.ByCategory},
+		{"status", &summary.ByStatus},
+		{"state", &summary.ByState},
+	} {
+		q := s.db.WithContext(ctx).Table("stats_counts").
+			Select(dim.column + " AS key, SUM(reports)::int AS count").
+			Group(dim.column).Order("count DESC").Order(dim.column)
+		if category != "" {
+			q = q.Where("category = ?", category)
+		}
+		entries := []CountEntry{}
+		if err := q.Scan(&entries).Error; err != nil {
+			return nil, err
+		}
+		*dim.into = entries
+	}
+	for _, e := range summary.ByStatus {
+		summary.Total += e.Count
+	}
+	return summary, nil
+}
+
+// ResolutionTimes is how quickly one group of reports was handled. Durations
+// are in hours and nil when nothing in the group has reached that stage.
+type ResolutionTimes struct {
+	Key                string   `json:"key,omitempty"`
+	Reports            int      `json:"reports"`
+	Verified           int      `json:"verified"`
+	VerifyMedianHours  *float64 `json:"verify_median_hours"`
+	VerifyP90Hours     *float64 `json:"verify_p90_hours"`
+	Resolved           int      `json:"resolved"`
+	ResolveMedianHours *float64 `json:"resolve_median_hours"`
+	ResolveP90Hours    *float64 `json:"resolve_p90_hours"`
+}
+
+type resolutionTimesRow struct {
+	Key            string
+	Reports        int
+	Verified       int
+	VerifyMedianS  *float64
+	VerifyP90S     *float64 `gorm:"column:verify_p90_s"`
+	Resolved       int
+	ResolveMedianS *float64
+	ResolveP90S    *float64 `gorm:"column:resolve_p90_s"`
+}
+
+func hours(seconds *float64) *float64 {
+	if seconds == nil {
+		return nil
+	}
+	h := *seconds / 3600
+	return &h
+}
+
+// ResolutionTimes returns median and p90 time from submission to
+// verification and to resolution. dimension is "all", "category" or
+// "state".
+func (s *StatsService) ResolutionTimes(ctx context.Context, dimension string) ([]ResolutionTimes, error) {
+	var rows []resolutionTimesRow
+	err := s.db.WithContext(ctx).Table("stats_resolution_times").
+		Where("dimension = ?", dimension).Order("reports DESC").Order("key").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ResolutionTimes, len(rows))
+	for i, r := range rows {
+		out[i] = ResolutionTimes{
+			Key:                r.Key,
+			Reports:            r.Reports,
+			Verified:           r.Verified,
+			VerifyMedianHours:  hours(r.VerifyMedianS),
+			VerifyP90Hours:     hours(r.VerifyP90S),
+			Resolved:           r.Resolved,
+			ResolveMedianHours: hours(r.ResolveMedianS),
+			ResolveP90Hours:    hours(r.ResolveP90S),
+		}
+	}
+	return out, nil
+}
+
+// TrendPoint is one month of activity
+type TrendPoint struct {
+	Month    string `json:"month"` // YYYY-MM
+	Created  int    `json:"created"`
+	Verified int    `json:"verified"`
+	Resolved int    `json:"resolved"`
+}
+
+// Trends returns the last months calendar months, oldest first, including
+// the current one. Months without activity are filled with zeros.
+func (s *StatsService) Trends(ctx context.Context, months int, category string) ([]TrendPoint, error) {
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(months - 1), 0)
+
+	var rows []struct {
+		Month    time.Time
+		Created  int
+		Verified int
+		Resolved int
+	}
+	q := s.db.WithContext(ctx).Table("stats_monthly").
+		Select("month, SUM(created)::int AS created, SUM(verified)::int AS verified, SUM(resolved)::int AS resolved").
+		Where("month >= ?", first).Group("month")
+	if category != "" {
+		q = q.Where("category = ?", category)
+	}
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	points := make([]TrendPoint, months)
+	index := make(map[string]int, months)
+	for i := range points {
+		key := first.AddDate(0, i, 0).Format("2006-01")
+		points[i].Month = key
+		index[key] = i
+	}
+	for _, r := range rows {
+		if i, ok := index[r.Month.Format("2006-01")]; ok {
+			points[i].Created = r.Created
+			points[i].Verified = r.Verified
+			points[i].Resolved = r.Resolved
+		}
+	}
+	return points, nil
+}
+
+// AuthorityStats ranks a state authority by how it handles the reports in
+// its state
+type AuthorityStats struct {
+	Rank               int      `json:"rank"`
+	AuthorityID        int      `json:"authority_id"`
+	AuthorityName      string   `json:"authority_name"`
+	State              string   `json:"state"`
+	TwitterHandle      *string  `json:"twitter_handle,omitempty"`
+	Reports            int      `json:"reports"`
+	Open               int      `json:"open"`
+	Resolved           int      `json:"resolved"`
+	ResolutionRate     float64  `json:"resolution_rate"` // resolved / reports
+	ResolveMedianHours *float64 `json:"resolve_median_hours"`
+}
+
+// AuthorityLeaderboard orders active authorities by resolution rate, then
+// by median time to resolve. Authorities with fewer than minReports reports
+// are left out so a single lucky report doesn't top the board.
+func (s *StatsService) AuthorityLeaderboard(ctx context.Context, minReports int) ([]AuthorityStats, error) {
+	var rows []struct {
+		AuthorityID    int
+		AuthorityName  string
+		State          string
+		TwitterHandle  *string
+		Reports        int
+		OpenReports    int
+		Resolved       int
+		ResolveMedianS *float64
+	}
+	err := s.db.WithContext(ctx).Table("stats_authorities").
+		Where("reports >= ?", minReports).
+		Order("resolved::float / NULLIF(reports, 0) DESC NULLS LAST").
+		Order("resolve_median_s ASC NULLS LAST").
+		Order("authority_name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AuthorityStats, len(rows))
+	for i, r := range rows {
+		out[i] = AuthorityStats{
+			Rank:               i + 1,
+			AuthorityID:        r.AuthorityID,
+			AuthorityName:      r.AuthorityName,
+			State:              r.State,
+			TwitterHandle:      r.TwitterHandle,
+			Reports:            r.Reports,
+			Open:               r.OpenReports,
+			Resolved:           r.Resolved,
+			ResolveMedianHours: hours(r.ResolveMedianS),
+		}
+		if r.Reports > 0 {
+			out[i].ResolutionRate = float64(r.Resolved) / float64(r.Reports)
+		}
+	}
+	return out, nil
+}
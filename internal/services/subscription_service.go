@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Limits on area subscriptions so matching stays cheap
+const (
+	MaxAreaRadiusMeters = 50000
+	MinAreaRadiusMeters = 100
+	MaxPolygonVertices  = 200
+)
+
+// ErrInvalidArea is returned for malformed or oversized areas
+var ErrInvalidArea = errors.New("invalid area")
+
+// SubscriptionService manages public subscriptions to reports and areas
+type SubscriptionService struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionService(db *gorm.DB) *SubscriptionService {
+	return &SubscriptionService{db: db}
+}
+
+// SubscribeReport follows a single report. Re-registering the same push
+// endpoint or email address for the same report is a no-op that returns the
+// existing row.
+func (s *SubscriptionService) SubscribeReport(ctx context.Context, reportID int, sub *models.Subscription) error {
+	var report models.Report
+	if err := s.db.WithContext(ctx).Select("id", "merged_into_id").First(&report, reportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReportNotFound
+		}
+		return err
+	}
+	// Follow the canonical report if this one was merged
+	if report.MergedIntoID != nil {
+		reportID = *report.MergedIntoID
+	}
+	sub.ReportID = &reportID
+	return s.create(ctx, sub, s.db.Where("report_id = ?", reportID))
+}
+
+// SubscribeArea follows reports within a circle or polygon, optionally
+// limited to some categories. Re-registering the same push endpoint or email
+// address for the same area updates the filters of the existing subscription.
+func (s *SubscriptionService) SubscribeArea(ctx context.Context, sub *models.Subscription) error {
+	if err := validateArea(sub); err != nil {
+		return err
+	}
+	if len(sub.Categories) > 0 {
+		seen := make(map[string]bool, len(sub.Categories))
+		unique := sub.Categories[:0]
+		for _, c := range sub.Categories {
+			if !seen[c] {
+				seen[c] = true
+				unique = append(unique, c)
+			}
+		}
+		sub.Categories = unique
+		var known int64
+		if err := s.db.WithContext(ctx).Model(&models.Category{}).
+			Where("name IN ? AND is_active = TRUE", []string(sub.Categories)).Count(&known).Error; err != nil {
+			return err
+		}
+		if int(known) != len(sub.Categories) {
+			return fmt.Errorf("%w: unknown category", ErrInvalidArea)
+		}
+	}
+	sub.ReportID = nil
+	if sub.Delivery == "" {
+		sub.Delivery = models.DeliveryInstant
+	}
+	sub.SetBounds()
+	same := s.db.Where("report_id IS NULL AND polygon IS NULL AND center_lat = ? AND center_lng = ? AND radius_m = ?",
+		sub.CenterLat, sub.CenterLng, sub.RadiusM)
+	if len(sub.Polygon) > 0 {
+		same = s.db.Where("report_id IS NULL AND polygon = ?", sub.Polygon)
+	}
+	return s.create(ctx, sub, same)
+}
+
+// validateArea checks that an area subscription has exactly one sane geometry
+func validateArea(sub *models.Subscription) error {
+	if len(sub.Polygon) > 0 {
+		if len(sub.Polygon) < 3 || len(sub.Polygon) > MaxPolygonVertices {
+			return fmt.Errorf("%w: a polygon needs between 3 and %d vertices", ErrInvalidArea, MaxPolygonVertices)
+		}
+		for _, p := range sub.Polygon {
+			if p[0] < -90 || p[0] > 90 || p[1] < -180 || p[1] > 180 {
+				return fmt.Errorf("%w: polygon vertex out of range", ErrInvalidArea)
+			}
+		}
+		minLat, minLng, maxLat, maxLng := utils.PolygonBounds(sub.Polygon)
+		if utils.HaversineMeters(minLat, minLng, maxLat, maxLng) > 2*MaxAreaRadiusMeters {
+			return fmt.Errorf("%w: polygon is too large", ErrInvalidArea)
+		}
+		sub.CenterLat, sub.CenterLng, sub.RadiusM = nil, nil, nil
+		return nil
+	}
+	if sub.CenterLat == nil || sub.CenterLng == nil || sub.RadiusM == nil {
+		return fmt.Errorf("%w: provide a circle (latitude, longitude, radius_m) or a polygon", ErrInvalidArea)
+	}
+	if *sub.CenterLat < -90 || *sub.CenterLat > 90 || *sub.CenterLng < -180 || *sub.CenterLng > 180 {
+		return fmt.Errorf("%w: centre out of range", ErrInvalidArea)
+	}
+	if *sub.RadiusM < MinAreaRadiusMeters || *sub.RadiusM > MaxAreaRadiusMeters {
+		return fmt.Errorf("%w: radius must be between %d and %d meters", ErrInvalidArea, MinAreaRadiusMeters, MaxAreaRadiusMeters)
+	}
+	return nil
+}
+
+// create stores sub unless the same push endpoint or email address already
+// follows the same report or area (same), in which case sub becomes the
+// existing row. Web push subscriptions are confirmed right away; email
+// subscriptions wait for their owner to confirm.
+func (s *SubscriptionService) create(ctx context.Context, sub *models.Subscription, same *gorm.DB) error {
+	var recipient *gorm.DB
+	switch {
+	case sub.Channel == models.ChannelWebPush && sub.PushEndpoint != nil:
+		recipient = s.db.Where("channel = ? AND push_endpoint = ?", models.ChannelWebPush, *sub.PushEndpoint)
+	case sub.Channel == models.ChannelEmail && sub.Email != nil:
+		recipient = s.db.Where("channel = ? AND lower(email) = lower(?)", models.ChannelEmail, *sub.Email)
+	}
+	if recipient != nil {
+		var existing models.Subscription
+		err := s.db.WithContext(ctx).Where(same).Where(recipient).First(&existing).Error
+		if err == nil {
+			if sub.ReportID == nil {
+				existing.Categories, existing.Delivery = sub.Categories, sub.Delivery
+				if sub.Locale != "" {
+					existing.Locale = sub.Locale
+				}
+				if err := s.db.WithContext(ctx).Model(&existing).
+					Select("categories", "delivery", "locale").Updates(&existing).Error; err != nil {
+					return err
+				}
+			}
+			*sub = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	token, err := utils.RandomToken(24)
+	if err != nil {
+		return err
+	}
+	sub.UnsubscribeToken = token
+	if sub.Locale == "" {
+		sub.Locale = "en"
+	}
+	if sub.Channel == models.ChannelWebPush {
+		now := time.Now()
+		sub.ConfirmedAt = &now
+	}
+	return s.db.WithContext(ctx).Create(sub).Error
+}
+
+// Confirm activates the email subscription whose confirmation link carried
+// token. A link works once.
+func (s *SubscriptionService) Confirm(ctx context.Context, token string) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := s.db.WithContext(ctx).Where("confirm_token_hash = ?", utils.HashToken(token)).First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(&sub).Updates(map[string]interface{}{
+		"confirmed_at":       now,
+		"confirm_token_hash": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	sub.ConfirmedAt, sub.ConfirmTokenHash = &now, nil
+	return &sub, nil
+}
+
+// GetByToken looks a subscription up by its unsubscribe token
+func (s *SubscriptionService) GetByToken(ctx context.Context, token string) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := s.db.WithContext(ctx).Where("unsubscribe_token = ?", token).First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UnsubscribePush removes subscriptions of a push endpoint: the one for
+// reportID when given, otherwise all of them. It returns how many were removed.
+func (s *SubscriptionService) UnsubscribePush(ctx context.Context, endpoint string, reportID *int) (int64, error) {
+	q := s.db.WithContext(ctx).Where("channel = ? AND push_endpoint = ?", models.ChannelWebPush, endpoint)
+	if reportID != nil {
+		q = q.Where("report_id = ?", *reportID)
+	}
+	res := q.Delete(&models.Subscription{})
+	return res.RowsAffected, res.Error
+}
+
+// AreaSubscriptionsMatching returns area subscriptions whose area contains
+// the report and whose category filter accepts it
+func (s *SubscriptionService) AreaSubscriptionsMatching(ctx context.Context, report *models.Report) ([]models.Subscription, error) {
+	var candidates []models.Subscription
+	err := s.db.WithContext(ctx).
+		Where("report_id IS NULL AND confirmed_at IS NOT NULL").
+		Where("min_lat <= ? AND max_lat >= ? AND min_lng <= ? AND max_lng >= ?",
+			report.Latitude, report.Latitude, report.Longitude, report.Longitude).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	matched := candidates[:0]
+	for _, sub := range candidates {
+		if sub.Matches(report) {
+			matched = append(matched, sub)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+func TestAreaSubscriptionsMatching(t *testing.T) {
+	db, fake := fakedb.New(t)
+	fake.SetRows("subscriptions", []string{"id", "center_lat", "center_lng", "radius_m", "polygon", "categories"},
+		// Circles 500 m north of the report
+		[]driver.Value{int64(1), 26.9169, 75.7873, int64(1000), nil, nil},
+		[]driver.Value{int64(2), 26.9169, 75.7873, int64(300), nil, nil},
+		[]driver.Value{int64(3), 26.9169, 75.7873, int64(1000), nil, "{potholes,broken_streetlight}"},
+		// A square around the report, and a triangle whose bounds hold it but which does not
+		[]driver.Value{int64(4), nil, nil, nil, "[[26.90,75.77],[26.92,75.77],[26.92,75.80],[26.90,75.80]]", nil},
+		[]driver.Value{int64(5), nil, nil, nil, "[[26.90,75.77],[26.92,75.77],[26.90,75.80]]", nil},
+		[]driver.Value{int64(6), nil, nil, nil, "[[26.90,75.77],[26.92,75.77],[26.92,75.80],[26.90,75.80]]", "{garbage_heap}"},
+	)
+	s := NewSubscriptionService(db)
+
+	report := &models.Report{ID: 12, Category: "potholes", Latitude: 26.9124, Longitude: 75.7873}
+	matched, err := s.AreaSubscriptionsMatching(context.Background(), report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, sub := range matched {
+		ids = append(ids, sub.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("matched subscriptions %v, want [1 3 4]", ids)
+	}
+	q := fake.Queried(`SELECT * FROM "subscriptions"`)[0]
+	if !strings.Contains(q.Query, "confirmed_at IS NOT NULL") || !strings.Contains(q.Query, "min_lat <= $1 AND max_lat >= $2") {
+		t.Errorf("candidates are not prefiltered on confirmation and bounds: %s", q.Query)
+	}
+}
+
+func TestValidateArea(t *testing.T) {
+	lat, lng := 26.9124, 75.7873
+	radius := func(m int) *models.Subscription {
+		return &models.Subscription{CenterLat: &lat, CenterLng: &lng, RadiusM: &m}
+	}
+	polygon := func(p ...[2]float64) *models.Subscription {
+		return &models.Subscription{Polygon: p}
+	}
+	for name, tc := range map[string]struct {
+		sub   *models.Subscription
+		valid bool
+	}{
+		"circle":              {radius(2000), true},
+		"smallest circle":     {radius(MinAreaRadiusMeters), true},
+		"circle too small":    {radius(MinAreaRadiusMeters - 1), false},
+		"circle too large":    {radius(MaxAreaRadiusMeters + 1), false},
+		"no geometry":         {&models.Subscription{}, false},
+		"triangle":            {polygon([2]float64{26.90, 75.77}, [2]float64{26.92, 75.77}, [2]float64{26.90, 75.80}), true},
+		"two vertices":        {polygon([2]float64{26.90, 75.77}, [2]float64{26.92, 75.77}), false},
+		"vertex out of range": {polygon([2]float64{26.90, 75.77}, [2]float64{96.92, 75.77}, [2]float64{26.90, 75.80}), false},
+		"polygon too large":   {polygon([2]float64{26.0, 75.0}, [2]float64{27.0, 75.0}, [2]float64{27.0, 76.0}), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := validateArea(tc.sub)
+			if tc.valid && err != nil {
+				t.Errorf("rejected: %v", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidArea) {
+				t.Errorf("err = %v, want ErrInvalidArea", err)
+			}
+		})
+	}
+
+	// A polygon wins over a circle sent alongside it
+	sub := radius(2000)
+	sub.Polygon = models.Polygon{{26.90, 75.77}, {26.92, 75.77}, {26.90, 75.80}}
+	if err := validateArea(sub); err != nil || sub.RadiusM != nil || sub.CenterLat != nil {
+		t.Errorf("err %v, radius %v; want the polygon kept and the circle dropped", err, sub.RadiusM)
+	}
+}
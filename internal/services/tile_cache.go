@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/utils"
+)
+
+// tileCacheBufferPx is how far around a changed report cached tiles are
+// dropped. It must cover the widest rendering buffer of any layer.
+const tileCacheBufferPx = 32
+
+// TileCache keeps rendered map tiles on disk as
+// <dir>/<layer>/<z>/<x>/<y>/<variant>, where variant encodes the filters a
+// tile was rendered with. Keeping every variant of a tile under one
+// directory lets a report change drop them all at once.
+type TileCache struct {
+	dir string
+	// ttl bounds staleness from changes that publish no event
+	ttl time.Duration
+	// maxBytes caps the size of the cache; Prune drops the least recently
+	// written tiles beyond it. Zero leaves the size unbounded.
+	maxBytes int64
+}
+
+func NewTileCache(dir string, ttl time.Duration, maxBytes int64) *TileCache {
+	return &TileCache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+func (c *TileCache) tileDir(layer string, z, x, y int) string {
+	return filepath.Join(c.dir, layer, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y))
+}
+
+// Get returns a cached tile if there is a fresh one
+func (c *TileCache) Get(layer string, z, x, y int, variant string) ([]byte, bool) {
+	path := filepath.Join(c.tileDir(layer, z, x, y), variant)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores a tile. Writes go through a temp file so readers never see a
+// partial tile.
+func (c *TileCache) Put(layer string, z, x, y int, variant string, data []byte) error {
+	return writeFileAtomic(c.tileDir(layer, z, x, y), variant, data)
+}
+
+// writeFileAtomic writes dir/name through a temp file in dir
+func writeFileAtomic(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// InvalidatePoint drops every cached tile, in every layer and at every zoom,
+// that shows the given location
+func (c *TileCache) InvalidatePoint(lat, lng float64) {
+	layers, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, layer := range layers {
+		if !layer.IsDir() {
+			continue
+		}
+		for z := 0; z <= utils.MaxTileZoom; z++ {
+			for _, t := range utils.TilesAround(lat, lng, z, tileCacheBufferPx) {
+				if err := os.RemoveAll(c.tileDir(layer.Name(), z, t[0], t[1])); err != nil {
+					utils.Error("tile cache: failed to drop %s/%d/%d/%d: %v", layer.Name(), z, t[0], t[1], err)
+				}
+			}
+		}
+	}
+}
+
+// Prune deletes expired tiles and leftover temp files, then the oldest
+// tiles until the cache fits in maxBytes, and finally empty directories
+func (c *TileCache) Prune(ctx context.Context) error {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var live []entry
+	var total int64
+	var dirs []string
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if path != c.dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Dropped by InvalidatePoint while walking
+			return nil
+		}
+		if time.Since(info.ModTime()) > c.ttl {
+			return os.Remove(path)
+		}
+		live = append(live, entry{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c.maxBytes > 0 && total > c.maxBytes {
+		sort.Slice(live, func(i, j int) bool { return live[i].modTime.Before(live[j].modTime) })
+		for _, e := range live {
+			if total <= c.maxBytes {
+				break
+			}
+			if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			total -= e.size
+		}
+	}
+	// Deepest first, so parents empty out after their children; Remove
+	// fails harmlessly on directories that still hold tiles
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+	return nil
+}
+
+// Run prunes the cache every interval until ctx is cancelled
+func (c *TileCache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Prune(ctx); err != nil && ctx.Err() == nil {
+			utils.Error("tile cache prune: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// HandleEvent drops tiles around reports that were created, changed status,
+// absorbed a duplicate or were deleted
+func (c *TileCache) HandleEvent(ctx context.Context, e Event) {
+	switch e.Type {
+	case EventReportCreated, EventReportStatusChanged, EventReportMerged, EventReportDeleted:
+		if e.Report != nil {
+			c.InvalidatePoint(e.Report.Latitude, e.Report.Longitude)
+		}
+	}
+}
+
+// tilePixelX and tilePixelY project a report to pixel coordinates relative
+// to the top-left corner of a tile, in SQL, the same way as
+// utils.WorldPixel. They take the named arguments from tilePixelArgs.
+const (
+	tilePixelX = "((longitude::float8 + 180) / 360 * @scale - @ox)"
+	tilePixelY = "((0.5 - ln((1 + sin(radians(latitude::float8))) / (1 - sin(radians(latitude::float8)))) / (4 * pi())) * @scale - @oy)"
+)
+
+func tilePixelArgs(z, x, y int) map[string]interface{} {
+	return map[string]interface{}{
+		"scale": float64(utils.TileSize) * float64(int(1)<<z),
+		"ox":    float64(x * utils.TileSize),
+		"oy":    float64(y * utils.TileSize),
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTrackingTokenNotFound = errors.New("tracking token not found")
+	ErrSubscriptionNotFound  = errors.New("subscription not found")
+)
+
+// MaxTrackedTokens caps how many tokens one "my reports" lookup may present
+const MaxTrackedTokens = 50
+
+// TrackingService lets anonymous reporters follow their reports without an account
+type TrackingService struct {
+	db *gorm.DB
+}
+
+func NewTrackingService(db *gorm.DB) *TrackingService {
+	return &TrackingService{db: db}
+}
+
+// TrackedReport is a report found through one of the presented tracking tokens
+type TrackedReport struct {
+	Token  string
+	Report *models.Report
+}
+
+// IssueToken creates a tracking token for the report. The raw token is
+// returned once and only its hash is stored.
+func (s *TrackingService) IssueToken(ctx context.Context, reportID int) (string, error) {
+	token, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	err = s.db.WithContext(ctx).Create(&models.TrackingToken{
+		ReportID:  reportID,
+		TokenHash: utils.HashToken(token),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// MyReports resolves tracking tokens stored on a device to their reports.
+// Unknown tokens are skipped; reports merged into another report are
+// replaced by the canonical report.
+func (s *TrackingService) MyReports(ctx context.Context, tokens []string) ([]TrackedReport, error) {
+	byHash := make(map[string]string, len(tokens))
+	hashes := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		h := utils.HashToken(t)
+		if _, dup := byHash[h]; dup {
+			continue
+		}
+		byHash[h] = t
+		hashes = append(hashes, h)
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	var found []models.TrackingToken
+	if err := s.db.WithContext(ctx).Where("token_hash IN ?", hashes).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	tracked := make([]TrackedReport, 0, len(found))
+	for _, tt := range found {
+		var report models.Report
+		err := s.db.WithContext(ctx).
+			Preload("StatusUpdates", func(db *gorm.DB) *gorm.DB { return db.Order("updated_at, id") }).
+			First(&report, tt.ReportID).Error
+		if err != nil {
+			return nil, err
+		}
+		if report.MergedIntoID != nil {
+			if err := s.db.WithContext(ctx).
+				Preload("StatusUpdates", func(db *gorm.DB) *gorm.DB { return db.Order("updated_at, id") }).
+				First(&report, *report.MergedIntoID).Error; err != nil {
+				return nil, err
+			}
+		}
+		tracked = append(tracked, TrackedReport{Token: byHash[tt.TokenHash], Report: &report})
+	}
+	return tracked, nil
+}
+
+// Subscribe registers a notification channel against the report behind the
+// tracking token. The subscription gets its own unsubscribe token. Email
+// subscriptions stay inactive until confirmed.
+func (s *TrackingService) Subscribe(ctx context.Context, token string, sub *models.Subscription) error {
+	var tt models.TrackingToken
+	err := s.db.WithContext(ctx).Where("token_hash = ?", utils.HashToken(token)).First(&tt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTrackingTokenNotFound
+	}
+	if err != nil {
+		return err
+	}
+	unsubscribe, err := utils.RandomToken(24)
+	if err != nil {
+		return err
+	}
+	// Follow the canonical report if this one was merged
+	reportID := tt.ReportID
+	var report models.Report
+	if err := s.db.WithContext(ctx).Select("id", "merged_into_id").First(&report, reportID).Error; err != nil {
+		return err
+	}
+	if report.MergedIntoID != nil {
+		reportID = *report.MergedIntoID
+	}
+	sub.ReportID = &reportID
+	sub.TrackingTokenID = &tt.ID
+	sub.UnsubscribeToken = unsubscribe
+	if sub.Locale == "" {
+		sub.Locale = "en"
+	}
+	if sub.Channel == models.ChannelWebPush {
+		now := time.Now()
+		sub.ConfirmedAt = &now
+	}
+	return s.db.WithContext(ctx).Create(sub).Error
+}
+
+// Unsubscribe removes the subscription identified by its unsubscribe token
+func (s *TrackingService) Unsubscribe(ctx context.Context, unsubscribeToken string) error {
+	res := s.db.WithContext(ctx).Where("unsubscribe_token = ?", unsubscribeToken).Delete(&models.Subscription{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/i18n"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Translatable entities
+const (
+	EntityCategory             = "category"
+	EntityAuthority            = "authority"
+	EntityNotificationTemplate = "notification_template"
+)
+
+// TranslatableFields lists the fields of each entity that can be translated.
+// Notification templates are translated as whole template sources, in the
+// same syntax as the files in NOTIFICATION_TEMPLATES_DIR.
+var TranslatableFields = map[string][]string{
+	EntityCategory:             {"name", "description"},
+	EntityAuthority:            {"authority_name"},
+	EntityNotificationTemplate: {"text", "html"},
+}
+
+var (
+	ErrTranslationNotFound = errors.New("translation not found")
+	ErrInvalidTranslation  = errors.New("invalid translation")
+)
+
+type translationKey struct {
+	entity, entityID, field, locale string
+}
+
+// TranslationService stores translations of entity text and looks them up
+// along each locale's fallback chain. Lookups are served from memory; the
+// table is reloaded after every change made here and periodically by Run to
+// pick up changes made by other instances.
+type TranslationService struct {
+	db        *gorm.DB
+	catalog   *i18n.Catalog
+	templates *NotificationTemplates
+
+	mu     sync.RWMutex
+	values map[translationKey]string
+}
+
+func NewTranslationService(db *gorm.DB, catalog *i18n.Catalog, templates *NotificationTemplates) *TranslationService {
+	return &TranslationService{
+		db:        db,
+		catalog:   catalog,
+		templates: templates,
+		values:    map[translationKey]string{},
+	}
+}
+
+// Reload reads every translation into memory
+func (s *TranslationService) Reload(ctx context.Context) error {
+	var rows []models.Translation
+	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return err
+	}
+	values := make(map[translationKey]string, len(rows))
+	for _, t := range rows {
+		values[translationKey{t.Entity, t.EntityID, t.Field, t.Locale}] = t.Value
+	}
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	return nil
+}
+
+// Run reloads the translations immediately and then every interval until
+// ctx is cancelled
+func (s *TranslationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Reload(ctx); err != nil {
+			utils.Error("translations reload: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns the translation stored for exactly this locale
+func (s *TranslationService) Get(entity, entityID, field, locale string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[translationKey{entity, entityID, field, locale}]
+	return v, ok
+}
+
+// Translate returns field in locale, following the locale's fallback chain,
+// or source when no language on the chain has a translation
+func (s *TranslationService) Translate(entity, entityID, field, locale, source string) string {
+	for _, l := range s.Chain(locale) {
+		if v, ok := s.Get(entity, entityID, field, l); ok {
+			return v
+		}
+	}
+	return source
+}
+
+// Chain is the lookup order for locale, e.g. [mr hi en]
+func (s *TranslationService) Chain(locale string) []string {
+	var catalog *i18n.Catalog
+	if s != nil {
+		catalog = s.catalog
+	}
+	return catalog.Chain(locale)
+}
+
+// Values returns every translation of field, ordered by locale
+func (s *TranslationService) Values(entity, entityID, field string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var locales []string
+	for k := range s.values {
+		if k.entity == entity && k.entityID == entityID && k.field == field {
+			locales = append(locales, k.locale)
+		}
+	}
+	sort.Strings(locales)
+	values := make([]string, len(locales))
+	for i, l := range locales {
+		values[i] = s.values[translationKey{entity, entityID, field, l}]
+	}
+	return values
+}
+
+// TranslationFilter narrows ListTranslations; empty fields match everything
+type TranslationFilter struct {
+	Entity   string
+	EntityID string
+	Locale   string
+}
+
+// ListTranslations returns the stored translations matching filter
+func (s *TranslationService) ListTranslations(ctx context.Context, filter TranslationFilter) ([]models.Translation, error) {
+	q := s.db.WithContext(ctx).Model(&models.Translation{})
+	if filter.Entity != "" {
+		q = q.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		q = q.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Locale != "" {
+		q = q.Where("locale = ?", filter.Locale)
+	}
+	var out []models.Translation
+	err := q.Order("entity, entity_id, field, locale").Find(&out).Error
+	return out, err
+}
+
+// SaveTranslation creates or replaces the translation for t's entity, field
+// and locale
+func (s *TranslationService) SaveTranslation(ctx context.Context, t *models.Translation) error {
+	if err := s.validate(ctx, t); err != nil {
+		return err
+	}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "entity"}, {Name: "entity_id"}, {Name: "field"}, {Name: "locale"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"value": t.Value, "updated_at": gorm.Expr("CURRENT_TIMESTAMP")}),
+	}).Create(t).Error
+	if err != nil {
+		return err
+	}
+	return s.Reload(ctx)
+}
+
+// DeleteTranslation removes a translation, so lookups fall back again
+func (s *TranslationService) DeleteTranslation(ctx context.Context, id int) error {
+	res := s.db.WithContext(ctx).Delete(&models.Translation{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTranslationNotFound
+	}
+	return s.Reload(ctx)
+}
+
+func (s *TranslationService) validate(ctx context.Context, t *models.Translation) error {
+	fields, ok := TranslatableFields[t.Entity]
+	if !ok {
+		return fmt.Errorf("%w: unknown entity %q", ErrInvalidTranslation, t.Entity)
+	}
+	known := false
+	for _, f := range fields {
+		if f == t.Field {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: %s has no translatable field %q", ErrInvalidTranslation, t.Entity, t.Field)
+	}
+	if t.Locale == i18n.DefaultLocale || !s.catalog.Supports(t.Locale) {
+		return fmt.Errorf("%w: unsupported locale %q", ErrInvalidTranslation, t.Locale)
+	}
+	if t.Value == "" {
+		return fmt.Errorf("%w: value is required", ErrInvalidTranslation)
+	}
+	if t.Entity == EntityNotificationTemplate {
+		if !s.templates.Has(t.EntityID) {
+			return fmt.Errorf("%w: unknown notification template %q", ErrInvalidTranslation, t.EntityID)
+		}
+		return validateTemplateSource(t.Field, t.Value)
+	}
+	table := map[string]string{EntityCategory: "categories", EntityAuthority: "state_authorities"}[t.Entity]
+	id, err := strconv.Atoi(t.EntityID)
+	if err != nil {
+		return fmt.Errorf("%w: unknown %s %q", ErrInvalidTranslation, t.Entity, t.EntityID)
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Table(table).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: unknown %s %q", ErrInvalidTranslation, t.Entity, t.EntityID)
+	}
+	return nil
+}
+
+// validateTemplateSource parses a translated notification template the way
+// it will be rendered; text templates must define the subject block
+func validateTemplateSource(field, source string) error {
+	if field == "html" {
+		if _, err := htmltemplate.New("html").Parse(source); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTranslation, err)
+		}
+		return nil
+	}
+	tmpl, err := texttemplate.New("text").Parse(source)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTranslation, err)
+	}
+	if tmpl.Lookup("subject") == nil {
+		return fmt.Errorf("%w: text template must define the subject block", ErrInvalidTranslation)
+	}
+	return nil
+}
+
+// TranslationSource is one translatable piece of English text
+type TranslationSource struct {
+	Entity   string `json:"entity"`
+	EntityID string `json:"entity_id"`
+	Field    string `json:"field"`
+	Source   string `json:"source"`
+}
+
+// LocaleCompleteness reports how much of the translatable text a locale
+// covers. Translated counts only the locale's own translations; Missing
+// text is served from the next locale in Chain.
+type LocaleCompleteness struct {
+	Locale     string              `json:"locale"`
+	Chain      []string            `json:"chain"`
+	Total      int                 `json:"total"`
+	Translated int                 `json:"translated"`
+	Percent    float64             `json:"percent"`
+	Missing    []TranslationSource `json:"missing,omitempty"`
+}
+
+// Completeness reports every supported locale except English, or only
+// locale when set, in which case the missing texts are listed too
+func (s *TranslationService) Completeness(ctx context.Context, locale string) ([]LocaleCompleteness, error) {
+	sources, err := s.sources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []LocaleCompleteness
+	for _, l := range s.catalog.Locales() {
+		if l == i18n.DefaultLocale || (locale != "" && l != locale) {
+			continue
+		}
+		c := LocaleCompleteness{Locale: l, Chain: s.catalog.Chain(l), Total: len(sources)}
+		for _, src := range sources {
+			if _, ok := s.Get(src.Entity, src.EntityID, src.Field, l); ok {
+				c.Translated++
+			} else if locale != "" {
+				c.Missing = append(c.Missing, src)
+			}
+		}
+		if c.Total > 0 {
+			c.Percent = float64(c.Translated) * 100 / float64(c.Total)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// sources lists the English text that should be translated: active
+// categories and authorities, and every notification template
+func (s *TranslationService) sources(ctx context.Context) ([]TranslationSource, error) {
+	var out []TranslationSource
+	var categories []models.Category
+	if err := s.db.WithContext(ctx).Where("is_active = TRUE").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		id := strconv.Itoa(c.ID)
+		out = append(out, TranslationSource{EntityCategory, id, "name", c.Name})
+		if c.Description != nil && *c.Description != "" {
+			out = append(out, TranslationSource{EntityCategory, id, "description", *c.Description})
+		}
+	}
+	var authorities []models.StateAuthority
+	if err := s.db.WithContext(ctx).Table("state_authorities").Where("is_active = TRUE").Order("id").Find(&authorities).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range authorities {
+		out = append(out, TranslationSource{EntityAuthority, strconv.Itoa(a.ID), "authority_name", a.AuthorityName})
+	}
+	for _, event := range s.templates.Events() {
+		for _, field := range TranslatableFields[EntityNotificationTemplate] {
+			if src, ok := s.templates.Source(event, field); ok {
+				out = append(out, TranslationSource{EntityNotificationTemplate, event, field, src})
+			}
+		}
+	}
+	return out, nil
+}
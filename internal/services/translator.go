@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrUnsupportedLanguagePair tells the caller a translator cannot translate
+// between two languages; the text is left untranslated.
+var ErrUnsupportedLanguagePair = errors.New("unsupported language pair")
+
+// Translator turns text from one language into another. Languages are codes
+// such as "hi" and "en", as returned by utils.DetectLanguage.
+type Translator interface {
+	// Name identifies the engine on stored translations
+	Name() string
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// DictionaryTranslator translates word by word from glossaries, one JSON
+// file per language pair named <from>-<to>.json mapping lower-case words and
+// short phrases to their translation. It needs no network and is
+// deterministic, which suits development and tests, but its output is only
+// a gloss: words missing from the glossary are kept as written, and a word
+// mapped to "" is dropped.
+type DictionaryTranslator struct {
+	glossaries map[string]map[string]string
+	maxPhrase  int
+}
+
+// NewDictionaryTranslator builds a translator from glossaries keyed by
+// "<from>-<to>"
+func NewDictionaryTranslator(glossaries map[string]map[string]string) *DictionaryTranslator {
+	d := &DictionaryTranslator{glossaries: map[string]map[string]string{}, maxPhrase: 1}
+	for pair, glossary := range glossaries {
+		entries := make(map[string]string, len(glossary))
+		for phrase, translation := range glossary {
+			phrase = strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+			entries[phrase] = translation
+			if n := len(strings.Fields(phrase)); n > d.maxPhrase {
+				d.maxPhrase = n
+			}
+		}
+		d.glossaries[pair] = entries
+	}
+	return d
+}
+
+// LoadDictionaryTranslator reads every <from>-<to>.json glossary in dir
+func LoadDictionaryTranslator(dir string) (*DictionaryTranslator, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	glossaries := map[string]map[string]string{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var glossary map[string]string
+		if err := json.Unmarshal(data, &glossary); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		glossaries[strings.TrimSuffix(filepath.Base(f), ".json")] = glossary
+	}
+	return NewDictionaryTranslator(glossaries), nil
+}
+
+func (d *DictionaryTranslator) Name() string {
+	return "dictionary"
+}
+
+// Translate replaces the longest glossary phrase at each word, keeping
+// punctuation and spacing as written
+func (d *DictionaryTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	glossary, ok := d.glossaries[from+"-"+to]
+	if !ok {
+		return "", fmt.Errorf("%w: %s to %s", ErrUnsupportedLanguagePair, from, to)
+	}
+	tokens := splitWords(text)
+	var out strings.Builder
+	for i := 0; i < len(tokens); {
+		if !tokens[i].word {
+			out.WriteString(tokens[i].text)
+			i++
+			continue
+		}
+		next := i + 1
+		translation := tokens[i].text
+		for n := d.maxPhrase; n >= 1; n-- {
+			phrase, end, ok := phraseAt(tokens, i, n)
+			if !ok {
+				continue
+			}
+			if t, ok := glossary[strings.ToLower(phrase)]; ok {
+				translation, next = t, end
+				break
+			}
+		}
+		out.WriteString(translation)
+		i = next
+		// Words that translate to nothing, such as English articles, take
+		// their following space with them
+		if translation == "" && i < len(tokens) && strings.TrimSpace(tokens[i].text) == "" {
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+type wordToken struct {
+	text string
+	word bool
+}
+
+// splitWords cuts text into runs of word characters and runs of everything
+// else. Combining marks count as word characters, so Devanagari vowel signs
+// stay with their letters.
+func splitWords(text string) []wordToken {
+	var tokens []wordToken
+	var cur strings.Builder
+	inWord := false
+	for _, r := range text {
+		w := unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+		if cur.Len() > 0 && w != inWord {
+			tokens = append(tokens, wordToken{cur.String(), inWord})
+			cur.Reset()
+		}
+		inWord = w
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, wordToken{cur.String(), inWord})
+	}
+	return tokens
+}
+
+// phraseAt joins the n words starting at tokens[i] with single spaces, as
+// long as only whitespace separates them, and returns the index after the
+// last one
+func phraseAt(tokens []wordToken, i, n int) (string, int, bool) {
+	words := []string{tokens[i].text}
+	j := i + 1
+	for len(words) < n {
+		if j+1 >= len(tokens) || strings.TrimSpace(tokens[j].text) != "" {
+			return "", 0, false
+		}
+		words = append(words, tokens[j+1].text)
+		j += 2
+	}
+	return strings.Join(words, " "), j, true
+}
+
+// LibreTranslator calls a LibreTranslate compatible HTTP API, self-hosted or
+// hosted (https://libretranslate.com). Other engines plug in the same way by
+// implementing Translator.
+type LibreTranslator struct {
+	URL    string
+	APIKey string
+	client *http.Client
+}
+
+func NewLibreTranslator(url, apiKey string) *LibreTranslator {
+	return &LibreTranslator{
+		URL:    strings.TrimRight(url, "/"),
+		APIKey: apiKey,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (l *LibreTranslator) Name() string {
+	return "libretranslate"
+}
+
+func (l *LibreTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"q":       text,
+		"source":  from,
+		"target":  to,
+		"format":  "text",
+		"api_key": l.APIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+		Error          string `json:"error"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("libretranslate returned %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("libretranslate returned %s: %s", resp.Status, result.Error)
+	}
+	return result.TranslatedText, nil
+}
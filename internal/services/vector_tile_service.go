@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	reportsLayer = "reports"
+	// mvtBufferPx includes points just outside the tile so symbols on the
+	// edge aren't clipped; it must stay within tileCacheBufferPx
+	mvtBufferPx = 8
+	// mvtDetailZoom is the first zoom at which every report is its own
+	// feature
+	mvtDetailZoom = 12
+)
+
+// mvtClusterCellPx is the grid reports are merged on below mvtDetailZoom.
+// Coarser zooms merge harder.
+func mvtClusterCellPx(z int) float64 {
+	switch {
+	case z <= 5:
+		return 16
+	case z <= 8:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// VectorTileService renders reports as Mapbox Vector Tiles
+type VectorTileService struct {
+	db    *gorm.DB
+	cache *TileCache
+}
+
+func NewVectorTileService(db *gorm.DB, cache *TileCache) *VectorTileService {
+	return &VectorTileService{db: db, cache: cache}
+}
+
+// ReportsTile returns tile z/x/y with a single "reports" point layer. From
+// mvtDetailZoom on, every live report is a feature with id, category and
+// status. Below that, reports of the same category and status are merged
+// per grid cell into one feature at their average position with a count;
+// a cell holding a single report keeps its id.
+func (s *VectorTileService) ReportsTile(ctx context.Context, z, x, y int) ([]byte, error) {
+	const variant = "reports.mvt"
+	if data, ok := s.cache.Get(reportsLayer, z, x, y, variant); ok {
+		return data, nil
+	}
+
+	minLat, minLng, maxLat, maxLng := utils.TileBounds(z, x, y, mvtBufferPx)
+	q := applyReportFilter(s.db.WithContext(ctx).Model(&models.Report{}), ReportFilter{
+		BBox: &BBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng},
+	})
+	args := tilePixelArgs(z, x, y)
+
+	var rows []struct {
+		ID       int
+		Category string
+		Status   string
+		Count    int
+		PX       float64 `gorm:"column:px"`
+		PY       float64 `gorm:"column:py"`
+	}
+	var err error
+	if z >= mvtDetailZoom {
+		err = q.Select("id, category, status, 1 AS count, "+tilePixelX+" AS px, "+tilePixelY+" AS py", args).
+			Order("id").Scan(&rows).Error
+	} else {
+		args["cell"] = mvtClusterCellPx(z)
+		err = q.Select("floor("+tilePixelX+" / @cell)::int AS cx, floor("+tilePixelY+" / @cell)::int AS cy, "+
+			"MIN(id) AS id, category, status, COUNT(*) AS count, "+
+			"AVG("+tilePixelX+") AS px, AVG("+tilePixelY+") AS py", args).
+			Group("cx, cy, category, status").
+			Order("id").Scan(&rows).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	layer := utils.NewMVTLayer(reportsLayer, utils.MVTExtent)
+	const scale = utils.MVTExtent / utils.TileSize
+	for _, r := range rows {
+		px, py := int(math.Round(r.PX*scale)), int(math.Round(r.PY*scale))
+		props := []utils.MVTProp{
+			{Key: "category", Value: r.Category},
+			{Key: "status", Value: r.Status},
+			{Key: "count", Value: r.Count},
+		}
+		var id uint64
+		if r.Count == 1 {
+			id = uint64(r.ID)
+			props = append(props, utils.MVTProp{Key: "id", Value: r.ID})
+		}
+		if err := layer.AddPoint(id, px, py, props); err != nil {
+			return nil, err
+		}
+	}
+	data := utils.EncodeMVT(layer)
+
+	if err := s.cache.Put(reportsLayer, z, x, y, variant, data); err != nil {
+		utils.Error("vector tiles: failed to cache tile %d/%d/%d: %v", z, x, y, err)
+	}
+	return data, nil
+}
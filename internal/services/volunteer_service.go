@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Limits for batch uploads
+const (
+	MaxBatchReports = 500
+
+	// capturedAtSkew tolerates volunteers' device clocks running ahead
+	capturedAtSkew = 10 * time.Minute
+)
+
+var (
+	ErrVolunteerKeyNotFound = errors.New("volunteer key not found")
+	ErrInvalidVolunteerKey  = errors.New("invalid volunteer key")
+)
+
+// VolunteerService manages volunteer API keys and the reports they upload
+// in batches
+type VolunteerService struct {
+	db      *gorm.DB
+	reports *ReportService
+	images  *ImageService
+}
+
+func NewVolunteerService(db *gorm.DB, reports *ReportService, images *ImageService) *VolunteerService {
+	return &VolunteerService{db: db, reports: reports, images: images}
+}
+
+// CreateKey issues an API key to a volunteer. The key is returned once;
+// only its hash is stored.
+func (s *VolunteerService) CreateKey(ctx context.Context, name, organization string) (*models.VolunteerKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidVolunteerKey)
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &models.VolunteerKey{
+		Name:         name,
+		Organization: strings.TrimSpace(organization),
+		KeyHash:      utils.HashToken(token),
+		IsActive:     true,
+	}
+	if err := s.db.WithContext(ctx).Create(key).Error; err != nil {
+		return nil, "", err
+	}
+	return key, token, nil
+}
+
+// ListKeys returns every key, newest first, with the number of reports
+// uploaded with it
+func (s *VolunteerService) ListKeys(ctx context.Context) ([]models.VolunteerKey, error) {
+	var keys []models.VolunteerKey
+	err := s.db.WithContext(ctx).
+		Select("volunteer_keys.*, (SELECT COUNT(*) FROM reports WHERE reports.volunteer_key_id = volunteer_keys.id) AS report_count").
+		Order("created_at DESC, id DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// RevokeKey disables a key for good. Reports already uploaded with it keep
+// their attribution.
+func (s *VolunteerService) RevokeKey(ctx context.Context, id int) error {
+	var key models.VolunteerKey
+	if err := s.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVolunteerKeyNotFound
+		}
+		return err
+	}
+	if !key.IsActive {
+		return nil
+	}
+	return s.db.WithContext(ctx).Model(&key).Updates(map[string]interface{}{"is_active": false, "revoked_at": time.Now()}).Error
+}
+
+// Authenticate returns the active key matching token, or
+// ErrVolunteerKeyNotFound, and records its use
+func (s *VolunteerService) Authenticate(ctx context.Context, token string) (*models.VolunteerKey, error) {
+	if token == "" {
+		return nil, ErrVolunteerKeyNotFound
+	}
+	var key models.VolunteerKey
+	err := s.db.WithContext(ctx).Where("key_hash = ? AND is_active = TRUE", utils.HashToken(token)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVolunteerKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(&key).Update("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ValidateCapturedAt checks the device timestamp of a surveyed report
+func ValidateCapturedAt(capturedAt time.Time) error {
+	if capturedAt.After(time.Now().Add(capturedAtSkew)) {
+		return fmt.Errorf("%w: captured_at is in the future", ErrInvalidReport)
+	}
+	return nil
+}
+
+// SubmitReport creates one report of a batch together with its photos, in
+// a single transaction, attributed to the volunteer's key. The report must
+// have passed ValidateNewReport. With a submissionID the upload can be
+// retried: a report already received from the same key is returned
+// instead, without an edit token, and created is false. A submissionID
+// used by anyone else fails with ErrSubmissionConflict.
+func (s *VolunteerService) SubmitReport(ctx context.Context, key *models.VolunteerKey, report *models.Report, submissionID string, images []UploadedImage) (editToken string, created bool, err error) {
+	report.VolunteerKeyID = &key.ID
+	var saved []models.Image
+	storeImages := func(tx *gorm.DB) error {
+		var err error
+		saved, err = s.images.StoreImages(ctx, tx, report.ID, nil, models.ImageTypeReport, images)
+		report.Images = saved
+		return err
+	}
+	if submissionID == "" {
+		editToken, err = s.reports.createReport(ctx, report, storeImages)
+		created = err == nil
+	} else {
+		editToken, created, err = s.reports.createSubmittedReport(ctx, report, submissionID, storeImages)
+	}
+	// Photos stored in a transaction that did not commit, such as one that
+	// lost a race for the submission ID, have no rows
+	if !created {
+		s.images.RemoveImages(ctx, saved)
+	}
+	return editToken, created, err
+}
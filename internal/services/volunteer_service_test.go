@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/projects-for-public/help-govern/internal/config"
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+// memoryImageStore keeps images in memory
+type memoryImageStore struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	next  int
+}
+
+func (m *memoryImageStore) Save(ctx context.Context, data []byte, contentType string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	id := fmt.Sprintf("img-%d", m.next)
+	m.files[id] = data
+	return "/static/uploads/" + id, id, nil
+}
+
+func (m *memoryImageStore) Delete(ctx context.Context, publicID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, publicID)
+	return nil
+}
+
+func newTestVolunteerService(t *testing.T) (*VolunteerService, *fakedb.DB, *memoryImageStore) {
+	t.Helper()
+	db, fake := fakedb.New(t)
+	store := &memoryImageStore{files: map[string][]byte{}}
+	events := NewEventBus()
+	reports := NewReportService(db, &config.Config{}, events)
+	return NewVolunteerService(db, reports, NewImageService(db, store, events)), fake, store
+}
+
+func volunteerPhotos() []UploadedImage {
+	return []UploadedImage{{Data: []byte("photo one"), ContentType: "image/jpeg"}, {Data: []byte("photo two"), ContentType: "image/jpeg"}}
+}
+
+func TestSubmitReportReplaysOwnSubmission(t *testing.T) {
+	s, fake, store := newTestVolunteerService(t)
+	row := submittedReportRow()
+	fake.SetRows("reports", append(submittedReportColumns, "volunteer_key_id"), append(row, int64(3)))
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	editToken, created, err := s.SubmitReport(context.Background(), &models.VolunteerKey{ID: 3}, &report, draftSubmissionID, volunteerPhotos())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || editToken != "" || report.ID != 12 {
+		t.Errorf("got created=%v, token %q, report %d; want report 12 as a replay", created, editToken, report.ID)
+	}
+	if len(store.files) != 0 {
+		t.Errorf("replay stored %d photos", len(store.files))
+	}
+}
+
+func TestSubmitReportRejectsAnotherKeysSubmission(t *testing.T) {
+	for name, keyID := range map[string]driver.Value{"other key": int64(4), "anonymous draft": nil} {
+		t.Run(name, func(t *testing.T) {
+			s, fake, _ := newTestVolunteerService(t)
+			fake.SetRows("reports", append(submittedReportColumns, "volunteer_key_id"), append(submittedReportRow(), keyID))
+
+			report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+			_, created, err := s.SubmitReport(context.Background(), &models.VolunteerKey{ID: 3}, &report, draftSubmissionID, nil)
+			if !errors.Is(err, ErrSubmissionConflict) || created {
+				t.Errorf("got created=%v, err %v; want ErrSubmissionConflict", created, err)
+			}
+			if report.ID == 12 {
+				t.Error("another key's report was returned")
+			}
+		})
+	}
+}
+
+func TestSubmitReportRemovesPhotosWhenCommitFails(t *testing.T) {
+	s, fake, store := newTestVolunteerService(t)
+	fake.SetRows("reports", submittedReportColumns)
+	fake.Fail("COMMIT", errors.New("connection reset"))
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	_, created, err := s.SubmitReport(context.Background(), &models.VolunteerKey{ID: 3}, &report, draftSubmissionID, volunteerPhotos())
+	if err == nil || created {
+		t.Fatalf("got created=%v, err %v; want the commit error", created, err)
+	}
+	if store.next != 2 {
+		t.Fatalf("%d photos saved, want both saved before the commit", store.next)
+	}
+	if len(store.files) != 0 {
+		t.Errorf("%d photo files left behind by the failed commit", len(store.files))
+	}
+}
+
+func TestSubmitReportKeepsPhotosOnSuccess(t *testing.T) {
+	s, fake, store := newTestVolunteerService(t)
+	fake.SetRows("reports", submittedReportColumns)
+
+	report := models.Report{Category: "potholes", Latitude: 26.9, Longitude: 75.8}
+	editToken, created, err := s.SubmitReport(context.Background(), &models.VolunteerKey{ID: 3}, &report, draftSubmissionID, volunteerPhotos())
+	if err != nil || !created || editToken == "" {
+		t.Fatalf("got created=%v, token %q, err %v", created, editToken, err)
+	}
+	if len(store.files) != 2 || len(report.Images) != 2 {
+		t.Errorf("%d files and %d images stored, want 2", len(store.files), len(report.Images))
+	}
+}
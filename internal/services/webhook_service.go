@@ -0,0 +1,544 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/projects-for-public/help-govern/internal/models"
+	"github.com/projects-for-public/help-govern/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Delivery tuning: a failed delivery is retried with exponential backoff
+// (2, 4, 8 ... minutes) up to maxWebhookAttempts. A webhook is disabled after
+// webhookDisableThreshold failed attempts in a row across all its deliveries.
+const (
+	maxWebhookAttempts      = 8
+	webhookDisableThreshold = 20
+	webhookBatchSize        = 20
+	webhookTimeout          = 10 * time.Second
+	webhookResponseLimit    = 1024
+
+	// webhookLease is how long a claimed batch has to be sent before another
+	// worker may claim it again; enough for every delivery to time out
+	webhookLease = webhookBatchSize * webhookTimeout * 3 / 2
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookDisabled  = errors.New("webhook is disabled")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+)
+
+// WebhookEventTypes are the events partners can subscribe to
+var WebhookEventTypes = []string{
+	EventReportCreated,
+	EventReportStatusChanged,
+	EventReportMerged,
+	EventReportDeleted,
+	EventImageApproved,
+	EventImageRejected,
+}
+
+// WebhookPayload is the JSON body POSTed to webhook endpoints
+type WebhookPayload struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       WebhookData `json:"data"`
+}
+
+// WebhookData carries the objects relevant to the event type
+type WebhookData struct {
+	Report       *WebhookReport       `json:"report,omitempty"`
+	StatusUpdate *models.StatusUpdate `json:"status_update,omitempty"`
+	Image        *models.Image        `json:"image,omitempty"`
+	MergedID     int                  `json:"merged_id,omitempty"`
+}
+
+// WebhookReport is the public view of a report sent to partners; moderator
+// notes and reporter details are left out.
+type WebhookReport struct {
+	ID                int        `json:"id"`
+	Category          string     `json:"category"`
+	Latitude          float64    `json:"latitude"`
+	Longitude         float64    `json:"longitude"`
+	Description       string     `json:"description"`
+	Status            string     `json:"status"`
+	State             *string    `json:"state,omitempty"`
+	City              *string    `json:"city,omitempty"`
+	ShareURL          string     `json:"share_url"`
+	ConfirmationCount int        `json:"confirmation_count"`
+	DuplicateCount    int        `json:"duplicate_count"`
+	Priority          int        `json:"priority"`
+	MergedIntoID      *int       `json:"merged_into_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+}
+
+// WebhookService manages partner webhooks and delivers events to them
+type WebhookService struct {
+	db      *gorm.DB
+	client  *http.Client
+	baseURL string
+}
+
+func NewWebhookService(db *gorm.DB, publicBaseURL string) *WebhookService {
+	return &WebhookService{
+		db:      db,
+		client:  &http.Client{Timeout: webhookTimeout},
+		baseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// WebhookInput holds the admin-editable fields of a webhook. Nil fields are
+// left unchanged on update.
+type WebhookInput struct {
+	URL          *string
+	Description  *string
+	EventTypes   []string
+	IsActive     *bool
+	RotateSecret bool
+}
+
+func validateEventTypes(types []string) error {
+	for _, t := range types {
+		known := false
+		for _, k := range WebhookEventTypes {
+			if t == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, t)
+		}
+	}
+	return nil
+}
+
+func validateWebhookURL(url *string) error {
+	if url == nil || *url == "" {
+		return fmt.Errorf("%w: url is required", ErrInvalidWebhook)
+	}
+	if !strings.HasPrefix(*url, "https://") && !strings.HasPrefix(*url, "http://") {
+		return fmt.Errorf("%w: url must use http or https", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// CreateWebhook registers an endpoint with a fresh signing secret
+func (s *WebhookService) CreateWebhook(ctx context.Context, in WebhookInput) (*models.Webhook, error) {
+	if err := validateWebhookURL(in.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(in.EventTypes); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	w := &models.Webhook{
+		URL:        *in.URL,
+		Secret:     secret,
+		EventTypes: pq.StringArray(in.EventTypes),
+		IsActive:   true,
+	}
+	if in.Description != nil {
+		w.Description = *in.Description
+	}
+	if err := s.db.WithContext(ctx).Create(w).Error; err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListWebhooks returns every webhook, newest first
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := s.db.WithContext(ctx).Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id int) (*models.Webhook, error) {
+	var w models.Webhook
+	if err := s.db.WithContext(ctx).First(&w, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// UpdateWebhook edits a webhook. Re-enabling a disabled webhook clears its
+// failure count.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id int, in WebhookInput) (*models.Webhook, error) {
+	w, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if in.URL != nil {
+		if err := validateWebhookURL(in.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *in.URL
+	}
+	if in.Description != nil {
+		updates["description"] = *in.Description
+	}
+	if in.EventTypes != nil {
+		if err := validateEventTypes(in.EventTypes); err != nil {
+			return nil, err
+		}
+		updates["event_types"] = pq.StringArray(in.EventTypes)
+	}
+	if in.IsActive != nil {
+		updates["is_active"] = *in.IsActive
+		if *in.IsActive && !w.IsActive {
+			updates["consecutive_failures"] = 0
+			updates["disabled_at"] = nil
+		}
+	}
+	if in.RotateSecret {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		updates["secret"] = secret
+	}
+	if len(updates) > 0 {
+		if err := s.db.WithContext(ctx).Model(w).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.GetWebhook(ctx, id)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int) error {
+	res := s.db.WithContext(ctx).Delete(&models.Webhook{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a webhook
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	var deliveries []models.WebhookDelivery
+	err := s.db.WithContext(ctx).Where("webhook_id = ?", webhookID).
+		Order("created_at DESC, id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Redeliver queues a fresh copy of a past delivery. The copy keeps the
+// event ID so receivers can deduplicate.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID int) (*models.WebhookDelivery, error) {
+	w, err := s.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !w.IsActive {
+		return nil, ErrWebhookDisabled
+	}
+	var original models.WebhookDelivery
+	err = s.db.WithContext(ctx).Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &models.WebhookDelivery{
+		WebhookID:     w.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// HandleEvent is subscribed to the EventBus. It queues a delivery for every
+// active webhook that wants the event.
+func (s *WebhookService) HandleEvent(ctx context.Context, e Event) {
+	var webhooks []models.Webhook
+	if err := s.db.WithContext(ctx).Where("is_active = TRUE").Find(&webhooks).Error; err != nil {
+		utils.Error("failed to load webhooks for %s: %v", e.Type, err)
+		return
+	}
+	var targets []models.Webhook
+	for _, w := range webhooks {
+		if w.Wants(e.Type) {
+			targets = append(targets, w)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	eventID, err := utils.RandomToken(16)
+	if err != nil {
+		utils.Error("failed to create event ID for %s: %v", e.Type, err)
+		return
+	}
+	payload, err := json.Marshal(s.payload(eventID, e))
+	if err != nil {
+		utils.Error("failed to encode %s webhook payload: %v", e.Type, err)
+		return
+	}
+	deliveries := make([]models.WebhookDelivery, 0, len(targets))
+	for _, w := range targets {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       eventID,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			Status:        "pending",
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if err := s.db.WithContext(ctx).Create(&deliveries).Error; err != nil {
+		utils.Error("failed to queue %s webhook deliveries: %v", e.Type, err)
+	}
+}
+
+func (s *WebhookService) payload(eventID string, e Event) WebhookPayload {
+	p := WebhookPayload{
+		ID:         eventID,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		Data: WebhookData{
+			StatusUpdate: e.StatusUpdate,
+			Image:        e.Image,
+			MergedID:     e.MergedID,
+		},
+	}
+	if r := e.Report; r != nil {
+		p.Data.Report = &WebhookReport{
+			ID:                r.ID,
+			Category:          r.Category,
+			Latitude:          r.Latitude,
+			Longitude:         r.Longitude,
+			Description:       r.Description,
+			Status:            r.Status,
+			State:             r.State,
+			City:              r.City,
+			ShareURL:          r.GenerateShareURL(s.baseURL),
+			ConfirmationCount: r.ConfirmationCount,
+			DuplicateCount:    r.DuplicateCount,
+			Priority:          r.Priority(),
+			MergedIntoID:      r.MergedIntoID,
+			CreatedAt:         r.CreatedAt,
+			VerifiedAt:        r.VerifiedAt,
+			StartedAt:         r.StartedAt,
+			ResolvedAt:        r.ResolvedAt,
+		}
+	}
+	return p
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature header value:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Receivers should
+// recompute it with their secret and reject old timestamps.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ProcessQueue delivers due webhook deliveries. A batch is claimed first and
+// then sent outside of any transaction, so slow endpoints hold no locks.
+func (s *WebhookService) ProcessQueue(ctx context.Context) (int, error) {
+	due, err := s.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var processed int
+	for i := range due {
+		if err := s.deliver(ctx, &due[i]); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// claim leases a batch of due deliveries to this worker by marking them
+// sending until webhookLease has passed. Rows are locked with SKIP LOCKED so
+// several workers can share the queue; a worker that dies leaves its batch
+// to be claimed again once the lease runs out.
+func (s *WebhookService) claim(ctx context.Context) ([]models.WebhookDelivery, error) {
+	now := time.Now()
+	var due []models.WebhookDelivery
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{"pending", "sending"}, now).
+			Order("next_attempt_at").Limit(webhookBatchSize).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		ids := make([]int, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+			due[i].Attempts++
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          "sending",
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(webhookLease),
+		}).Error
+	})
+	return due, err
+}
+
+// deliver makes one attempt at a claimed delivery and records the outcome on
+// the delivery and its webhook
+func (s *WebhookService) deliver(ctx context.Context, d *models.WebhookDelivery) error {
+	var w models.Webhook
+	if err := s.db.WithContext(ctx).First(&w, d.WebhookID).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	if !w.IsActive {
+		return s.db.WithContext(ctx).Model(d).Updates(map[string]interface{}{
+			"status":     "failed",
+			"last_error": ErrWebhookDisabled.Error(),
+		}).Error
+	}
+
+	updates := map[string]interface{}{}
+	status, body, sendErr := s.send(ctx, &w, d, now)
+	if status != 0 {
+		updates["response_status"] = status
+		updates["response_body"] = body
+	}
+	if sendErr == nil {
+		updates["status"] = "succeeded"
+		updates["delivered_at"] = now
+		updates["last_error"] = nil
+		if err := s.db.WithContext(ctx).Model(d).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.db.WithContext(ctx).Model(&models.Webhook{}).
+			Where("id = ? AND consecutive_failures > 0", w.ID).
+			Update("consecutive_failures", 0).Error
+	}
+
+	updates["last_error"] = sendErr.Error()
+	if d.Attempts >= maxWebhookAttempts {
+		utils.Error("webhook delivery %d failed permanently: %v", d.ID, sendErr)
+		updates["status"] = "failed"
+	} else {
+		utils.Error("webhook delivery %d failed (attempt %d), will retry: %v", d.ID, d.Attempts, sendErr)
+		updates["status"] = "pending"
+		updates["next_attempt_at"] = now.Add(time.Minute << uint(d.Attempts))
+	}
+	if err := s.db.WithContext(ctx).Model(d).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// Counted in the database, as other workers may be failing the same
+	// webhook at the same time
+	var counted models.Webhook
+	err := s.db.WithContext(ctx).Raw(`UPDATE webhooks SET
+			consecutive_failures = consecutive_failures + 1,
+			is_active = is_active AND consecutive_failures + 1 < ?,
+			disabled_at = CASE WHEN is_active AND consecutive_failures + 1 >= ? THEN ? ELSE disabled_at END
+		WHERE id = ? RETURNING id, consecutive_failures, is_active`,
+		webhookDisableThreshold, webhookDisableThreshold, now, w.ID).Scan(&counted).Error
+	if err != nil {
+		return err
+	}
+	if !counted.IsActive && counted.ConsecutiveFailures == webhookDisableThreshold {
+		utils.Error("webhook %d disabled after %d consecutive failures", w.ID, counted.ConsecutiveFailures)
+	}
+	return nil
+}
+
+// send POSTs the payload. It returns the response status and a truncated
+// body when the endpoint answered; non-2xx answers are errors.
+func (s *WebhookService) send(ctx context.Context, w *models.Webhook, d *models.WebhookDelivery, now time.Time) (int, string, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "help-govern-webhooks/1")
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-ID", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(w.Secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	// Postgres text rejects invalid UTF-8 and NUL bytes
+	respBody := strings.ReplaceAll(strings.ToValidUTF8(string(raw), ""), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, respBody, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// Run processes the delivery queue every interval until ctx is cancelled
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ProcessQueue(ctx); err != nil {
+				utils.Error("webhook queue: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+)
+
+const testWebhookSecret = "whsec_3b1f0c8e2d"
+
+func TestSignWebhookPayload(t *testing.T) {
+	at := time.Unix(1751196600, 0)
+	body := []byte(`{"id":"4Qf0p3n1Yq8x7A2bZc9d1w","type":"report.created"}`)
+
+	sig := SignWebhookPayload(testWebhookSecret, at, body)
+	if !regexp.MustCompile(`^t=1751196600,v1=[0-9a-f]{64}$`).MatchString(sig) {
+		t.Fatalf("signature %q is not t=<unix>,v1=<hex>", sig)
+	}
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte("1751196600." + string(body)))
+	if want := "t=1751196600,v1=" + hex.EncodeToString(mac.Sum(nil)); sig != want {
+		t.Errorf("signature %q, want %q", sig, want)
+	}
+	if other := SignWebhookPayload("another secret", at, body); other == sig {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+// webhookQueue queues one delivery of a report.created event for webhook 4
+// at url, on its attempt-th attempt
+func webhookQueue(fake *fakedb.DB, url string, active bool, attempt int) {
+	fake.SetRows("webhook_deliveries", []string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts"},
+		[]driver.Value{int64(9), int64(4), "4Qf0p3n1Yq8x7A2bZc9d1w", EventReportCreated, `{"type":"report.created"}`, "pending", int64(attempt - 1)})
+	fake.SetRows("webhooks", []string{"id", "url", "secret", "is_active"},
+		[]driver.Value{int64(4), url, testWebhookSecret, active})
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer endpoint.Close()
+	db, fake := fakedb.New(t)
+	webhookQueue(fake, endpoint.URL, true, 1)
+	s := NewWebhookService(db, "https://helpgovern.example")
+
+	if n, err := s.ProcessQueue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("processed %d deliveries: %v", n, err)
+	}
+	if got == nil {
+		t.Fatal("endpoint was not called")
+	}
+	if got.Header.Get("X-Webhook-Event") != EventReportCreated || got.Header.Get("X-Webhook-Delivery") != "9" {
+		t.Errorf("headers %v", got.Header)
+	}
+	sig := got.Header.Get("X-Webhook-Signature")
+	ts, err := strconv.ParseInt(strings.TrimPrefix(strings.SplitN(sig, ",", 2)[0], "t="), 10, 64)
+	if err != nil {
+		t.Fatalf("signature %q has no timestamp: %v", sig, err)
+	}
+	if want := SignWebhookPayload(testWebhookSecret, time.Unix(ts, 0), body); sig != want {
+		t.Errorf("signature %q does not verify with the webhook secret, want %q", sig, want)
+	}
+	if resets := fake.Executed(`UPDATE "webhooks" SET "consecutive_failures"=$1`); len(resets) != 1 {
+		t.Errorf("a success should reset the failure count: %v", resets)
+	}
+}
+
+func TestWebhookFailureCountsTowardsDisabling(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer endpoint.Close()
+	db, fake := fakedb.New(t)
+	webhookQueue(fake, endpoint.URL, true, 3)
+	s := NewWebhookService(db, "https://helpgovern.example")
+
+	if _, err := s.ProcessQueue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	counted := fake.Queried("UPDATE webhooks SET")
+	if len(counted) != 1 {
+		t.Fatalf("failure not counted on the webhook: %v", counted)
+	}
+	args := counted[0].Args
+	// is_active turns false, and disabled_at is set, on the 20th failure in a row
+	if len(args) != 4 || args[0] != int64(20) || args[1] != int64(20) || args[3] != int64(4) {
+		t.Errorf("counting args %v, want the threshold 20 twice and webhook 4", args)
+	}
+	retry := fake.Executed(`UPDATE "webhook_deliveries" SET "last_error"=$1,"next_attempt_at"=$2,"response_body"=$3,"response_status"=$4,"status"=$5`)
+	if len(retry) != 1 || retry[0].Args[4] != "pending" {
+		t.Errorf("delivery not rescheduled: %v", retry)
+	}
+	if at, ok := retry[0].Args[1].(time.Time); !ok || time.Until(at) < 7*time.Minute || time.Until(at) > 8*time.Minute {
+		t.Errorf("third attempt retried at %v, want in 8 minutes", retry[0].Args[1])
+	}
+}
+
+func TestDisabledWebhookIsNotCalled(t *testing.T) {
+	called := false
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer endpoint.Close()
+	db, fake := fakedb.New(t)
+	webhookQueue(fake, endpoint.URL, false, 1)
+	s := NewWebhookService(db, "https://helpgovern.example")
+
+	if _, err := s.ProcessQueue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("a disabled webhook was called")
+	}
+	failed := fake.Executed(`UPDATE "webhook_deliveries" SET "last_error"=$1,"status"=$2`)
+	if len(failed) != 1 || failed[0].Args[0] != ErrWebhookDisabled.Error() || failed[0].Args[1] != "failed" {
+		t.Errorf("delivery not failed: %v", failed)
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/models"
+	"gorm.io/gorm"
+)
+
+// Web Push (RFC 8030) with message encryption (RFC 8291, aes128gcm content
+// coding from RFC 8188) and VAPID authentication (RFC 8292).
+
+const (
+	pushRecordSize = 4096
+	// MaxPushPayload is what fits in a single aes128gcm record after the
+	// padding delimiter and the 16-byte GCM tag
+	MaxPushPayload = pushRecordSize - 86 - 17
+	pushTTL        = 24 * time.Hour
+)
+
+var b64 = base64.RawURLEncoding
+
+// VAPIDKeys is the application server's P-256 key pair. Keys are exchanged as
+// unpadded base64url: the public key as an uncompressed point (65 bytes), the
+// private key as the raw scalar (32 bytes).
+type VAPIDKeys struct {
+	PublicKey  string
+	PrivateKey string
+
+	private *ecdh.PrivateKey
+}
+
+// GenerateVAPIDKeys creates a new key pair
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{
+		PublicKey:  b64.EncodeToString(priv.PublicKey().Bytes()),
+		PrivateKey: b64.EncodeToString(priv.Bytes()),
+		private:    priv,
+	}, nil
+}
+
+// ParseVAPIDKeys loads a key pair from configuration and checks that the
+// public key matches the private key
+func ParseVAPIDKeys(publicKey, privateKey string) (*VAPIDKeys, error) {
+	raw, err := b64.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode VAPID private key: %w", err)
+	}
+	priv, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse VAPID private key: %w", err)
+	}
+	derived := b64.EncodeToString(priv.PublicKey().Bytes())
+	if publicKey != derived {
+		return nil, errors.New("VAPID public key does not match private key")
+	}
+	return &VAPIDKeys{PublicKey: publicKey, PrivateKey: privateKey, private: priv}, nil
+}
+
+// authorization builds the "vapid t=..., k=..." header for the push service
+// that owns endpoint
+func (k *VAPIDKeys) authorization(endpoint, subject string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + b64.EncodeToString(claims)
+
+	pub := k.private.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(k.private.Bytes()),
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS ES256 signatures are the fixed-width concatenation r || s
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return "vapid t=" + signingInput + "." + b64.EncodeToString(sig) + ", k=" + k.PublicKey, nil
+}
+
+// hkdf implements RFC 5869 extract-and-expand for outputs up to one hash block
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
+
+// decodeKey accepts both padded and unpadded base64url, as browsers differ
+func decodeKey(s string) ([]byte, error) {
+	if b, err := b64.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// encryptPushPayload encrypts plaintext for the user agent identified by its
+// p256dh public key and auth secret (RFC 8291 section 3.4)
+func encryptPushPayload(plaintext []byte, p256dh, authSecret string) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return sealPushPayload(plaintext, p256dh, authSecret, asPrivate, salt)
+}
+
+// sealPushPayload is encryptPushPayload with the application server's
+// ephemeral key and the salt given
+func sealPushPayload(plaintext []byte, p256dh, authSecret string, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > MaxPushPayload {
+		return nil, fmt.Errorf("push payload too large: %d bytes", len(plaintext))
+	}
+	uaPublicRaw, err := decodeKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("decode p256dh: %w", err)
+	}
+	auth, err := decodeKey(authSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decode auth: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicRaw)
+	if err != nil {
+		return nil, fmt.Errorf("parse p256dh: %w", err)
+	}
+
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// key_info = "WebPush: info" || 0x00 || ua_public || as_public
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicRaw...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdf(auth, sharedSecret, keyInfo, 32)
+
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// A single record, terminated by the 0x02 last-record delimiter
+	record := append(append([]byte{}, plaintext...), 0x02)
+	ciphertext := gcm.Seal(nil, nonce, record, nil)
+
+	// Header: salt (16) || rs (4) || idlen (1) || keyid (as_public)
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(pushRecordSize))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	body.Write(ciphertext)
+	return body.Bytes(), nil
+}
+
+// PushMessage is the JSON payload the service worker receives
+type PushMessage struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	URL      string `json:"url"`
+	ReportID int    `json:"report_id"`
+	Status   string `json:"status,omitempty"`
+}
+
+// WebPushNotifier delivers notifications to browser push subscriptions.
+// The notification body is the JSON PushMessage; the subscription's keys are
+// looked up from the database.
+type WebPushNotifier struct {
+	db      *gorm.DB
+	keys    *VAPIDKeys
+	subject string
+	client  *http.Client
+}
+
+func NewWebPushNotifier(db *gorm.DB, keys *VAPIDKeys, subject string) *WebPushNotifier {
+	return &WebPushNotifier{
+		db:      db,
+		keys:    keys,
+		subject: subject,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (w *WebPushNotifier) Send(ctx context.Context, n *models.Notification) error {
+	if n.SubscriptionID == nil {
+		return fmt.Errorf("%w: push notification without subscription", ErrRecipientGone)
+	}
+	var sub models.Subscription
+	if err := w.db.WithContext(ctx).First(&sub, *n.SubscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: subscription %d removed", ErrRecipientGone, *n.SubscriptionID)
+		}
+		return err
+	}
+	if sub.PushEndpoint == nil || sub.PushP256dh == nil || sub.PushAuth == nil {
+		return fmt.Errorf("%w: subscription %d has no push keys", ErrRecipientGone, sub.ID)
+	}
+	body, err := encryptPushPayload([]byte(n.BodyText), *sub.PushP256dh, *sub.PushAuth)
+	if err != nil {
+		return err
+	}
+	auth, err := w.keys.authorization(*sub.PushEndpoint, w.subject, time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *sub.PushEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", auth)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: push service returned %d", ErrRecipientGone, resp.StatusCode)
+	default:
+		return fmt.Errorf("push service returned %d", resp.StatusCode)
+	}
+}
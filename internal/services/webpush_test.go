@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projects-for-public/help-govern/internal/fakedb"
+	"github.com/projects-for-public/help-govern/internal/models"
+)
+
+// RFC 8291 section 5 example
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcBody      = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSealPushPayloadMatchesRFC8291Example(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := sealPushPayload([]byte(rfcPlaintext), rfcUAPublic, rfcAuth, asPrivate, mustDecode(t, rfcSalt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The example uses a single unpadded record of size 4096, as we do
+	if got := b64.EncodeToString(body); got != rfcBody {
+		t.Errorf("body = %s\nwant   %s", got, rfcBody)
+	}
+}
+
+// decryptPushPayload is the user agent side of RFC 8291, written out
+// independently of the sender
+func decryptPushPayload(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, auth []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("body of %d bytes has no header", len(body))
+	}
+	salt, rs, idlen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	if rs != pushRecordSize {
+		t.Errorf("record size = %d, want %d", rs, pushRecordSize)
+	}
+	asPublicRaw := body[21 : 21+idlen]
+	ciphertext := body[21+idlen:]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicRaw)
+	if err != nil {
+		t.Fatalf("keyid is not a P-256 public key: %v", err)
+	}
+	ecdhSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacSHA256 := func(key []byte, parts ...[]byte) []byte {
+		h := hmac.New(sha256.New, key)
+		for _, p := range parts {
+			h.Write(p)
+		}
+		return h.Sum(nil)
+	}
+	prkKey := hmacSHA256(auth, ecdhSecret)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPrivate.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicRaw...)
+	ikm := hmacSHA256(prkKey, keyInfo, []byte{1})
+	prk := hmacSHA256(salt, ikm)
+	cek := hmacSHA256(prk, []byte("Content-Encoding: aes128gcm\x00"), []byte{1})[:16]
+	nonce := hmacSHA256(prk, []byte("Content-Encoding: nonce\x00"), []byte{1})[:12]
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	record = bytes.TrimRight(record, "\x00")
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatal("record does not end with the last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+func TestDecryptPushPayloadRFC8291Example(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decryptPushPayload(t, mustDecode(t, rfcBody), uaPrivate, mustDecode(t, rfcAuth))
+	if string(got) != rfcPlaintext {
+		t.Errorf("plaintext = %q", got)
+	}
+}
+
+// pushService is an httptest stand-in for a browser vendor's push service
+type pushService struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newPushService(t *testing.T, status int) *pushService {
+	t.Helper()
+	ps := &pushService{status: status}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ps.mu.Lock()
+		ps.requests = append(ps.requests, r)
+		ps.bodies = append(ps.bodies, body)
+		ps.mu.Unlock()
+		w.WriteHeader(ps.status)
+	}))
+	t.Cleanup(ps.Close)
+	return ps
+}
+
+// browser is a push subscription as a user agent creates it
+type browser struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	t.Helper()
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &browser{private: priv, auth: auth}
+}
+
+// subscribe stores the browser's subscription with ID id in the fake database
+func (b *browser) subscribe(fake *fakedb.DB, id int, endpoint string) {
+	fake.SetRows("subscriptions",
+		[]string{"id", "channel", "push_endpoint", "push_p256dh", "push_auth"},
+		[]driver.Value{int64(id), models.ChannelWebPush, endpoint,
+			b64.EncodeToString(b.private.PublicKey().Bytes()), b64.EncodeToString(b.auth)},
+	)
+}
+
+func pushNotification(subscriptionID int) *models.Notification {
+	body, _ := json.Marshal(PushMessage{
+		Title:    "Report #12 is now Resolved",
+		Body:     "Resolved: road relaid",
+		URL:      "https://helpgovern.example/r/Xk3u9PzQaL1m",
+		ReportID: 12,
+		Status:   "resolved",
+	})
+	return &models.Notification{
+		ID:             3,
+		Channel:        models.ChannelWebPush,
+		SubscriptionID: &subscriptionID,
+		Event:          "status_changed",
+		BodyText:       string(body),
+	}
+}
+
+func TestWebPushSendEncryptsForSubscription(t *testing.T) {
+	db, fake := fakedb.New(t)
+	ps := newPushService(t, http.StatusCreated)
+	ua := newBrowser(t)
+	ua.subscribe(fake, 7, ps.URL+"/wpush/v2/abc")
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := pushNotification(7)
+
+	if err := NewWebPushNotifier(db, keys, "mailto:admin@helpgovern.example").Send(context.Background(), n); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(ps.requests) != 1 {
+		t.Fatalf("push service got %d requests, want 1", len(ps.requests))
+	}
+	req := ps.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/wpush/v2/abc" {
+		t.Errorf("request = %s %s", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Content-Encoding"); got != "aes128gcm" {
+		t.Errorf("Content-Encoding = %q", got)
+	}
+	if got := req.Header.Get("TTL"); got != "86400" {
+		t.Errorf("TTL = %q", got)
+	}
+	if got := decryptPushPayload(t, ps.bodies[0], ua.private, ua.auth); string(got) != n.BodyText {
+		t.Errorf("payload = %s\nwant      %s", got, n.BodyText)
+	}
+}
+
+func TestWebPushSendVAPIDAuthorization(t *testing.T) {
+	db, fake := fakedb.New(t)
+	ps := newPushService(t, http.StatusCreated)
+	newBrowser(t).subscribe(fake, 7, ps.URL+"/wpush/v2/abc")
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewWebPushNotifier(db, keys, "mailto:admin@helpgovern.example").Send(context.Background(), pushNotification(7)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	header := ps.requests[0].Header.Get("Authorization")
+	rest, ok := strings.CutPrefix(header, "vapid t=")
+	if !ok {
+		t.Fatalf("Authorization = %q, want the vapid scheme", header)
+	}
+	jwt, k, ok := strings.Cut(rest, ", k=")
+	if !ok {
+		t.Fatalf("Authorization = %q has no k parameter", header)
+	}
+	if k != keys.PublicKey {
+		t.Errorf("k = %s, want the VAPID public key %s", k, keys.PublicKey)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("t = %q is not a JWS", jwt)
+	}
+
+	var jose struct{ Typ, Alg string }
+	if err := json.Unmarshal(mustDecode(t, parts[0]), &jose); err != nil || jose.Alg != "ES256" || jose.Typ != "JWT" {
+		t.Errorf("JWT header = %s", mustDecode(t, parts[0]))
+	}
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(mustDecode(t, parts[1]), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != ps.URL {
+		t.Errorf("aud = %q, want the push service origin %q", claims.Aud, ps.URL)
+	}
+	if claims.Sub != "mailto:admin@helpgovern.example" {
+		t.Errorf("sub = %q", claims.Sub)
+	}
+	// RFC 8292 caps exp at 24 hours from now
+	if exp := time.Unix(claims.Exp, 0); !exp.After(time.Now()) || exp.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("exp = %v", exp)
+	}
+
+	pub := mustDecode(t, k)
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pub[1:33]),
+		Y:     new(big.Int).SetBytes(pub[33:]),
+	}
+	sig := mustDecode(t, parts[2])
+	if len(sig) != 64 {
+		t.Fatalf("signature is %d bytes, want 64", len(sig))
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		t.Error("JWT signature does not verify with k")
+	}
+}
+
+func TestWebPushGoneSubscriptionIsPruned(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			db, fake := fakedb.New(t)
+			ps := newPushService(t, status)
+			newBrowser(t).subscribe(fake, 7, ps.URL+"/wpush/v2/abc")
+			keys, err := GenerateVAPIDKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc := NewNotificationService(db, nil, nil, "https://helpgovern.example")
+			svc.RegisterNotifier(models.ChannelWebPush, NewWebPushNotifier(db, keys, "mailto:admin@helpgovern.example"))
+
+			n := pushNotification(7)
+			n.Attempts = 1
+			if err := svc.deliver(context.Background(), n); err != nil {
+				t.Fatalf("deliver: %v", err)
+			}
+			deleted := fake.Executed(`DELETE FROM "subscriptions"`)
+			if len(deleted) != 1 || len(deleted[0].Args) != 1 || deleted[0].Args[0] != int64(7) {
+				t.Errorf("subscription deletes = %+v, want one for subscription 7", deleted)
+			}
+			updates := fake.Executed(`UPDATE "notifications"`)
+			if len(updates) != 1 || !containsArg(updates[0].Args, "failed") {
+				t.Errorf("notification updates = %+v, want it marked failed", updates)
+			}
+		})
+	}
+}
+
+func TestWebPushServerErrorIsRetried(t *testing.T) {
+	db, fake := fakedb.New(t)
+	ps := newPushService(t, http.StatusServiceUnavailable)
+	newBrowser(t).subscribe(fake, 7, ps.URL+"/wpush/v2/abc")
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewWebPushNotifier(db, keys, "mailto:admin@helpgovern.example").Send(context.Background(), pushNotification(7))
+	if err == nil || errors.Is(err, ErrRecipientGone) {
+		t.Fatalf("err = %v, want a retryable error", err)
+	}
+	if deleted := fake.Executed(`DELETE FROM "subscriptions"`); len(deleted) != 0 {
+		t.Errorf("subscription deleted after a server error: %+v", deleted)
+	}
+}
+
+func containsArg(args []driver.Value, want driver.Value) bool {
+	for _, a := range args {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
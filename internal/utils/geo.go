@@ -0,0 +1,58 @@
+package utils
+
+import "math"
+
+const earthRadiusMeters = 6371000.0
+
+// HaversineMeters returns the great-circle distance between two points in meters.
+func HaversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// BoundingBox returns a lat/lng box that fully contains the circle of the
+// given radius around (lat, lng). Useful as an index-friendly prefilter
+// before an exact distance check.
+func BoundingBox(lat, lng, radiusMeters float64) (minLat, minLng, maxLat, maxLng float64) {
+	dLat := radiusMeters / earthRadiusMeters * 180 / math.Pi
+	dLng := dLat / math.Max(math.Cos(toRadians(lat)), 1e-6)
+	return lat - dLat, lng - dLng, lat + dLat, lng + dLng
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// PointInPolygon reports whether (lat, lng) lies inside the ring of
+// [lat, lng] vertices, using ray casting. The ring may be open or closed.
+// Fine for neighbourhood-sized polygons; edges are treated as straight
+// lines in lat/lng space.
+func PointInPolygon(lat, lng float64, ring [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		latI, lngI := ring[i][0], ring[i][1]
+		latJ, lngJ := ring[j][0], ring[j][1]
+		if (latI > lat) != (latJ > lat) &&
+			lng < (lngJ-lngI)*(lat-latI)/(latJ-latI)+lngI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// PolygonBounds returns the lat/lng box enclosing the ring
+func PolygonBounds(ring [][2]float64) (minLat, minLng, maxLat, maxLng float64) {
+	if len(ring) == 0 {
+		return 0, 0, 0, 0
+	}
+	minLat, minLng = ring[0][0], ring[0][1]
+	maxLat, maxLng = minLat, minLng
+	for _, p := range ring[1:] {
+		minLat, maxLat = math.Min(minLat, p[0]), math.Max(maxLat, p[0])
+		minLng, maxLng = math.Min(minLng, p[1]), math.Max(maxLng, p[1])
+	}
+	return minLat, minLng, maxLat, maxLng
+}
@@ -0,0 +1,60 @@
+package utils
+
+import "unicode"
+
+// scriptLanguages maps a writing system to the language it most likely
+// means in reports from India
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Oriya, "or"},
+	{unicode.Arabic, "ur"},
+	{unicode.Latin, "en"},
+}
+
+// DetectLanguage guesses the language of text from the script most of its
+// letters are written in, e.g. "hi" for Devanagari and "en" for Latin. It
+// returns "" when text has no letters. Devanagari with the letter ळ, which
+// Hindi does not use, is taken as Marathi. Hindi typed in Latin letters is
+// reported as English.
+func DetectLanguage(text string) string {
+	counts := make([]int, len(scriptLanguages))
+	marathi := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r == 'ळ' {
+			marathi = true
+		}
+		for i, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	best := -1
+	for i, n := range counts {
+		if n > 0 && (best < 0 || n > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	language := scriptLanguages[best].language
+	if language == "hi" && marathi {
+		return "mr"
+	}
+	return language
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// A minimal Mapbox Vector Tile (spec v2.1) encoder for point layers. The
+// protobuf wire format is written by hand; only the fields needed for
+// points are supported.
+
+// MVTExtent is the default tile coordinate range
+const MVTExtent = 4096
+
+// Protobuf field numbers from vector_tile.proto
+const (
+	mvtTileLayers = 3
+
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+	mvtLayerVersion  = 15
+
+	mvtFeatureID       = 1
+	mvtFeatureTags     = 2
+	mvtFeatureType     = 3
+	mvtFeatureGeometry = 4
+
+	mvtValueString = 1
+	mvtValueDouble = 3
+	mvtValueSint   = 6
+	mvtValueBool   = 7
+
+	mvtGeomPoint = 1
+	mvtMoveTo    = 1
+
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+)
+
+// MVTLayer collects point features for one layer. Keys and values are
+// deduplicated across features as the spec intends.
+type MVTLayer struct {
+	name     string
+	extent   uint32
+	keys     []string
+	keyIdx   map[string]uint32
+	values   [][]byte
+	valueIdx map[string]uint32
+	features [][]byte
+}
+
+func NewMVTLayer(name string, extent uint32) *MVTLayer {
+	return &MVTLayer{
+		name:     name,
+		extent:   extent,
+		keyIdx:   map[string]uint32{},
+		valueIdx: map[string]uint32{},
+	}
+}
+
+// MVTProp is one feature attribute. Value must be a string, an integer, a
+// float64 or a bool.
+type MVTProp struct {
+	Key   string
+	Value interface{}
+}
+
+// Len is the number of features added so far
+func (l *MVTLayer) Len() int {
+	return len(l.features)
+}
+
+// AddPoint adds a point feature at tile coordinates (x, y), which may fall
+// outside [0, extent) for points in the tile buffer
+func (l *MVTLayer) AddPoint(id uint64, x, y int, props []MVTProp) error {
+	tags := make([]uint32, 0, 2*len(props))
+	for _, p := range props {
+		v, err := encodeMVTValue(p.Value)
+		if err != nil {
+			return fmt.Errorf("mvt: property %s: %w", p.Key, err)
+		}
+		tags = append(tags, l.key(p.Key), l.value(v))
+	}
+
+	var f []byte
+	if id != 0 {
+		f = appendVarintField(f, mvtFeatureID, id)
+	}
+	if len(tags) > 0 {
+		var packed []byte
+		for _, t := range tags {
+			packed = binary.AppendUvarint(packed, uint64(t))
+		}
+		f = appendBytesField(f, mvtFeatureTags, packed)
+	}
+	f = appendVarintField(f, mvtFeatureType, mvtGeomPoint)
+	var geom []byte
+	geom = binary.AppendUvarint(geom, uint64(mvtMoveTo|1<<3)) // MoveTo, one point
+	geom = binary.AppendUvarint(geom, uint64(zigzag(int64(x))))
+	geom = binary.AppendUvarint(geom, uint64(zigzag(int64(y))))
+	f = appendBytesField(f, mvtFeatureGeometry, geom)
+
+	l.features = append(l.features, f)
+	return nil
+}
+
+func (l *MVTLayer) key(k string) uint32 {
+	if i, ok := l.keyIdx[k]; ok {
+		return i
+	}
+	i := uint32(len(l.keys))
+	l.keys = append(l.keys, k)
+	l.keyIdx[k] = i
+	return i
+}
+
+func (l *MVTLayer) value(v []byte) uint32 {
+	if i, ok := l.valueIdx[string(v)]; ok {
+		return i
+	}
+	i := uint32(len(l.values))
+	l.values = append(l.values, v)
+	l.valueIdx[string(v)] = i
+	return i
+}
+
+// marshal encodes the Layer message
+func (l *MVTLayer) marshal() []byte {
+	var b []byte
+	b = appendVarintField(b, mvtLayerVersion, 2)
+	b = appendBytesField(b, mvtLayerName, []byte(l.name))
+	for _, f := range l.features {
+		b = appendBytesField(b, mvtLayerFeatures, f)
+	}
+	for _, k := range l.keys {
+		b = appendBytesField(b, mvtLayerKeys, []byte(k))
+	}
+	for _, v := range l.values {
+		b = appendBytesField(b, mvtLayerValues, v)
+	}
+	return appendVarintField(b, mvtLayerExtent, uint64(l.extent))
+}
+
+// EncodeMVT encodes a tile. Empty layers are left out, so a tile without
+// features encodes to zero bytes, which is a valid empty tile.
+func EncodeMVT(layers ...*MVTLayer) []byte {
+	var b []byte
+	for _, l := range layers {
+		if l.Len() > 0 {
+			b = appendBytesField(b, mvtTileLayers, l.marshal())
+		}
+	}
+	return b
+}
+
+// encodeMVTValue encodes a Value message
+func encodeMVTValue(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return appendBytesField(nil, mvtValueString, []byte(v)), nil
+	case int:
+		return appendVarintField(nil, mvtValueSint, zigzag(int64(v))), nil
+	case int64:
+		return appendVarintField(nil, mvtValueSint, zigzag(v)), nil
+	case float64:
+		b := appendTag(nil, mvtValueDouble, wire64)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case bool:
+		n := uint64(0)
+		if v {
+			n = 1
+		}
+		return appendVarintField(nil, mvtValueBool, n), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+func zigzag(n int64) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
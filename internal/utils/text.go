@@ -0,0 +1,11 @@
+package utils
+
+import "unicode/utf8"
+
+// Truncate shortens s to at most max runes, marking the cut with an ellipsis
+func Truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
@@ -0,0 +1,84 @@
+package utils
+
+import "math"
+
+// Web mercator ("slippy map") tile helpers, as used by Leaflet and OSM.
+
+const (
+	// TileSize is the edge of a raster tile in pixels
+	TileSize = 256
+	// MaxTileZoom is the deepest zoom level served
+	MaxTileZoom = 18
+	// maxMercatorLat is where web mercator is cut off
+	maxMercatorLat = 85.0511287798066
+)
+
+// ValidTile reports whether z/x/y names an existing tile up to MaxTileZoom
+func ValidTile(z, x, y int) bool {
+	if z < 0 || z > MaxTileZoom {
+		return false
+	}
+	n := 1 << z
+	return x >= 0 && x < n && y >= 0 && y < n
+}
+
+// WorldPixel projects a point to pixel coordinates of the whole world map at
+// zoom z, with (0, 0) at the north-west corner
+func WorldPixel(lat, lng float64, z int) (px, py float64) {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	scale := float64(TileSize) * float64(int(1)<<z)
+	px = (lng + 180) / 360 * scale
+	sin := math.Sin(toRadians(lat))
+	py = (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * scale
+	return px, py
+}
+
+// tileLat is the latitude of the top edge of tile row y at zoom z
+func tileLat(y float64, z int) float64 {
+	n := math.Pi - 2*math.Pi*y/float64(int(1)<<z)
+	return math.Atan(math.Sinh(n)) * 180 / math.Pi
+}
+
+// TileBounds returns the lat/lng box covered by tile z/x/y, grown by
+// bufferPx pixels on every side
+func TileBounds(z, x, y int, bufferPx float64) (minLat, minLng, maxLat, maxLng float64) {
+	b := bufferPx / TileSize
+	n := float64(int(1) << z)
+	minLng = (float64(x)-b)/n*360 - 180
+	maxLng = (float64(x)+1+b)/n*360 - 180
+	maxLat = tileLat(math.Max(0, float64(y)-b), z)
+	minLat = tileLat(math.Min(n, float64(y)+1+b), z)
+	return minLat, minLng, maxLat, maxLng
+}
+
+// TilesAround returns the tiles at zoom z within bufferPx pixels of a point,
+// i.e. every tile whose buffered bounds contain it
+func TilesAround(lat, lng float64, z int, bufferPx float64) [][2]int {
+	px, py := WorldPixel(lat, lng, z)
+	n := 1 << z
+	clamp := func(v float64) int {
+		t := int(math.Floor(v / TileSize))
+		if t < 0 {
+			return 0
+		}
+		if t >= n {
+			return n - 1
+		}
+		return t
+	}
+	var tiles [][2]int
+	for x := clamp(px - bufferPx); x <= clamp(px+bufferPx); x++ {
+		for y := clamp(py - bufferPx); y <= clamp(py+bufferPx); y++ {
+			tiles = append(tiles, [2]int{x, y})
+		}
+	}
+	return tiles
+}
+
+// PixelLatLng is the inverse of WorldPixel
+func PixelLatLng(px, py float64, z int) (lat, lng float64) {
+	scale := float64(TileSize) * float64(int(1)<<z)
+	lng = px/scale*360 - 180
+	lat = tileLat(py/TileSize, z)
+	return lat, lng
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "time"
+
+// ParseTimeOrDate accepts an RFC 3339 timestamp or a bare YYYY-MM-DD date,
+// which is taken as midnight UTC.
+func ParseTimeOrDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
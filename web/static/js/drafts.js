@@ -0,0 +1,84 @@
+// Reports drafted offline wait in IndexedDB until they can be sent to
+// POST /reports/drafts. Loaded by the page and by the service worker, so it
+// only uses what both have.
+const Drafts = (function () {
+    const DB_NAME = 'help-govern';
+    const DRAFTS = 'drafts';       // waiting to be sent
+    const SUBMITTED = 'submitted'; // replies the page has not shown yet
+    const SYNC_TAG = 'report-drafts';
+
+    function open() {
+        return new Promise((resolve, reject) => {
+            const req = indexedDB.open(DB_NAME, 1);
+            req.onupgradeneeded = () => {
+                req.result.createObjectStore(DRAFTS, { keyPath: 'submission_id' });
+                req.result.createObjectStore(SUBMITTED, { keyPath: 'submission_id' });
+            };
+            req.onsuccess = () => resolve(req.result);
+            req.onerror = () => reject(req.error);
+        });
+    }
+
+    async function run(store, mode, fn) {
+        const db = await open();
+        return new Promise((resolve, reject) => {
+            const tx = db.transaction(store, mode);
+            const req = fn(tx.objectStore(store));
+            tx.oncomplete = () => resolve(req.result);
+            tx.onerror = () => reject(tx.error);
+        });
+    }
+
+    const all = store => run(store, 'readonly', s => s.getAll());
+    const put = (store, value) => run(store, 'readwrite', s => s.put(value));
+    const remove = (store, key) => run(store, 'readwrite', s => s.delete(key));
+
+    // save queues a report. Its submission ID stays with it, so sending it
+    // more than once still creates a single report.
+    async function save(report) {
+        const draft = Object.assign({}, report, { submission_id: crypto.randomUUID() });
+        await put(DRAFTS, draft);
+        return draft;
+    }
+
+    // sync sends every queued draft. A draft the server rejects is dropped
+    // along with the reason; network and server errors keep it queued and
+    // fail the sync, so the browser tries again later.
+    async function sync() {
+        let retry = false;
+        for (const draft of await all(DRAFTS)) {
+            let resp;
+            try {
+                resp = await fetch('/reports/drafts', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(draft)
+                });
+            } catch (err) {
+                retry = true;
+                continue;
+            }
+            if (resp.status >= 500 || resp.status === 429) {
+                retry = true;
+                continue;
+            }
+            const reply = await resp.json().catch(() => ({}));
+            await put(SUBMITTED, { submission_id: draft.submission_id, ok: resp.ok, reply: reply });
+            await remove(DRAFTS, draft.submission_id);
+        }
+        if (retry) {
+            throw new Error('some report drafts could not be sent');
+        }
+    }
+
+    // takeSubmitted returns the replies to sent drafts, once
+    async function takeSubmitted() {
+        const replies = await all(SUBMITTED);
+        for (const r of replies) {
+            await remove(SUBMITTED, r.submission_id);
+        }
+        return replies;
+    }
+
+    return { SYNC_TAG, save, sync, takeSubmitted, pending: () => all(DRAFTS) };
+})();
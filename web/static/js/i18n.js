@@ -0,0 +1,8 @@
+// Translations rendered into the page by the server, keyed by English text.
+// t('Submit Report') returns the text in the page language, or the English
+// text itself when it has no translation.
+const i18nMessages = JSON.parse(document.getElementById('i18n-messages')?.textContent || '{}') || {};
+
+function t(msg) {
+    return i18nMessages[msg] || msg;
+}
@@ -84,12 +84,40 @@ document.addEventListener('DOMContentLoaded', function () {
         m.bindPopup(
             `<b>${report.category.replace(/_/g, ' ')}</b><br>` +
             `${report.description}<br>` +
-            `<span>Status: ${report.status}</span>`
+            `<span>${t('Status:')} ${report.status}</span>`
         );
         markers.addLayer(m);
     });
     map.addLayer(markers);
 
+    // --- Report density heatmap, rendered server-side per tile ---
+    var heat = L.tileLayer('/tiles/heat/{z}/{x}/{y}.png', {
+        opacity: 0.75,
+        maxZoom: 18,
+        attribution: 'Report density'
+    });
+    L.control.layers(null, {
+        [t('Reports')]: markers,
+        [t('Density heatmap')]: heat
+    }).addTo(map);
+
+    // --- Report page "See it on the map" link (/?report=ID): focus on that report ---
+    var sharedId = new URLSearchParams(window.location.search).get('report');
+    if (sharedId) {
+        fetch('/reports/' + sharedId)
+            .then(function (resp) { return resp.ok ? resp.json() : null; })
+            .then(function (report) {
+                if (!report) return;
+                var m = L.marker([report.latitude, report.longitude]).addTo(map);
+                m.bindPopup(
+                    `<b>${report.category.replace(/_/g, ' ')}</b><br>` +
+                    `${report.description}<br>` +
+                    `<span>${t('Status:')} ${report.status}</span>`
+                ).openPopup();
+                map.setView([report.latitude, report.longitude], 16);
+            });
+    }
+
     // --- GPS Location Capture ---
     // Add a button to the map for geolocation
     var locateBtn = L.control({ position: 'topleft' });
@@ -102,12 +130,12 @@ document.addEventListener('DOMContentLoaded', function () {
         div.style.display = 'flex';
         div.style.alignItems = 'center';
         div.style.justifyContent = 'center';
-        div.title = 'Detect my location';
+        div.title = t('Detect my location');
         div.innerHTML = '<span style="font-size:20px;">📍</span>';
         div.onclick = function (e) {
             e.stopPropagation();
             if (!navigator.geolocation) {
-                alert('Geolocation is not supported by your browser.');
+                alert(t('Geolocation is not supported by your browser.'));
                 return;
             }
             div.innerHTML = '<span style="font-size:20px;">⏳</span>';
@@ -127,7 +155,7 @@ document.addEventListener('DOMContentLoaded', function () {
                 setLatLngFields(lat, lng);
                 div.innerHTML = '<span style="font-size:20px;">📍</span>';
             }, function (err) {
-                alert('Could not get your location: ' + err.message);
+                alert(t('Could not get your location:') + ' ' + err.message);
                 div.innerHTML = '<span style="font-size:20px;">📍</span>';
             });
         };
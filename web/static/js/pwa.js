@@ -0,0 +1,8 @@
+// Installs the service worker, which keeps the site usable offline and
+// sends reports drafted offline in the background
+if ('serviceWorker' in navigator) {
+    window.addEventListener('load', function () {
+        navigator.serviceWorker.register('/sw.js')
+            .catch(err => console.error('Service worker registration failed:', err));
+    });
+}
@@ -5,24 +5,18 @@ document.addEventListener('DOMContentLoaded', function () {
     const latInput = document.getElementById('latitude');
     const lngInput = document.getElementById('longitude');
 
-    // Placeholder: Populate categories (should fetch from backend in future)
-    const categories = [
-        'potholes',
-        'broken_streetlight',
-        'no_streetlight',
-        'water_leaks',
-        'poor_drainage',
-        'damaged_sidewalk',
-        'accident_prone',
-        'garbage_heap',
-        'wrong_side_driving'
-    ];
-    categories.forEach(cat => {
-        const opt = document.createElement('option');
-        opt.value = cat;
-        opt.textContent = cat.replace(/_/g, ' ').replace(/\b\w/g, l => l.toUpperCase());
-        categorySelect.appendChild(opt);
-    });
+    // Category names come back in the page language
+    fetch('/categories?lang=' + encodeURIComponent(document.documentElement.lang || 'en'))
+        .then(resp => resp.json())
+        .then(body => {
+            (body.categories || []).forEach(cat => {
+                const opt = document.createElement('option');
+                opt.value = cat.name;
+                opt.textContent = cat.display_name;
+                categorySelect.appendChild(opt);
+            });
+        })
+        .catch(err => console.error('Could not load categories:', err));
 
     // Helper to reset error highlights
     function resetFieldStyles() {
@@ -39,20 +33,21 @@ document.addEventListener('DOMContentLoaded', function () {
             category: form.category.value,
             latitude: parseFloat(form.latitude.value),
             longitude: parseFloat(form.longitude.value),
-            description: form.description.value
+            description: form.description.value,
+            track: true
         };
         if (!data.category) {
-            errorMsg += 'Please select a category.\n';
+            errorMsg += t('Please select a category.') + '\n';
             categorySelect.classList.add('input-error');
             hasError = true;
         }
         if (isNaN(data.latitude) || data.latitude < -90 || data.latitude > 90) {
-            errorMsg += 'Please enter a valid latitude (-90 to 90).\n';
+            errorMsg += t('Please enter a valid latitude (-90 to 90).') + '\n';
             latInput.classList.add('input-error');
             hasError = true;
         }
         if (isNaN(data.longitude) || data.longitude < -180 || data.longitude > 180) {
-            errorMsg += 'Please enter a valid longitude (-180 to 180).\n';
+            errorMsg += t('Please enter a valid longitude (-180 to 180).') + '\n';
             lngInput.classList.add('input-error');
             hasError = true;
         }
@@ -61,25 +56,128 @@ document.addEventListener('DOMContentLoaded', function () {
             return;
         }
         try {
-            const resp = await fetch('/reports', {
+            let resp = await fetch('/reports', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
                 body: JSON.stringify(data)
             });
-            const respData = await resp.json();
+            let respData = await resp.json();
+            // Nearby open reports of the same category: ask before adding another pin
+            if (resp.status === 409 && respData.candidates) {
+                const list = respData.candidates.map(c =>
+                    `#${c.id} (${Math.round(c.distance_m)} m away, ${c.status})${c.description ? ': ' + c.description : ''}`
+                ).join('\n');
+                if (!confirm(`${respData.details}\n\n${list}\n\n${t('Press OK to submit a new report anyway, or Cancel if it is the same issue.')}`)) {
+                    const first = respData.candidates[0];
+                    resultDiv.innerHTML = `${t('This issue is already reported:')} <a href='/reports/${first.id}' target='_blank'>#${first.id}</a>`;
+                    return;
+                }
+                data.ignore_duplicates = true;
+                resp = await fetch('/reports', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(data)
+                });
+                respData = await resp.json();
+            }
             if (resp.ok) {
-                resultDiv.innerHTML = `<span style='color:green'>${respData.message}</span><br>Share URL: <a href='${respData.share_url}' target='_blank'>${respData.share_url}</a>`;
+                remember(respData);
+                resultDiv.innerHTML = `<span style='color:green'>${respData.message}</span><br>${t('Share URL:')} <a href='${respData.share_url}' target='_blank'>${respData.share_url}</a>`;
                 form.reset();
                 resetFieldStyles();
             } else {
                 let details = respData.details ? `<br><small>${respData.details}</small>` : '';
-                resultDiv.innerHTML = `<span style='color:red'>${respData.error || 'Submission failed'}</span>${details}`;
+                resultDiv.innerHTML = `<span style='color:red'>${respData.error || t('Submission failed')}</span>${details}`;
             }
         } catch (err) {
-            resultDiv.innerHTML = `<span style='color:red'>Network error: ${err}</span>`;
+            // Offline: keep the report on this device and send it later
+            if (typeof Drafts !== 'undefined' && window.isSecureContext) {
+                try {
+                    await Drafts.save(data);
+                    await requestSync();
+                    resultDiv.innerHTML = `<span style='color:green'>${t('You are offline. Your report was saved and will be sent when you are back online.')}</span>`;
+                    form.reset();
+                    resetFieldStyles();
+                    return;
+                } catch (saveErr) {
+                    console.error('Could not save draft:', saveErr);
+                }
+            }
+            resultDiv.innerHTML = `<span style='color:red'>${t('Network error:')} ${err}</span>`;
         }
     });
 
+    // Keep the private edit token on this device so the reporter can add
+    // photos or withdraw the report later. A draft synced again comes back
+    // without one; keep the token from the first reply.
+    function remember(respData) {
+        if (respData.edit_token) {
+            const tokens = JSON.parse(localStorage.getItem('editTokens') || '{}');
+            tokens[respData.id] = respData.edit_token;
+            localStorage.setItem('editTokens', JSON.stringify(tokens));
+        }
+        if (respData.tracking_token) {
+            const tracking = JSON.parse(localStorage.getItem('trackingTokens') || '[]');
+            tracking.push(respData.tracking_token);
+            localStorage.setItem('trackingTokens', JSON.stringify(tracking));
+        }
+    }
+
+    // Background sync sends drafts even after the page is closed; without
+    // it, drafts go out the next time this page is open and online
+    async function requestSync() {
+        if ('serviceWorker' in navigator && 'SyncManager' in window) {
+            const reg = await navigator.serviceWorker.ready;
+            await reg.sync.register(Drafts.SYNC_TAG);
+        } else if (navigator.onLine) {
+            await syncNow();
+        }
+    }
+
+    async function syncNow() {
+        try {
+            await Drafts.sync();
+        } catch (err) {
+            console.error('Could not send drafts:', err);
+        }
+        await showSynced();
+    }
+
+    // Tell the reporter what happened to the reports drafted offline
+    async function showSynced() {
+        const lines = [];
+        for (const r of await Drafts.takeSubmitted()) {
+            if (r.ok) {
+                remember(r.reply);
+                lines.push(`<span style='color:green'>${t('Your offline report was sent.')}</span> <a href='${r.reply.share_url}' target='_blank'>${r.reply.share_url}</a>`);
+            } else {
+                const details = r.reply.details ? ` <small>${r.reply.details}</small>` : '';
+                lines.push(`<span style='color:red'>${t('Your offline report could not be sent.')}</span>${details}`);
+            }
+        }
+        if (lines.length) {
+            resultDiv.innerHTML = lines.join('<br>');
+        }
+    }
+
+    if (typeof Drafts !== 'undefined' && window.isSecureContext) {
+        if ('serviceWorker' in navigator) {
+            navigator.serviceWorker.addEventListener('message', e => {
+                if (e.data && e.data.type === 'drafts-synced') {
+                    showSynced();
+                }
+            });
+        }
+        if (!('SyncManager' in window)) {
+            window.addEventListener('online', syncNow);
+            if (navigator.onLine) {
+                syncNow();
+            }
+        } else {
+            showSynced();
+        }
+    }
+
     // Remove error highlight on input
     [categorySelect, latInput, lngInput].forEach(f => {
         f.addEventListener('input', () => f.classList.remove('input-error'));
@@ -0,0 +1,62 @@
+// Service worker. The server renders this template at startup with the
+// hashed asset URLs of the release, so every release gets its own cache.
+const CACHE = 'help-govern-{{ .Version }}';
+const PRECACHE = [
+{{- range .Precache }}
+    '{{ . }}',
+{{- end }}
+];
+
+importScripts('{{ asset "js/drafts.js" }}');
+
+self.addEventListener('install', event => {
+    event.waitUntil(caches.open(CACHE)
+        .then(cache => cache.addAll(PRECACHE))
+        .then(() => self.skipWaiting()));
+});
+
+// Drop the caches of earlier releases
+self.addEventListener('activate', event => {
+    event.waitUntil(caches.keys()
+        .then(keys => Promise.all(keys
+            .filter(key => key.startsWith('help-govern-') && key !== CACHE)
+            .map(key => caches.delete(key))))
+        .then(() => self.clients.claim()));
+});
+
+function remember(req, resp) {
+    if (resp.ok) {
+        const copy = resp.clone();
+        caches.open(CACHE).then(cache => cache.put(req, copy));
+    }
+    return resp;
+}
+
+self.addEventListener('fetch', event => {
+    const req = event.request;
+    const url = new URL(req.url);
+    if (req.method !== 'GET' || url.origin !== self.location.origin) {
+        return;
+    }
+    // Hashed assets never change under the same name
+    if (url.pathname.startsWith('/assets/')) {
+        event.respondWith(caches.match(req).then(hit => hit || fetch(req).then(resp => remember(req, resp))));
+        return;
+    }
+    // Pages come from the network when possible, otherwise from the last
+    // copy seen, otherwise the offline page. API calls are left alone.
+    if (req.mode === 'navigate') {
+        event.respondWith(fetch(req)
+            .then(resp => remember(req, resp))
+            .catch(() => caches.match(req).then(hit => hit || caches.match('/offline'))));
+    }
+});
+
+// Send reports drafted offline, then let open pages show the outcome
+self.addEventListener('sync', event => {
+    if (event.tag !== Drafts.SYNC_TAG) {
+        return;
+    }
+    event.waitUntil(Drafts.sync().finally(() => self.clients.matchAll({ type: 'window' })
+        .then(clients => clients.forEach(client => client.postMessage({ type: 'drafts-synced' })))));
+});